@@ -21,12 +21,27 @@ func (c *Component) Name() string {
 	return "Alertmanager"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"alertmanager"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey}
+}
+
 // PreInit called before Init()
 func (c *Component) PreInit(ctx context.Context) error {
 	SetDefaultConfig()
 	return nil
 }
 
+// DependsOnElements returns keys of elements which must be registered before this one is initialized
+func (c *Component) DependsOnElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&micro.LoggingElementKey}
+}
+
 // Init the component
 func (c *Component) Init(server *micro.Server) error {
 
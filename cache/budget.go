@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// EvictionPolicy selects the sensors whose points are evicted when the total budget is exceeded
+type EvictionPolicy int
+
+const (
+	// EvictOldest evicts the oldest points of all sensors first
+	EvictOldest EvictionPolicy = iota
+	// EvictLeastRecentlySearched evicts the points of the sensors searched least recently first, oldest points first
+	EvictLeastRecentlySearched
+	// EvictProportional evicts the oldest points of every sensor in proportion to its size
+	EvictProportional
+)
+
+// String implements fmt.Stringer
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictOldest:
+		return "oldest"
+	case EvictLeastRecentlySearched:
+		return "leastRecentlySearched"
+	case EvictProportional:
+		return "proportional"
+	}
+	return fmt.Sprintf("EvictionPolicy(%d)", int(p))
+}
+
+// ParseEvictionPolicy parses the name returned by EvictionPolicy.String
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	for _, p := range []EvictionPolicy{EvictOldest, EvictLeastRecentlySearched, EvictProportional} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return EvictOldest, fmt.Errorf("unknown eviction policy %q", name)
+}
+
+// EvictionReason is the reason points are removed from memory
+type EvictionReason string
+
+const (
+	// EvictExpired is for points older than expire
+	EvictExpired EvictionReason = "expired"
+	// EvictIdle is for points of sensors not searched within the idle timeout
+	EvictIdle EvictionReason = "idle"
+	// EvictBudget is for points evicted by the total budget
+	EvictBudget EvictionReason = "budget"
+	// EvictSensorBudget is for points evicted by the budget per sensor
+	EvictSensorBudget EvictionReason = "sensorBudget"
+	// EvictStopped is for points in memory when the container stops
+	EvictStopped EvictionReason = "stopped"
+)
+
+// EvictionCallback receives points of a sensor in time order before they are removed from memory.
+// It is called with the lock of the sensor held, so it must not call the container for the same sensor.
+type EvictionCallback func(id uint64, points []IDataPoint, reason EvictionReason)
+
+// Budget limits the size of points in memory, 0 is no limit.
+// Budgets are enforced every second, evicted points are moved to the disk tier if it is set.
+type Budget struct {
+	Total     uint64 // bytes of all sensors
+	PerSensor uint64 // bytes of each sensor, the oldest points are evicted
+	Policy    EvictionPolicy
+}
+
+var (
+	evictedPoints = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "cache",
+		Name:      "evicted_points_total",
+		Help:      "Points removed from memory of data caches.",
+	}, []string{"reason"})
+	evictedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "cache",
+		Name:      "evicted_bytes_total",
+		Help:      "Bytes of points removed from memory of data caches.",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(evictedPoints, evictedBytes)
+}
+
+// budgetCache is a DataCache over the budget
+type budgetCache struct {
+	cache      *DataCache
+	size       uint64
+	oldest     int64
+	lastSearch int64
+}
+
+// enforceBudget evicts points of sensors over the budget per sensor, then evicts points by the policy until the size
+// of all sensors is in the total budget
+func (cc *DataCacheContainer) enforceBudget() {
+	var caches []*budgetCache
+	var total uint64
+	cc.caches.Range(func(key, value interface{}) bool {
+		nc := value.(*DataCache)
+		if cc.budget.PerSensor > 0 {
+			if size := nc.size(); size > cc.budget.PerSensor {
+				cc.evict(nc, size-cc.budget.PerSensor, EvictSensorBudget)
+			}
+		}
+		oldest, ok := nc.oldest()
+		if !ok {
+			return true
+		}
+		size := nc.size()
+		total += size
+		caches = append(caches, &budgetCache{cache: nc, size: size, oldest: oldest, lastSearch: nc.lastSearch.Load()})
+		return true
+	})
+	if cc.budget.Total == 0 || total <= cc.budget.Total {
+		return
+	}
+	excess := total - cc.budget.Total
+	switch cc.budget.Policy {
+	case EvictLeastRecentlySearched:
+		sort.Slice(caches, func(i, j int) bool {
+			if caches[i].lastSearch != caches[j].lastSearch {
+				return caches[i].lastSearch < caches[j].lastSearch
+			}
+			return caches[i].oldest < caches[j].oldest
+		})
+		for _, c := range caches {
+			if excess == 0 {
+				break
+			}
+			excess -= min(excess, cc.evict(c.cache, min(excess, c.size), EvictBudget))
+		}
+	case EvictProportional:
+		for _, c := range caches {
+			// rounded up so the sizes of all sensors are in the budget
+			share := (excess*c.size + total - 1) / total
+			cc.evict(c.cache, share, EvictBudget)
+		}
+	default:
+		for excess > 0 && len(caches) > 0 {
+			i := 0
+			for j, c := range caches {
+				if c.oldest < caches[i].oldest {
+					i = j
+				}
+			}
+			c := caches[i]
+			// evict until the next oldest sensor
+			size := excess
+			if len(caches) > 1 {
+				size = min(excess, c.cache.sizeBefore(nextOldest(caches, i)))
+			}
+			excess -= min(excess, cc.evict(c.cache, max(size, 1), EvictBudget))
+			if oldest, ok := c.cache.oldest(); ok {
+				c.oldest = oldest
+			} else {
+				caches = append(caches[:i], caches[i+1:]...)
+			}
+		}
+	}
+}
+
+// nextOldest returns the oldest time of caches other than i
+func nextOldest(caches []*budgetCache, i int) int64 {
+	var next int64
+	found := false
+	for j, c := range caches {
+		if j != i && (!found || c.oldest < next) {
+			next = c.oldest
+			found = true
+		}
+	}
+	return next
+}
+
+// evict removes the oldest points of size at least from a sensor and returns the size removed
+func (cc *DataCacheContainer) evict(nc *DataCache, size uint64, reason EvictionReason) uint64 {
+	freed, err := nc.evict(size, reason, cc.evicted())
+	if err != nil {
+		cc.logger.Errorw("spill cache", "id", fmt.Sprintf("%012X", nc.id), "error", err)
+	}
+	return freed
+}
+
+// evicted returns the handler of points removed from memory, nil if there is neither disk tier nor callback
+func (cc *DataCacheContainer) evicted() func([]IDataPoint, EvictionReason) error {
+	if cc.disk == nil && cc.onEvict == nil {
+		return nil
+	}
+	return func(points []IDataPoint, reason EvictionReason) error {
+		if cc.onEvict != nil {
+			cc.onEvict(points[0].GetID(), points, reason)
+		}
+		if cc.disk == nil || reason == EvictIdle {
+			return nil
+		}
+		return cc.disk.Write(points[0].GetID(), points)
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// budgetContainer returns a container with 3 sensors of 5 points of 4 bytes, the points of sensor k are k*100ms late
+func budgetContainer(budget Budget) (*DataCacheContainer, *[]string) {
+	cc := NewDataCacheContainer(0, int64(time.Hour/time.Microsecond), false, zap.NewNop().Sugar())
+	cc.SetBudget(budget)
+	evicted := &[]string{}
+	cc.SetEvictionCallback(func(id uint64, points []IDataPoint, reason EvictionReason) {
+		*evicted = append(*evicted, fmt.Sprintf("%d:%d:%s", id, len(points), reason))
+	})
+	// the daemon is not started, budgets are enforced by the tests
+	cc.running.Store(true)
+	from := time.Now().Add(-time.Minute)
+	for id := uint64(1); id <= 3; id++ {
+		for _, dp := range testPoints(id, from.Add(time.Duration(id)*100*time.Millisecond), 5) {
+			cc.Input(dp)
+		}
+	}
+	return cc, evicted
+}
+
+func counts(cc *DataCacheContainer) []int {
+	stat := cc.GetStat()
+	return []int{stat[1].Count, stat[2].Count, stat[3].Count}
+}
+
+func TestBudgetPerSensor(t *testing.T) {
+	before := testutil.ToFloat64(evictedPoints.WithLabelValues(string(EvictSensorBudget)))
+	cc, evicted := budgetContainer(Budget{PerSensor: 12})
+	cc.enforceBudget()
+	assert.Equal(t, []int{3, 3, 3}, counts(cc))
+	assert.ElementsMatch(t, []string{"1:2:sensorBudget", "2:2:sensorBudget", "3:2:sensorBudget"}, *evicted)
+	stat := cc.GetStat()[1]
+	assert.Equal(t, uint64(2), stat.Evicted)
+	assert.Equal(t, uint64(8), stat.EvictedSize)
+	assert.Equal(t, float64(6), testutil.ToFloat64(evictedPoints.WithLabelValues(string(EvictSensorBudget)))-before)
+}
+
+func TestBudgetPolicies(t *testing.T) {
+	// the oldest points of all sensors
+	cc, evicted := budgetContainer(Budget{Total: 40, Policy: EvictOldest})
+	cc.enforceBudget()
+	assert.Equal(t, []int{3, 3, 4}, counts(cc))
+	assert.Equal(t, []string{"1:1:budget", "2:1:budget", "3:1:budget", "1:1:budget", "2:1:budget"}, *evicted)
+
+	// the sensor never searched
+	cc, evicted = budgetContainer(Budget{Total: 40, Policy: EvictLeastRecentlySearched})
+	for _, id := range []uint64{3, 1} {
+		_, err := cc.Search(&SearchRequest{ID: id})
+		assert.Nil(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	cc.enforceBudget()
+	assert.Equal(t, 0, cc.GetStat()[2].Count)
+	assert.Equal(t, []string{"2:5:budget"}, *evicted)
+	// then the sensor searched earlier
+	cc.budget.Total = 30
+	cc.enforceBudget()
+	assert.Equal(t, []int{5, 0, 2}, counts(cc))
+
+	cc, evicted = budgetContainer(Budget{Total: 30, Policy: EvictProportional})
+	cc.enforceBudget()
+	assert.Equal(t, []int{2, 2, 2}, counts(cc))
+	assert.ElementsMatch(t, []string{"1:3:budget", "2:3:budget", "3:3:budget"}, *evicted)
+}
+
+func TestParseEvictionPolicy(t *testing.T) {
+	for _, p := range []EvictionPolicy{EvictOldest, EvictLeastRecentlySearched, EvictProportional} {
+		parsed, err := ParseEvictionPolicy(p.String())
+		assert.Nil(t, err)
+		assert.Equal(t, p, parsed)
+	}
+	_, err := ParseEvictionPolicy("newest")
+	assert.NotNil(t, err)
+}
@@ -2,10 +2,10 @@ package cache
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
-	linkedList "github.com/emirpasic/gods/lists/singlylinkedlist"
 	"go.uber.org/atomic"
 
 	"github.com/kiga-hub/arc/logging"
@@ -18,93 +18,167 @@ type SearchRequest struct {
 	TimeTo   int64 // us
 }
 
-// DataCache maintain data
+// DataCache maintain data of a sensor in time order.
+// Searches hold the read lock only to locate the range by binary search and copy it, so they rarely block input.
 type DataCache struct {
 	id         uint64
-	lock       *sync.Mutex
+	lock       *sync.RWMutex
 	from       int64 //us
 	to         int64 //us
-	data       *linkedList.List
+	data       pointRing
 	totalSize  uint64
-	expire     int64 //us
-	lastSearch int64 //us
+	expire     int64         //us
+	lastSearch *atomic.Int64 //us
+	// points and bytes evicted by budgets
+	evictedCount uint64
+	evictedSize  uint64
 }
 
 // NewDataCache create a new DataCache
 func NewDataCache(id uint64, expire int64) *DataCache {
 	return &DataCache{
-		id:     id,
-		lock:   new(sync.Mutex),
-		from:   time.Now().UnixMicro(),
-		to:     time.Now().UnixMicro(),
-		data:   linkedList.New(),
-		expire: expire, //us
+		id:         id,
+		lock:       new(sync.RWMutex),
+		from:       time.Now().UnixMicro(),
+		to:         time.Now().UnixMicro(),
+		expire:     expire, //us
+		lastSearch: atomic.NewInt64(0),
 	}
 }
 
 func (c *DataCache) input(dp IDataPoint) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	c.data.Add(dp)
+	c.data.push(dp)
 	c.totalSize += dp.GetSize()
-	c.to = dp.GetTime()
+	c.to = max(c.to, dp.GetTime())
 }
 
 func (c *DataCache) search(request *SearchRequest) []IDataPoint {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.lastSearch = time.Now().UnixMicro()
-	result := make([]IDataPoint, 0, 8)
-	var dp IDataPoint
-	it := c.data.Iterator()
-	for it.Begin(); it.Next(); {
-		item := it.Value().(IDataPoint)
-		if item.GetTime() < request.TimeFrom {
-			continue
-		}
-		if item.GetTime() > request.TimeTo {
-			break
-		}
-		if dp == nil {
-			dp = item
+	c.lastSearch.Store(time.Now().UnixMicro())
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.data.between(request.TimeFrom, request.TimeTo)
+}
+
+// searchTiered searches the disk tier and memory with the read lock held.
+// Points are moved to disk with the write lock held, so every point is found exactly once.
+func (c *DataCache) searchTiered(request *SearchRequest, disk *DiskTier) ([]IDataPoint, error) {
+	c.lastSearch.Store(time.Now().UnixMicro())
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	onDisk, err := disk.Search(request)
+	if err != nil {
+		return nil, err
+	}
+	return mergePoints(onDisk, c.data.between(request.TimeFrom, request.TimeTo)), nil
+}
+
+// mergePoints merges two slices in time order, a late point in memory can be older than points on disk
+func mergePoints(a, b []IDataPoint) []IDataPoint {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 || a[len(a)-1].GetTime() <= b[0].GetTime() {
+		return append(a, b...)
+	}
+	result := make([]IDataPoint, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].GetTime() < a[0].GetTime() {
+			result = append(result, b[0])
+			b = b[1:]
 		} else {
-			result = append(result, dp)
-			dp = item
+			result = append(result, a[0])
+			a = a[1:]
 		}
 	}
-	if dp != nil {
-		result = append(result, dp)
+	result = append(result, a...)
+	return append(result, b...)
+}
+
+// cleanTimeout removes expired points, they are passed to evicted before removed if evicted is not nil.
+// It returns true if the cache is idle and cleared.
+func (c *DataCache) cleanTimeout(idleTimeout int, evicted func([]IDataPoint, EvictionReason) error) (bool, error) {
+	now := time.Now().UnixMicro()
+	lastSearch := c.lastSearch.Load()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if idleTimeout > 0 && lastSearch > 0 && lastSearch < now-int64(idleTimeout) {
+		_, err := c.remove(c.data.len(), EvictIdle, evicted)
+		c.data.clear()
+		return true, err
 	}
-	return result
+	_, err := c.remove(c.data.search(now-c.expire), EvictExpired, evicted)
+	return false, err
 }
 
-func (c *DataCache) cleanTimeout(idleTimeout int) bool {
+// evict removes the oldest points of size at least and returns the size removed,
+// they are passed to evicted before removed if evicted is not nil
+func (c *DataCache) evict(size uint64, reason EvictionReason, evicted func([]IDataPoint, EvictionReason) error) (uint64, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	now := time.Now().UnixMicro()
-	if idleTimeout > 0 && c.lastSearch > 0 && c.lastSearch < now-int64(idleTimeout) {
-		c.data.Clear()
-		return true
+	n := 0
+	var freed uint64
+	for ; n < c.data.len() && freed < size; n++ {
+		freed += c.data.at(n).GetSize()
 	}
-	threshold := now - c.expire
-	it := c.data.Iterator()
-	index := -1
-	for it.Begin(); it.Next(); {
-		d := it.Value().(IDataPoint) // us
-		if d.GetTime() < threshold {
-			c.totalSize -= d.GetSize()
-			c.from = d.GetTime()
-			index = it.Index()
-		} else {
-			break
-		}
+	return c.remove(n, reason, evicted)
+}
+
+// oldest returns the time of the first point, false if it is empty
+func (c *DataCache) oldest() (int64, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.data.len() == 0 {
+		return 0, false
 	}
-	if index >= 0 {
-		for i := 0; i <= index; i++ {
-			c.data.Remove(0)
-		}
+	return c.data.at(0).GetTime(), true
+}
+
+// size returns the total size of points
+func (c *DataCache) size() uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.totalSize
+}
+
+// sizeBefore returns the size of points before t
+func (c *DataCache) sizeBefore(t int64) uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var size uint64
+	for i, n := 0, c.data.search(t); i < n; i++ {
+		size += c.data.at(i).GetSize()
 	}
-	return false
+	return size
+}
+
+// remove removes the first n points with the lock held and returns their size.
+// Points are passed to evicted before removed, so they can always be found in memory or on disk.
+func (c *DataCache) remove(n int, reason EvictionReason, evicted func([]IDataPoint, EvictionReason) error) (uint64, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	var err error
+	if evicted != nil {
+		points := make([]IDataPoint, n)
+		c.data.copyTo(points, 0, n)
+		err = evicted(points, reason)
+	}
+	var size uint64
+	for i := 0; i < n; i++ {
+		size += c.data.at(i).GetSize()
+	}
+	c.totalSize -= size
+	c.from = c.data.at(n - 1).GetTime()
+	c.data.popFront(n)
+	if reason == EvictBudget || reason == EvictSensorBudget {
+		c.evictedCount += uint64(n)
+		c.evictedSize += size
+	}
+	evictedPoints.WithLabelValues(string(reason)).Add(float64(n))
+	evictedBytes.WithLabelValues(string(reason)).Add(float64(size))
+	return size, err
 }
 
 // DataCacheStat contains the stat of the DataCache
@@ -114,17 +188,25 @@ type DataCacheStat struct {
 	From   int64
 	To     int64
 	Expire int64
+	// points and bytes evicted by budgets
+	Evicted     uint64
+	EvictedSize uint64
+	LastSearch  int64     // us, 0 if never searched
+	Disk        *DiskStat // nil if the disk tier is not set
 }
 
 func (c *DataCache) stat() *DataCacheStat {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	return &DataCacheStat{
-		Count:  c.data.Size(),
-		Size:   c.totalSize,
-		From:   c.from,
-		To:     c.to,
-		Expire: c.expire,
+		Count:       c.data.len(),
+		Size:        c.totalSize,
+		From:        c.from,
+		To:          c.to,
+		Expire:      c.expire,
+		Evicted:     c.evictedCount,
+		EvictedSize: c.evictedSize,
+		LastSearch:  c.lastSearch.Load(),
 	}
 }
 
@@ -138,6 +220,9 @@ type DataCacheContainer struct {
 	isPrintStat   bool
 	isSearchCache bool
 	logger        logging.ILogger
+	disk          *DiskTier
+	budget        Budget
+	onEvict       EvictionCallback
 }
 
 // NewDataCacheContainer create a new DataCacheContainer
@@ -157,6 +242,23 @@ func NewDataCacheContainer(idleTimeout int, expire int64, isSearchCache bool, lo
 	return cc
 }
 
+// SetDiskTier sets the disk tier receiving expired points, Search returns points of both memory and disk.
+// It must be called before Start, the disk tier is closed by Stop.
+func (cc *DataCacheContainer) SetDiskTier(disk *DiskTier) {
+	cc.disk = disk
+}
+
+// SetBudget sets the budget of points in memory, it must be called before Start
+func (cc *DataCacheContainer) SetBudget(budget Budget) {
+	cc.budget = budget
+}
+
+// SetEvictionCallback sets the callback receiving points removed from memory, so they can be persisted.
+// Points evicted for idle are not written to the disk tier. It must be called before Start.
+func (cc *DataCacheContainer) SetEvictionCallback(callback EvictionCallback) {
+	cc.onEvict = callback
+}
+
 // Start the container
 func (cc *DataCacheContainer) Start(isPrintStat bool) {
 	cc.logger.Info("DataCacheContainer starting")
@@ -174,6 +276,18 @@ func (cc *DataCacheContainer) Stop() {
 		return
 	}
 	cc.running.Store(false)
+	if cc.evicted() != nil {
+		// keep the window in memory for the restart
+		cc.caches.Range(func(key, value interface{}) bool {
+			cc.evict(value.(*DataCache), math.MaxUint64, EvictStopped)
+			return true
+		})
+	}
+	if cc.disk != nil {
+		if err := cc.disk.Close(); err != nil {
+			cc.logger.Errorw("close disk tier", "error", err)
+		}
+	}
 	cc.logger.Debug("DataCacheContainer stopped")
 }
 
@@ -204,7 +318,10 @@ func (cc *DataCacheContainer) Search(request *SearchRequest) ([]IDataPoint, erro
 		cc.logger.Debugf("DataCacheContainer starting cache for id %d...\n", request.ID)
 	}
 
-	return nc.search(request), nil
+	if cc.disk == nil {
+		return nc.search(request), nil
+	}
+	return nc.searchTiered(request, cc.disk)
 }
 
 func (cc *DataCacheContainer) daemon() {
@@ -220,12 +337,22 @@ func (cc *DataCacheContainer) daemon() {
 			if !cc.isSearchCache {
 				timeouts = 0
 			}
-			if ok := nc.cleanTimeout(timeouts); ok {
+			ok, err := nc.cleanTimeout(timeouts, cc.evicted())
+			if err != nil {
+				cc.logger.Errorw("spill cache", "id", fmt.Sprintf("%012X", key.(uint64)), "error", err)
+			}
+			if ok {
 				cc.logger.Debugf("DataCacheContainer stopping cache for id %d...\n", key.(uint64))
 				cc.caches.Delete(key)
 			}
 			return true
 		})
+		if cc.budget.Total > 0 || cc.budget.PerSensor > 0 {
+			cc.enforceBudget()
+		}
+		if cc.disk != nil {
+			cc.disk.Clean(time.Now().UnixMicro())
+		}
 	}
 }
 
@@ -236,6 +363,17 @@ func (cc *DataCacheContainer) GetStat() map[uint64]*DataCacheStat {
 		stat[key.(uint64)] = value.(*DataCache).stat()
 		return true
 	})
+	if cc.disk == nil {
+		return stat
+	}
+	for id, disk := range cc.disk.Stat() {
+		st, ok := stat[id]
+		if !ok {
+			st = &DataCacheStat{Expire: cc.expire}
+			stat[id] = st
+		}
+		st.Disk = disk
+	}
 	return stat
 }
 
@@ -245,13 +383,19 @@ func (cc *DataCacheContainer) PrintStat() {
 	var totalSize uint64
 	var totalCount uint64
 	for k, v := range cc.GetStat() {
+		disk := ""
+		if v.Disk != nil && v.Disk.Count > 0 {
+			ft := time.UnixMicro(v.Disk.From).Format("15:04:05")
+			tt := time.UnixMicro(v.Disk.To).Format("15:04:05")
+			disk = fmt.Sprintf("[disk %s.%03d-%s.%03d][%s]", ft, v.Disk.From%1e6, tt, v.Disk.To%1e6, cc.printSize(v.Disk.Size))
+		}
 		if v.Size == 0 {
-			output += fmt.Sprintf("%012X: [0B]\n", k)
+			output += fmt.Sprintf("%012X: [0B]%s\n", k, disk)
 			continue
 		}
 		ft := time.UnixMicro(v.From).Format("15:04:05")
 		tt := time.UnixMicro(v.To).Format("15:04:05")
-		output += fmt.Sprintf("%012X: [%s.%03d-%s.%03d][%s]\n", k, ft, v.From%1e6, tt, v.To%1e6, cc.printSize(v.Size))
+		output += fmt.Sprintf("%012X: [%s.%03d-%s.%03d][%s]%s\n", k, ft, v.From%1e6, tt, v.To%1e6, cc.printSize(v.Size), disk)
 		totalSize += v.Size
 		totalCount++
 	}
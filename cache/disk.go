@@ -0,0 +1,468 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+const (
+	// diskRecordHeader is time(8) + size(4) + crc32 of data(4)
+	diskRecordHeader = 16
+	diskSegmentExt   = ".seg"
+	// diskMaxRecord is the max size of a record, larger sizes are treated as broken data
+	diskMaxRecord = 256 * 1024 * 1024
+)
+
+// PointCodec encodes data points of the disk tier
+type PointCodec interface {
+	Encode(dp IDataPoint) ([]byte, error)
+	Decode(id uint64, t int64, data []byte) (IDataPoint, error)
+}
+
+// DataPointCodec is the PointCodec of *DataPoint
+type DataPointCodec struct{}
+
+// Encode implements PointCodec
+func (DataPointCodec) Encode(dp IDataPoint) ([]byte, error) {
+	p, ok := dp.(*DataPoint)
+	if !ok {
+		return nil, fmt.Errorf("encode %T as DataPoint", dp)
+	}
+	return p.Data, nil
+}
+
+// Decode implements PointCodec
+func (DataPointCodec) Decode(id uint64, t int64, data []byte) (IDataPoint, error) {
+	return &DataPoint{ID: id, Time: time.UnixMicro(t), Data: data}, nil
+}
+
+// DiskConfig configures the disk tier
+type DiskConfig struct {
+	Path        string     // directory of segment files, a sub directory per sensor
+	Retention   int64      // us, segments ending before now-Retention are deleted, 0 keeps them
+	SegmentSize int64      // bytes, a new segment is started when the last one exceeds it, 64MB if not set
+	Codec       PointCodec // DataPointCodec if not set
+}
+
+// DiskStat is the stat of the points of a sensor on disk
+type DiskStat struct {
+	Segments int
+	Count    int
+	Size     uint64
+	From     int64 // us
+	To       int64 // us
+}
+
+type diskIndexEntry struct {
+	time   int64
+	offset int64
+	size   uint32
+}
+
+// diskSegment is a segment file of records, its name is the time of its first record.
+// Records are appended as they come, the index is kept in time order so a late record is indexed at its time.
+type diskSegment struct {
+	start int64
+	path  string
+	size  int64
+	index []diskIndexEntry
+}
+
+func (s *diskSegment) first() int64 {
+	if len(s.index) == 0 {
+		return s.start
+	}
+	return s.index[0].time
+}
+
+func (s *diskSegment) last() int64 {
+	if len(s.index) == 0 {
+		return s.start
+	}
+	return s.index[len(s.index)-1].time
+}
+
+// add indexes a record, after the records of the same time
+func (s *diskSegment) add(entry diskIndexEntry) {
+	if entry.time >= s.last() || len(s.index) == 0 {
+		s.index = append(s.index, entry)
+		return
+	}
+	i := sort.Search(len(s.index), func(i int) bool { return s.index[i].time > entry.time })
+	s.index = append(s.index, diskIndexEntry{})
+	copy(s.index[i+1:], s.index[i:])
+	s.index[i] = entry
+}
+
+// sensorDisk is the segments of a sensor, the last one is open to append
+type sensorDisk struct {
+	lock     sync.RWMutex
+	id       uint64
+	dir      string
+	segments []*diskSegment
+	active   *os.File
+	// broken is true if a failed write can not be truncated, the next write starts a new segment
+	broken bool
+}
+
+// DiskTier keeps data points in segment files per sensor, with a time index of each segment in memory.
+// Points of a sensor are appended to the last segment, a point older than the ones written is indexed at its time,
+// so the time ranges of segments may overlap. The index is rebuilt from the files when it is opened,
+// so the window written before a restart can be searched again.
+type DiskTier struct {
+	config  DiskConfig
+	logger  logging.ILogger
+	lock    sync.RWMutex
+	sensors map[uint64]*sensorDisk
+	closed  bool
+}
+
+// NewDiskTier opens the disk tier at config.Path and recovers the segments in it, broken tails are truncated
+func NewDiskTier(config DiskConfig, logger logging.ILogger) (*DiskTier, error) {
+	if config.Path == "" {
+		return nil, errors.New("disk tier path not set")
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = 64 * 1024 * 1024
+	}
+	if config.Codec == nil {
+		config.Codec = DataPointCodec{}
+	}
+	if err := os.MkdirAll(config.Path, 0o755); err != nil {
+		return nil, err
+	}
+	d := &DiskTier{
+		config:  config,
+		logger:  logger,
+		sensors: map[uint64]*sensorDisk{},
+	}
+	entries, err := os.ReadDir(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseUint(entry.Name(), 16, 64)
+		if err != nil {
+			continue
+		}
+		s, err := d.recover(id, filepath.Join(config.Path, entry.Name()))
+		if err != nil {
+			_ = d.Close()
+			return nil, fmt.Errorf("recover sensor %012X: %w", id, err)
+		}
+		d.sensors[id] = s
+	}
+	return d, nil
+}
+
+// recover loads the segments of a sensor
+func (d *DiskTier) recover(id uint64, dir string) (*sensorDisk, error) {
+	s := &sensorDisk{id: id, dir: dir}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, diskSegmentExt) {
+			continue
+		}
+		start, err := strconv.ParseInt(strings.TrimSuffix(name, diskSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, &diskSegment{start: start, path: filepath.Join(dir, name)})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].start < s.segments[j].start })
+	for _, segment := range s.segments {
+		if err := d.scan(segment); err != nil {
+			return nil, err
+		}
+	}
+	if n := len(s.segments); n > 0 {
+		s.active, err = os.OpenFile(s.segments[n-1].path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// scan builds the index of a segment from the record headers, the file is truncated after the last valid record
+func (d *DiskTier) scan(segment *diskSegment) error {
+	f, err := os.OpenFile(segment.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := make([]byte, diskRecordHeader)
+	var offset int64
+	for offset+diskRecordHeader <= info.Size() {
+		if _, err := f.ReadAt(header, offset); err != nil {
+			return err
+		}
+		t := int64(binary.BigEndian.Uint64(header[0:8]))
+		size := binary.BigEndian.Uint32(header[8:12])
+		if size > diskMaxRecord || offset+diskRecordHeader+int64(size) > info.Size() {
+			break
+		}
+		data := make([]byte, size)
+		if _, err := f.ReadAt(data, offset+diskRecordHeader); err != nil {
+			return err
+		}
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[12:16]) {
+			break
+		}
+		segment.add(diskIndexEntry{time: t, offset: offset, size: size})
+		offset += diskRecordHeader + int64(size)
+	}
+	segment.size = offset
+	if offset < info.Size() {
+		d.logger.Warnw("truncate broken segment", "path", segment.path, "size", info.Size(), "valid", offset)
+		return f.Truncate(offset)
+	}
+	return nil
+}
+
+// sensor returns the disk of a sensor, it is created if create is true
+func (d *DiskTier) sensor(id uint64, create bool) (*sensorDisk, error) {
+	d.lock.RLock()
+	s, ok := d.sensors[id]
+	closed := d.closed
+	d.lock.RUnlock()
+	if closed {
+		return nil, errors.New("disk tier closed")
+	}
+	if ok || !create {
+		return s, nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if s, ok = d.sensors[id]; ok {
+		return s, nil
+	}
+	s = &sensorDisk{id: id, dir: filepath.Join(d.config.Path, fmt.Sprintf("%012X", id))}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, err
+	}
+	d.sensors[id] = s
+	return s, nil
+}
+
+// Write appends points of a sensor, points are expected in time order but late ones are kept as well
+func (d *DiskTier) Write(id uint64, points []IDataPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	s, err := d.sensor(id, true)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	header := make([]byte, diskRecordHeader)
+	for _, dp := range points {
+		data, err := d.config.Codec.Encode(dp)
+		if err != nil {
+			return err
+		}
+		n := len(s.segments)
+		if n == 0 || s.broken || s.segments[n-1].size >= d.config.SegmentSize {
+			if err := s.rotate(dp.GetTime()); err != nil {
+				return err
+			}
+			n = len(s.segments)
+		}
+		segment := s.segments[n-1]
+		binary.BigEndian.PutUint64(header[0:8], uint64(dp.GetTime()))
+		binary.BigEndian.PutUint32(header[8:12], uint32(len(data)))
+		binary.BigEndian.PutUint32(header[12:16], crc32.ChecksumIEEE(data))
+		if _, err := s.active.Write(append(header, data...)); err != nil {
+			// cut off a partial record, so the next one is appended after the last valid record
+			if err0 := s.active.Truncate(segment.size); err0 != nil {
+				s.broken = true
+				return errors.Join(err, err0)
+			}
+			return err
+		}
+		segment.add(diskIndexEntry{time: dp.GetTime(), offset: segment.size, size: uint32(len(data))})
+		segment.size += diskRecordHeader + int64(len(data))
+	}
+	return nil
+}
+
+// rotate syncs the active segment and starts a new one
+func (s *sensorDisk) rotate(start int64) error {
+	if s.active != nil {
+		if err := s.active.Sync(); err != nil {
+			return err
+		}
+		if err := s.active.Close(); err != nil {
+			return err
+		}
+		s.active = nil
+	}
+	s.broken = false
+	segment := &diskSegment{start: start, path: filepath.Join(s.dir, fmt.Sprintf("%020d%s", start, diskSegmentExt))}
+	f, err := os.OpenFile(segment.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.active = f
+	s.segments = append(s.segments, segment)
+	return nil
+}
+
+// Search returns the points of request on disk in time order
+func (d *DiskTier) Search(request *SearchRequest) ([]IDataPoint, error) {
+	s, err := d.sensor(request.ID, false)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var result []IDataPoint
+	// segments overlap if late points are written, so every segment is checked and the points are merged
+	merged := 0
+	for _, segment := range s.segments {
+		if len(segment.index) == 0 || segment.first() > request.TimeTo || segment.last() < request.TimeFrom {
+			continue
+		}
+		j := sort.Search(len(segment.index), func(j int) bool { return segment.index[j].time >= request.TimeFrom })
+		points, err := d.read(s.id, segment, j, request.TimeTo)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) > 0 {
+			result = append(result, points...)
+			merged++
+		}
+	}
+	if merged > 1 {
+		sort.SliceStable(result, func(i, j int) bool { return result[i].GetTime() < result[j].GetTime() })
+	}
+	return result, nil
+}
+
+// read decodes the records of a segment from index j until TimeTo, the crc of every record is verified
+func (d *DiskTier) read(id uint64, segment *diskSegment, j int, timeTo int64) ([]IDataPoint, error) {
+	f, err := os.Open(segment.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var result []IDataPoint
+	for ; j < len(segment.index) && segment.index[j].time <= timeTo; j++ {
+		entry := segment.index[j]
+		record := make([]byte, diskRecordHeader+int64(entry.size))
+		if _, err := f.ReadAt(record, entry.offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data := record[diskRecordHeader:]
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(record[12:16]) {
+			return nil, fmt.Errorf("bad crc of record at %d in %s", entry.offset, segment.path)
+		}
+		dp, err := d.config.Codec.Decode(id, entry.time, data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, dp)
+	}
+	return result, nil
+}
+
+// Clean deletes segments of all sensors ending before now-Retention, now is in us
+func (d *DiskTier) Clean(now int64) {
+	if d.config.Retention <= 0 {
+		return
+	}
+	threshold := now - d.config.Retention
+	d.lock.RLock()
+	sensors := make([]*sensorDisk, 0, len(d.sensors))
+	for _, s := range d.sensors {
+		sensors = append(sensors, s)
+	}
+	d.lock.RUnlock()
+	for _, s := range sensors {
+		s.lock.Lock()
+		n := 0
+		for n < len(s.segments) && s.segments[n].last() < threshold {
+			if n == len(s.segments)-1 && s.active != nil {
+				_ = s.active.Close()
+				s.active = nil
+			}
+			if err := os.Remove(s.segments[n].path); err != nil {
+				d.logger.Warnw("remove segment", "path", s.segments[n].path, "error", err)
+			}
+			n++
+		}
+		s.segments = s.segments[n:]
+		s.lock.Unlock()
+	}
+}
+
+// Stat returns the stat of sensors on disk
+func (d *DiskTier) Stat() map[uint64]*DiskStat {
+	stat := map[uint64]*DiskStat{}
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	for id, s := range d.sensors {
+		s.lock.RLock()
+		st := &DiskStat{Segments: len(s.segments)}
+		for _, segment := range s.segments {
+			if len(segment.index) == 0 {
+				continue
+			}
+			if st.Count == 0 || segment.first() < st.From {
+				st.From = segment.first()
+			}
+			if st.Count == 0 || segment.last() > st.To {
+				st.To = segment.last()
+			}
+			st.Count += len(segment.index)
+			st.Size += uint64(segment.size)
+		}
+		s.lock.RUnlock()
+		stat[id] = st
+	}
+	return stat
+}
+
+// Close syncs and closes the segments
+func (d *DiskTier) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	var errs []error
+	for _, s := range d.sensors {
+		s.lock.Lock()
+		if s.active != nil {
+			errs = append(errs, s.active.Sync(), s.active.Close())
+			s.active = nil
+		}
+		s.lock.Unlock()
+	}
+	return errors.Join(errs...)
+}
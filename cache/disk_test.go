@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testPoints(id uint64, from time.Time, n int) []IDataPoint {
+	points := make([]IDataPoint, 0, n)
+	for i := 0; i < n; i++ {
+		points = append(points, &DataPoint{ID: id, Time: from.Add(time.Duration(i) * time.Second), Data: []byte{byte(i), 1, 2, 3}})
+	}
+	return points
+}
+
+func pointTimes(points []IDataPoint) []int64 {
+	times := make([]int64, 0, len(points))
+	for _, dp := range points {
+		times = append(times, dp.GetTime())
+	}
+	return times
+}
+
+func TestDiskTier(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	config := DiskConfig{Path: t.TempDir(), SegmentSize: 3 * (diskRecordHeader + 4)}
+	d, err := NewDiskTier(config, logger)
+	assert.Nil(t, err)
+	from := time.UnixMicro(1e12)
+	points := testPoints(1, from, 10)
+	assert.Nil(t, d.Write(1, points[:4]))
+	assert.Nil(t, d.Write(1, points[4:]))
+	assert.Equal(t, &DiskStat{Segments: 4, Count: 10, Size: 10 * (diskRecordHeader + 4), From: points[0].GetTime(), To: points[9].GetTime()}, d.Stat()[1])
+
+	search := func(d *DiskTier, from, to int) []IDataPoint {
+		result, err := d.Search(&SearchRequest{ID: 1, TimeFrom: points[from].GetTime(), TimeTo: points[to].GetTime()})
+		assert.Nil(t, err)
+		return result
+	}
+	assert.Equal(t, points[2:8], search(d, 2, 7))
+	assert.Equal(t, pointTimes(points), pointTimes(search(d, 0, 9)))
+	result, err := d.Search(&SearchRequest{ID: 2, TimeFrom: 0, TimeTo: points[9].GetTime()})
+	assert.Nil(t, err)
+	assert.Empty(t, result)
+	assert.Nil(t, d.Close())
+
+	// a torn record at the tail is truncated on recovery
+	s := d.sensors[1]
+	last := s.segments[len(s.segments)-1].path
+	f, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.Nil(t, err)
+	_, err = f.Write([]byte{0, 0, 0})
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	d, err = NewDiskTier(config, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, pointTimes(points[2:8]), pointTimes(search(d, 2, 7)))
+	info, err := os.Stat(last)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(diskRecordHeader+4), info.Size())
+	assert.Nil(t, d.Write(1, testPoints(1, from.Add(10*time.Second), 1)))
+	assert.Equal(t, 11, d.Stat()[1].Count)
+
+	// segments ending before the retention are deleted
+	d.config.Retention = int64(time.Second / time.Microsecond)
+	d.Clean(points[7].GetTime())
+	assert.Equal(t, pointTimes(points[6:10]), pointTimes(search(d, 0, 9)))
+	files, err := filepath.Glob(filepath.Join(config.Path, "000000000001", "*"+diskSegmentExt))
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+	assert.Nil(t, d.Close())
+}
+
+func TestDiskTierLatePoints(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	config := DiskConfig{Path: t.TempDir(), SegmentSize: 3 * (diskRecordHeader + 4)}
+	d, err := NewDiskTier(config, logger)
+	assert.Nil(t, err)
+	ms := func(n int64) int64 { return n * int64(time.Millisecond/time.Microsecond) }
+	search := func(d *DiskTier, from, to int64) []int64 {
+		result, err := d.Search(&SearchRequest{ID: 1, TimeFrom: ms(from), TimeTo: ms(to)})
+		assert.Nil(t, err)
+		return pointTimes(result)
+	}
+
+	// points at 10..14ms fill two segments, a late point at 5ms goes to the last one
+	var points []IDataPoint
+	for _, n := range []int64{10, 11, 12, 13, 14, 5} {
+		points = append(points, &DataPoint{ID: 1, Time: time.UnixMicro(ms(n)), Data: []byte{0, 1, 2, 3}})
+	}
+	assert.Nil(t, d.Write(1, points))
+	all := []int64{ms(5), ms(10), ms(11), ms(12), ms(13), ms(14)}
+	assert.Equal(t, all, search(d, 0, 20))
+	assert.Equal(t, []int64{ms(5)}, search(d, 4, 6))
+	assert.Equal(t, []int64{ms(12), ms(13)}, search(d, 12, 13))
+	stat := d.Stat()[1]
+	assert.Equal(t, ms(5), stat.From)
+	assert.Equal(t, ms(14), stat.To)
+	assert.Nil(t, d.Close())
+
+	// the index is kept in time order after recovery
+	d, err = NewDiskTier(config, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, all, search(d, 0, 20))
+	assert.Equal(t, []int64{ms(5)}, search(d, 4, 6))
+	assert.Nil(t, d.Close())
+}
+
+func TestDiskTierBrokenRecords(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	d, err := NewDiskTier(DiskConfig{Path: t.TempDir()}, logger)
+	assert.Nil(t, err)
+	defer d.Close()
+	from := time.UnixMicro(1e12)
+	points := testPoints(1, from, 4)
+	request := &SearchRequest{ID: 1, TimeFrom: points[0].GetTime(), TimeTo: points[3].GetTime()}
+	assert.Nil(t, d.Write(1, points[:2]))
+
+	// a failed write which can not be truncated is not indexed, the next write starts a new segment
+	s, _ := d.sensor(1, false)
+	active := s.active
+	s.active, err = os.Open(active.Name())
+	assert.Nil(t, err)
+	assert.NotNil(t, d.Write(1, points[2:3]))
+	assert.Nil(t, active.Close())
+	assert.Nil(t, d.Write(1, points[3:]))
+	assert.Equal(t, 2, d.Stat()[1].Segments)
+	result, err := d.Search(request)
+	assert.Nil(t, err)
+	assert.Equal(t, pointTimes(append(points[:2:2], points[3])), pointTimes(result))
+
+	// a record changed on disk is not returned
+	f, err := os.OpenFile(s.segments[0].path, os.O_WRONLY, 0o644)
+	assert.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, diskRecordHeader)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+	_, err = d.Search(request)
+	assert.NotNil(t, err)
+}
+
+func TestContainerDisk(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	config := DiskConfig{Path: t.TempDir()}
+	d, err := NewDiskTier(config, logger)
+	assert.Nil(t, err)
+	cc := NewDataCacheContainer(0, int64(time.Hour/time.Microsecond), false, logger)
+	cc.SetDiskTier(d)
+	cc.SetBudget(Budget{Total: 12})
+	cc.Start(false)
+
+	from := time.Now().Add(-time.Minute)
+	points := testPoints(1, from, 5)
+	points = append(points, testPoints(2, from.Add(500*time.Millisecond), 5)...)
+	for _, dp := range points {
+		cc.Input(dp)
+	}
+	request := &SearchRequest{ID: 1, TimeFrom: from.UnixMicro(), TimeTo: time.Now().UnixMicro()}
+	// the oldest points of both sensors are moved to disk
+	assert.Eventually(t, func() bool {
+		stat := cc.GetStat()
+		return stat[1].Size+stat[2].Size <= 12
+	}, 3*time.Second, 10*time.Millisecond)
+	stat := cc.GetStat()
+	assert.Equal(t, 5, stat[1].Count+stat[1].Disk.Count)
+	assert.Equal(t, 5, stat[2].Count+stat[2].Disk.Count)
+	assert.True(t, stat[1].Disk.Count > 0)
+	result, err := cc.Search(request)
+	assert.Nil(t, err)
+	assert.Equal(t, pointTimes(points[:5]), pointTimes(result))
+	cc.Stop()
+
+	// the window is recovered after a restart
+	d, err = NewDiskTier(config, logger)
+	assert.Nil(t, err)
+	cc = NewDataCacheContainer(0, int64(time.Hour/time.Microsecond), false, logger)
+	cc.SetDiskTier(d)
+	cc.Start(false)
+	defer cc.Stop()
+	result, err = cc.Search(request)
+	assert.Nil(t, err)
+	assert.Equal(t, pointTimes(points[:5]), pointTimes(result))
+	assert.Equal(t, points[0].(*DataPoint).Data, result[0].(*DataPoint).Data)
+}
+
+func TestContainerDiskLatePoint(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	d, err := NewDiskTier(DiskConfig{Path: t.TempDir()}, logger)
+	assert.Nil(t, err)
+	cc := NewDataCacheContainer(0, int64(time.Hour/time.Microsecond), false, logger)
+	cc.SetDiskTier(d)
+	cc.SetBudget(Budget{PerSensor: 16})
+	// the daemon is not started, budgets are enforced by the test
+	cc.running.Store(true)
+
+	from := time.Now().Add(-time.Minute)
+	points := testPoints(1, from, 10)
+	for _, dp := range points {
+		cc.Input(dp)
+	}
+	cc.enforceBudget()
+	stat := cc.GetStat()[1]
+	assert.Equal(t, 4, stat.Count)
+	assert.Equal(t, 6, stat.Disk.Count)
+
+	// a late point in memory is older than points on disk
+	late := &DataPoint{ID: 1, Time: from.Add(2500 * time.Millisecond), Data: []byte{0, 1, 2, 3}}
+	cc.Input(late)
+	result, err := cc.Search(&SearchRequest{ID: 1, TimeFrom: from.UnixMicro(), TimeTo: time.Now().UnixMicro()})
+	assert.Nil(t, err)
+	expected := append(append(pointTimes(points[:3]), late.GetTime()), pointTimes(points[3:])...)
+	assert.Equal(t, expected, pointTimes(result))
+}
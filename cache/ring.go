@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"sort"
+)
+
+// pointRing is a growable ring of points ordered by time, points are appended at the end and removed from the front
+type pointRing struct {
+	items []IDataPoint
+	head  int
+	n     int
+}
+
+func (r *pointRing) len() int {
+	return r.n
+}
+
+// at returns the i-th point from the front
+func (r *pointRing) at(i int) IDataPoint {
+	return r.items[(r.head+i)%len(r.items)]
+}
+
+func (r *pointRing) grow() {
+	size := len(r.items) * 2
+	if size == 0 {
+		size = 64
+	}
+	items := make([]IDataPoint, size)
+	r.copyTo(items, 0, r.n)
+	r.items = items
+	r.head = 0
+}
+
+// copyTo copies points [from, to) to dst
+func (r *pointRing) copyTo(dst []IDataPoint, from, to int) {
+	if from >= to {
+		return
+	}
+	start := (r.head + from) % len(r.items)
+	n := copy(dst, r.items[start:min(len(r.items), start+to-from)])
+	copy(dst[n:], r.items[:to-from-n])
+}
+
+// push adds a point, a point older than the last one is inserted in order
+func (r *pointRing) push(dp IDataPoint) {
+	if r.n == len(r.items) {
+		r.grow()
+	}
+	i := r.n
+	if r.n > 0 && dp.GetTime() < r.at(r.n-1).GetTime() {
+		i = r.search(dp.GetTime() + 1)
+	}
+	for j := r.n; j > i; j-- {
+		r.items[(r.head+j)%len(r.items)] = r.at(j - 1)
+	}
+	r.items[(r.head+i)%len(r.items)] = dp
+	r.n++
+}
+
+// search returns the index of the first point at or after t
+func (r *pointRing) search(t int64) int {
+	return sort.Search(r.n, func(i int) bool { return r.at(i).GetTime() >= t })
+}
+
+// between returns a copy of the points in [from, to]
+func (r *pointRing) between(from, to int64) []IDataPoint {
+	i := r.search(from)
+	j := r.search(to + 1)
+	if i >= j {
+		return nil
+	}
+	result := make([]IDataPoint, j-i)
+	r.copyTo(result, i, j)
+	return result
+}
+
+// popFront removes the first n points
+func (r *pointRing) popFront(n int) {
+	for i := 0; i < n; i++ {
+		r.items[(r.head+i)%len(r.items)] = nil
+	}
+	if r.n -= n; r.n == 0 {
+		r.head = 0
+		return
+	}
+	r.head = (r.head + n) % len(r.items)
+}
+
+// clear removes all points and releases the memory
+func (r *pointRing) clear() {
+	*r = pointRing{}
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointRing(t *testing.T) {
+	r := &pointRing{}
+	from := time.UnixMicro(1e12)
+	points := testPoints(1, from, 300)
+	for _, dp := range points[:100] {
+		r.push(dp)
+	}
+	// wrap around the end of items
+	r.popFront(90)
+	for _, dp := range points[100:150] {
+		r.push(dp)
+	}
+	assert.Equal(t, 60, r.len())
+	assert.Equal(t, 128, len(r.items))
+	assert.Equal(t, points[95:121], r.between(points[95].GetTime(), points[120].GetTime()))
+	assert.Equal(t, points[90:150], r.between(0, points[299].GetTime()))
+	assert.Empty(t, r.between(points[150].GetTime(), points[299].GetTime()))
+	assert.Empty(t, r.between(points[100].GetTime()+1, points[101].GetTime()-1))
+
+	// grow keeps the order
+	for _, dp := range points[150:] {
+		r.push(dp)
+	}
+	assert.Equal(t, points[90:], r.between(0, points[299].GetTime()))
+
+	// an old point is inserted in order
+	late := &DataPoint{ID: 1, Time: points[100].(*DataPoint).Time.Add(time.Millisecond)}
+	r.push(late)
+	assert.Equal(t, []IDataPoint{points[100], late, points[101]}, r.between(points[100].GetTime(), points[101].GetTime()))
+
+	r.popFront(r.len())
+	assert.Equal(t, 0, r.len())
+	assert.Empty(t, r.between(0, points[299].GetTime()))
+}
+
+func TestDataCacheSearch(t *testing.T) {
+	c := NewDataCache(1, int64(time.Hour/time.Microsecond))
+	from := time.Now().Add(-time.Minute)
+	points := testPoints(1, from, 10)
+	for _, dp := range points {
+		c.input(dp)
+	}
+	assert.Equal(t, points[3:6], c.search(&SearchRequest{ID: 1, TimeFrom: points[3].GetTime(), TimeTo: points[5].GetTime()}))
+	assert.Equal(t, uint64(40), c.size())
+
+	// inputs go on while searching
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for _, dp := range testPoints(1, from.Add(10*time.Second), 1000) {
+			c.input(dp)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			assert.Equal(t, points[3:6], c.search(&SearchRequest{ID: 1, TimeFrom: points[3].GetTime(), TimeTo: points[5].GetTime()}))
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, 1010, c.stat().Count)
+
+	c.expire = int64(30500 * time.Millisecond / time.Microsecond)
+	idle, err := c.cleanTimeout(0, nil)
+	assert.Nil(t, err)
+	assert.False(t, idle)
+	stat := c.stat()
+	assert.Equal(t, 1010-30, stat.Count)
+	assert.Equal(t, uint64(4*(1010-30)), stat.Size)
+
+	// a late point does not move the end back
+	late := &DataPoint{ID: 1, Time: from.Add(40500 * time.Millisecond), Data: []byte{0, 1, 2, 3}}
+	c.input(late)
+	assert.Equal(t, stat.To, c.stat().To)
+	assert.Equal(t, late, c.search(&SearchRequest{ID: 1, TimeFrom: late.GetTime(), TimeTo: late.GetTime()})[0])
+}
+
+func BenchmarkDataCacheSearch(b *testing.B) {
+	from := time.UnixMicro(1e12)
+	for _, n := range []int{1e3, 1e4, 1e5, 1e6} {
+		c := NewDataCache(1, 0)
+		for i := 0; i < n; i++ {
+			c.input(&DataPoint{ID: 1, Time: from.Add(time.Duration(i) * time.Millisecond)})
+		}
+		// 10 points in the middle
+		request := &SearchRequest{ID: 1, TimeFrom: from.Add(time.Duration(n/2) * time.Millisecond).UnixMicro()}
+		request.TimeTo = request.TimeFrom + 9*1000
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if len(c.search(request)) != 10 {
+					b.Fatal("wrong result")
+				}
+			}
+		})
+	}
+}
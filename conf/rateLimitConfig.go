@@ -0,0 +1,61 @@
+package conf
+
+import "github.com/spf13/viper"
+
+const (
+	rateLimitStore    = "rateLimit.store"
+	rateLimitPolicies = "rateLimit.policies"
+
+	// RateLimitStoreMemory counts requests in memory of each replica
+	RateLimitStoreMemory = "memory"
+	// RateLimitStoreRedis counts requests in redis shared by all replicas, redis component is required
+	RateLimitStoreRedis = "redis"
+
+	// RateLimitKeySensor counts requests of each sensorid query param, or of each client ip if it is empty
+	RateLimitKeySensor = "sensor"
+	// RateLimitKeyIP counts requests of each client ip
+	RateLimitKeyIP = "ip"
+	// RateLimitKeyRoute counts all requests of the policy together
+	RateLimitKeyRoute = "route"
+)
+
+var defaultRateLimitConfig = RateLimitConfig{
+	Store: RateLimitStoreMemory,
+}
+
+// RateLimitQuota is the request rate allowed for one key
+type RateLimitQuota struct {
+	Rate  float64 `toml:"rate" json:"rate"`   // requests per second, zero means no limit
+	Burst int     `toml:"burst" json:"burst"` // max requests at once
+}
+
+// RateLimitPolicy limits the requests to routes under a path prefix
+type RateLimitPolicy struct {
+	Name    string                    `toml:"name" json:"name"`                 // name of the policy, used as metric label and store key prefix. path if empty
+	Path    string                    `toml:"path" json:"path"`                 // path prefix of routes including apiRoot, the longest matched one takes effect
+	Method  string                    `toml:"method" json:"method,omitempty"`   // http method, all methods if empty
+	Key     string                    `toml:"key" json:"key,omitempty"`         // sensor(default), ip or route
+	Rate    float64                   `toml:"rate" json:"rate"`                 // requests per second of each key, zero means no limit
+	Burst   int                       `toml:"burst" json:"burst"`               // max requests at once of each key
+	Sensors map[string]RateLimitQuota `toml:"sensors" json:"sensors,omitempty"` // quota of given sensor ids, override rate and burst of the policy
+}
+
+// RateLimitConfig is the rate limit config of http api, requests matching no policy are limited by basic apiRate and apiBurst
+type RateLimitConfig struct {
+	Store    string            `toml:"store" json:"store,omitempty"` // memory or redis, requires restart
+	Policies []RateLimitPolicy `toml:"policies" json:"policies,omitempty"`
+}
+
+// SetDefaultRateLimitConfig set default rate limit configuration
+func SetDefaultRateLimitConfig() {
+	viper.SetDefault(rateLimitStore, defaultRateLimitConfig.Store)
+}
+
+// GetRateLimitConfig get rate limit configuration
+func GetRateLimitConfig() (*RateLimitConfig, error) {
+	config := &RateLimitConfig{
+		Store: viper.GetString(rateLimitStore),
+	}
+	err := viper.UnmarshalKey(rateLimitPolicies, &config.Policies)
+	return config, err
+}
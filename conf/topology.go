@@ -64,6 +64,8 @@ type NodeConfig struct {
 	DataTransfer *DataTransferConfig `json:"data_transfer,omitempty"`
 	// APM configuration
 	APM *APMConfig `json:"apm,omitempty"`
+	// RateLimit of http api, replaces the policies in config file
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
 }
 
 // HardwareConfig defines the hardware configuration of the node
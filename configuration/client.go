@@ -1,12 +1,19 @@
 package configuration
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"log"
+	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/nacos-group/nacos-sdk-go/clients"
 	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/common/http_agent"
+	"github.com/nacos-group/nacos-sdk-go/common/nacos_server"
 	"github.com/nacos-group/nacos-sdk-go/model"
 	"github.com/nacos-group/nacos-sdk-go/vo"
 )
@@ -15,6 +22,13 @@ import (
 type NacosClient struct {
 	Client       config_client.IConfigClient
 	NamingClient naming_client.INamingClient
+
+	clientConfig  constant.ClientConfig
+	serverConfigs []constant.ServerConfig
+	// configServer sends requests not supported by Client, it is created on first use
+	configServer     *nacos_server.NacosServer
+	configServerErr  error
+	configServerOnce sync.Once
 }
 
 //NewNacos New Nacos Client
@@ -36,7 +50,12 @@ func NewNacos(clientConfig constant.ClientConfig, serverConfigs []constant.Serve
 		return nil, err
 	}
 
-	return &NacosClient{Client: client, NamingClient: namingClient}, nil
+	return &NacosClient{
+		Client:        client,
+		NamingClient:  namingClient,
+		clientConfig:  clientConfig,
+		serverConfigs: serverConfigs,
+	}, nil
 }
 
 // Publish Config
@@ -52,6 +71,51 @@ func (c *NacosClient) Publish(dataID, group, content string) (bool, error) {
 	return ok, err
 }
 
+// PublishCas publishes config only if the md5 of its content on the server is casMd5, it returns false if
+// the content was changed. Nacos 1.4 or later is required. If the config does not exist, it is created
+// unless another client creates it at the same time, so the md5 of an empty content can be used to create only.
+func (c *NacosClient) PublishCas(dataID, group, content, casMd5 string) (bool, error) {
+	timeoutMs := c.clientConfig.TimeoutMs
+	if timeoutMs == 0 {
+		timeoutMs = 10000
+	}
+	c.configServerOnce.Do(func() {
+		c.configServer, c.configServerErr = nacos_server.NewNacosServer(c.serverConfigs, c.clientConfig,
+			&http_agent.HttpAgent{}, timeoutMs, c.clientConfig.Endpoint)
+	})
+	if c.configServerErr != nil {
+		return false, c.configServerErr
+	}
+	params := map[string]string{
+		"dataId":  dataID,
+		"group":   group,
+		"content": content,
+	}
+	if c.clientConfig.NamespaceId != "" {
+		params["tenant"] = c.clientConfig.NamespaceId
+	}
+	headers := map[string]string{
+		"casMd5":    casMd5,
+		"accessKey": c.clientConfig.AccessKey,
+		"secretKey": c.clientConfig.SecretKey,
+	}
+	result, err := c.configServer.ReqConfigApi(constant.CONFIG_PATH, params, headers, http.MethodPost, timeoutMs)
+	if err != nil {
+		// the server rejects a publish of a changed config with an error
+		if current, getErr := c.Get(dataID, group); getErr == nil && ContentMd5(current) != casMd5 && current != content {
+			return false, nil
+		}
+		return false, err
+	}
+	return strings.TrimSpace(strings.ToLower(result)) == "true", nil
+}
+
+// ContentMd5 returns the md5 of config content as nacos, for PublishCas
+func ContentMd5(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
 // Get Config
 func (c *NacosClient) Get(dataID, group string) (string, error) {
 	return c.Client.GetConfig(vo.ConfigParam{
@@ -0,0 +1,258 @@
+package framedata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	grpcPrometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/protobuf/pb"
+	"github.com/kiga-hub/arc/tracing"
+)
+
+// ErrClientClosed is returned by Send after Close
+var ErrClientClosed = errors.New("frame data client closed")
+
+// ClientStats is the stats of the client
+type ClientStats struct {
+	Target    string `json:"target"`
+	Connected bool   `json:"connected"` // a stream is open
+	Queued    int    `json:"queued"`    // frames waiting to be sent
+	Pending   int64  `json:"pending"`   // frames sent and not acknowledged
+	Sent      uint64 `json:"sent"`      // frames sent the first time
+	Resent    uint64 `json:"resent"`    // frames sent again after a stream failed
+	Acked     uint64 `json:"acked"`     // frames acknowledged by the server
+	Failures  uint64 `json:"failures"`  // streams failed
+}
+
+// Client streams frames to a FrameData service.
+// Frames are sent in streams of at most config.StreamFrames frames, a stream is acknowledged when it is closed,
+// and the frames of a failed stream are sent again in a new one, so frames are delivered at least once.
+// Send blocks when config.QueueSize frames are waiting, so a slow server pushes back on the caller.
+type Client struct {
+	config    *Config
+	logger    func() logging.ILogger
+	tracer    opentracing.Tracer
+	conn      *grpc.ClientConn
+	client    pb.FrameDataClient
+	queue     chan *pb.FrameDataRequest
+	ctx       context.Context // streams are canceled if Close gives up draining
+	cancel    context.CancelFunc
+	started   atomic.Bool
+	closed    atomic.Bool
+	stop      chan struct{}
+	done      chan struct{}
+	connected atomic.Bool
+	pending   atomic.Int64
+	sent      atomic.Uint64
+	resent    atomic.Uint64
+	acked     atomic.Uint64
+	failures  atomic.Uint64
+}
+
+// NewClient create a Client of config.Target, it connects in background
+func NewClient(config *Config, logger func() logging.ILogger) (*Client, error) {
+	conn, err := grpc.Dial(config.Target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStreamInterceptor(grpcPrometheus.StreamClientInterceptor),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("dial frame data %s: %w", config.Target, err)
+	}
+	queueSize := config.QueueSize
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	c := &Client{
+		config: config,
+		logger: logger,
+		conn:   conn,
+		client: pb.NewFrameDataClient(conn),
+		queue:  make(chan *pb.FrameDataRequest, queueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c, nil
+}
+
+// SetTracer sets the tracer to propagate spans of streams to the server, it must be called before Start
+func (c *Client) SetTracer(tracer opentracing.Tracer) {
+	c.tracer = tracer
+}
+
+// Start sends frames in background
+func (c *Client) Start() {
+	if c.started.CompareAndSwap(false, true) {
+		go c.run()
+	}
+}
+
+// Send queues a frame, it blocks if the queue is full until ctx is done.
+// key and value must not be modified after that.
+func (c *Client) Send(ctx context.Context, key, value []byte) error {
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+	req := &pb.FrameDataRequest{Key: key, Value: value}
+	select {
+	case c.queue <- req:
+		return nil
+	case <-c.stop:
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the stats of the client
+func (c *Client) Stats() *ClientStats {
+	return &ClientStats{
+		Target:    c.config.Target,
+		Connected: c.connected.Load(),
+		Queued:    len(c.queue),
+		Pending:   c.pending.Load(),
+		Sent:      c.sent.Load(),
+		Resent:    c.resent.Load(),
+		Acked:     c.acked.Load(),
+		Failures:  c.failures.Load(),
+	}
+}
+
+// Close sends the queued frames and waits for them to be acknowledged until ctx is done.
+// Frames sent concurrently with Close may be dropped.
+func (c *Client) Close(ctx context.Context) error {
+	if c.closed.CompareAndSwap(false, true) {
+		close(c.stop)
+	}
+	if c.started.Load() {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			c.cancel()
+			<-c.done
+		}
+	}
+	c.cancel()
+	err := c.conn.Close()
+	if dropped := int64(len(c.queue)) + c.pending.Load(); dropped > 0 {
+		return fmt.Errorf("drop %d frames not acknowledged", dropped)
+	}
+	return err
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+	var pending []*pb.FrameDataRequest
+	interval := time.Duration(c.config.ReconnectInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Second
+	}
+	maxBackoff := time.Duration(c.config.MaxReconnectInterval) * time.Millisecond
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+	backoff := interval
+	for {
+		stopped, err := c.stream(&pending)
+		c.connected.Store(false)
+		if err == nil {
+			if stopped {
+				return
+			}
+			backoff = interval
+			continue
+		}
+		c.failures.Inc()
+		c.logger().Warnw("stream frame data", "target", c.config.Target, "pending", len(pending), "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-c.ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// stream sends the pending frames again and then the queued ones in a stream until it is acknowledged.
+// It returns true if the client is stopped and all frames are acknowledged.
+func (c *Client) stream(pending *[]*pb.FrameDataRequest) (bool, error) {
+	ctx, finish, _ := tracing.GetGRPCClientSpan(c.ctx, c.logger(), c.tracer, operationName, false)
+	defer finish()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.FrameDataCallback(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.connected.Store(true)
+	for _, req := range *pending {
+		if err := stream.Send(req); err != nil {
+			return false, err
+		}
+		c.resent.Inc()
+	}
+
+	send := func(req *pb.FrameDataRequest) error {
+		*pending = append(*pending, req)
+		c.pending.Inc()
+		c.sent.Inc()
+		return stream.Send(req)
+	}
+	ack := func() error {
+		resp, err := stream.CloseAndRecv()
+		if err != nil {
+			return err
+		}
+		if !resp.GetSuccessed() {
+			return fmt.Errorf("frame data not acknowledged")
+		}
+		c.acked.Add(uint64(len(*pending)))
+		c.pending.Sub(int64(len(*pending)))
+		*pending = (*pending)[:0]
+		return nil
+	}
+
+	flushInterval := time.Duration(c.config.FlushInterval) * time.Millisecond
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+	timer := time.NewTimer(flushInterval)
+	defer timer.Stop()
+	for {
+		if c.config.StreamFrames > 0 && len(*pending) >= c.config.StreamFrames {
+			return false, ack()
+		}
+		select {
+		case req := <-c.queue:
+			if err := send(req); err != nil {
+				return false, err
+			}
+		case <-timer.C:
+			if len(*pending) > 0 {
+				return false, ack()
+			}
+			timer.Reset(flushInterval)
+		case <-c.stop:
+			select {
+			case req := <-c.queue:
+				if err := send(req); err != nil {
+					return false, err
+				}
+				continue
+			default:
+			}
+			return true, ack()
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package framedata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+// received collects frames handled by a server
+type received struct {
+	lock   sync.Mutex
+	values []string
+	fail   map[string]bool // values failing once
+}
+
+func (r *received) handle(ctx context.Context, key, value []byte) error {
+	_ = ctx
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.fail[string(value)] {
+		delete(r.fail, string(value))
+		return errors.New("handler failed")
+	}
+	r.values = append(r.values, string(key)+"="+string(value))
+	return nil
+}
+
+func (r *received) get() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.values...)
+}
+
+func testConfig() Config {
+	config := defaultConfig
+	config.Address = "127.0.0.1:0"
+	config.StreamFrames = 3
+	config.FlushInterval = 50
+	config.ReconnectInterval = 10
+	config.MaxReconnectInterval = 50
+	return config
+}
+
+func testLogger() func() logging.ILogger {
+	logger := zap.NewNop().Sugar()
+	return func() logging.ILogger { return logger }
+}
+
+func startServer(t *testing.T, config Config, r *received) *Server {
+	s := NewServer(&config, testLogger())
+	s.SetHandler(r.handle)
+	assert.Nil(t, s.Start())
+	return s
+}
+
+func sendFrames(t *testing.T, c *Client, n int) []string {
+	var want []string
+	for i := 0; i < n; i++ {
+		value := fmt.Sprint(i)
+		assert.Nil(t, c.Send(context.Background(), []byte("k"), []byte(value)))
+		want = append(want, "k="+value)
+	}
+	return want
+}
+
+func TestClientServer(t *testing.T) {
+	config := testConfig()
+	r := &received{}
+	s := startServer(t, config, r)
+	defer s.Close(context.Background())
+
+	tracer := mocktracer.New()
+	s.SetTracer(tracer)
+	config.Target = s.Addr().String()
+	c, err := NewClient(&config, testLogger())
+	assert.Nil(t, err)
+	c.SetTracer(tracer)
+	c.Start()
+
+	want := sendFrames(t, c, 10)
+	// acknowledged by the flush interval
+	assert.Eventually(t, func() bool { return c.Stats().Acked == 10 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, want, r.get())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, c.Close(ctx))
+	assert.Equal(t, ErrClientClosed, c.Send(context.Background(), nil, nil))
+	stats := c.Stats()
+	assert.Equal(t, uint64(10), stats.Sent)
+	assert.Equal(t, uint64(0), stats.Failures)
+	assert.Equal(t, uint64(10), s.Stats().Frames)
+
+	// the spans of server streams are children of client streams
+	spans := map[int]*mocktracer.MockSpan{}
+	for _, span := range tracer.FinishedSpans() {
+		spans[span.SpanContext.SpanID] = span
+	}
+	servers := 0
+	for _, span := range spans {
+		if fmt.Sprint(span.Tag("span.kind")) == "server" {
+			servers++
+			assert.Equal(t, "client", fmt.Sprint(spans[span.ParentID].Tag("span.kind")))
+		}
+	}
+	assert.True(t, servers >= 4)
+}
+
+func TestClientReconnect(t *testing.T) {
+	// reserve an address the server listens on later
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	config := testConfig()
+	config.Address = l.Addr().String()
+	config.Target = config.Address
+	assert.Nil(t, l.Close())
+
+	c, err := NewClient(&config, testLogger())
+	assert.Nil(t, err)
+	c.Start()
+	want := sendFrames(t, c, 5)
+	assert.Eventually(t, func() bool { return c.Stats().Failures > 0 }, time.Second, 10*time.Millisecond)
+
+	r := &received{fail: map[string]bool{"4": true}}
+	s := startServer(t, config, r)
+	defer s.Close(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, c.Close(ctx))
+
+	// the failed stream of 3, 4 is sent again
+	assert.Equal(t, append(want[:4:4], "k=3", "k=4"), r.get())
+	stats := c.Stats()
+	assert.Equal(t, uint64(5), stats.Acked)
+	assert.Equal(t, uint64(2), stats.Resent)
+	assert.Equal(t, uint64(1), s.Stats().HandlerErrors)
+}
+
+func TestClientCloseTimeout(t *testing.T) {
+	config := testConfig()
+	config.Target = "127.0.0.1:1"
+	c, err := NewClient(&config, testLogger())
+	assert.Nil(t, err)
+	c.Start()
+	sendFrames(t, c, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, c.Close(ctx))
+}
+
+func TestClientReconnectInterval(t *testing.T) {
+	config := testConfig()
+	config.Target = "127.0.0.1:1"
+	config.ReconnectInterval = 0
+	config.MaxReconnectInterval = 0
+	c, err := NewClient(&config, testLogger())
+	assert.Nil(t, err)
+	c.Start()
+	sendFrames(t, c, 2)
+	assert.Eventually(t, func() bool { return c.Stats().Failures > 0 }, time.Second, 10*time.Millisecond)
+
+	// a failing stream is not retried in a tight loop without reconnect interval
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, uint64(1), c.Stats().Failures)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, c.Close(ctx))
+}
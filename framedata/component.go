@@ -0,0 +1,119 @@
+package framedata
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/kiga-hub/arc/micro"
+	"github.com/kiga-hub/arc/tracing"
+)
+
+var (
+	// ServerElementKey is ElementKey for the FrameData server
+	ServerElementKey = micro.ElementKey("FrameDataServerComponent")
+	// ClientElementKey is ElementKey for the FrameData client
+	ClientElementKey = micro.ElementKey("FrameDataClientComponent")
+)
+
+// Component is Component for FrameData, it serves the FrameData service if enabled,
+// and streams frames to config.Target if it is set.
+// The handler of the server is set by Handler, or by components calling (*Server).SetHandler in Init.
+// Spans are propagated by the tracer of the tracing component if it is set up.
+type Component struct {
+	micro.EmptyComponent
+	Handler Handler
+	server  *Server
+	client  *Client
+	tracer  func() opentracing.Tracer
+}
+
+// Name of the component
+func (c *Component) Name() string {
+	return "FrameData"
+}
+
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"framedata"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ServerElementKey, &ClientElementKey}
+}
+
+// PreInit called before Init()
+func (c *Component) PreInit(ctx context.Context) error {
+	_ = ctx
+	// load config
+	SetDefaultConfig()
+	return nil
+}
+
+// Init the component
+func (c *Component) Init(server *micro.Server) error {
+	conf := GetConfig()
+	logger := micro.GenerateLoggerForModule(server, "framedata")
+	// the tracing component may be initialized after this one
+	c.tracer = func() opentracing.Tracer {
+		tracer, _ := server.GetElement(&tracing.ElementKey).(opentracing.Tracer)
+		return tracer
+	}
+	if conf.Enable {
+		c.server = NewServer(conf, logger)
+		if c.Handler != nil {
+			c.server.SetHandler(c.Handler)
+		}
+		server.RegisterElement(&ServerElementKey, c.server)
+	}
+	if conf.Target != "" {
+		client, err := NewClient(conf, logger)
+		if err != nil {
+			return err
+		}
+		c.client = client
+		server.RegisterElement(&ClientElementKey, c.client)
+	}
+	return nil
+}
+
+// Status of the component
+func (c *Component) Status() *micro.ComponentStatus {
+	status := c.EmptyComponent.Status()
+	if c.server != nil {
+		status.Params["server"] = c.server.Stats()
+	}
+	if c.client != nil {
+		status.Params["client"] = c.client.Stats()
+	}
+	return status
+}
+
+// Start the component
+func (c *Component) Start(ctx context.Context) error {
+	_ = ctx
+	tracer := c.tracer()
+	if c.server != nil {
+		c.server.SetTracer(tracer)
+		if err := c.server.Start(); err != nil {
+			return err
+		}
+	}
+	if c.client != nil {
+		c.client.SetTracer(tracer)
+		c.client.Start()
+	}
+	return nil
+}
+
+// Stop the component, queued frames are sent before ctx is done
+func (c *Component) Stop(ctx context.Context) error {
+	if c.server != nil {
+		c.server.Close(ctx)
+	}
+	if c.client != nil {
+		return c.client.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package framedata
+
+import "github.com/spf13/viper"
+
+const (
+	framedataEnable               = "framedata.enable"
+	framedataAddress              = "framedata.address"
+	framedataMaxRecvMsgSize       = "framedata.maxRecvMsgSize"
+	framedataTarget               = "framedata.target"
+	framedataQueueSize            = "framedata.queueSize"
+	framedataStreamFrames         = "framedata.streamFrames"
+	framedataFlushInterval        = "framedata.flushInterval"
+	framedataReconnectInterval    = "framedata.reconnectInterval"
+	framedataMaxReconnectInterval = "framedata.maxReconnectInterval"
+)
+
+var defaultConfig = Config{
+	Enable:               false,
+	Address:              ":8974",
+	MaxRecvMsgSize:       4 * 1024 * 1024,
+	Target:               "",
+	QueueSize:            1024,
+	StreamFrames:         1000,
+	FlushInterval:        1000,
+	ReconnectInterval:    1000,
+	MaxReconnectInterval: 30000,
+}
+
+// Config FrameData configuration
+type Config struct {
+	Enable         bool   `toml:"enable"`         // serve the FrameData service
+	Address        string `toml:"address"`        // listen address of the service
+	MaxRecvMsgSize int    `toml:"maxRecvMsgSize"` // bytes, max size of a received frame
+
+	Target               string `toml:"target"`               // address of the FrameData service to stream frames to, empty disables the client
+	QueueSize            int    `toml:"queueSize"`            // frames queued to send, Send blocks if it is full
+	StreamFrames         int    `toml:"streamFrames"`         // frames sent in a stream before it is closed and acknowledged, they are sent again if it fails
+	FlushInterval        int    `toml:"flushInterval"`        // ms, the stream is closed and acknowledged at least every interval if frames are sent
+	ReconnectInterval    int    `toml:"reconnectInterval"`    // ms, wait before opening a stream again after an error, doubled on each error, 1s if not set
+	MaxReconnectInterval int    `toml:"maxReconnectInterval"` // ms, max wait before opening a stream again, at least reconnectInterval
+}
+
+// SetDefaultConfig -
+func SetDefaultConfig() {
+	viper.SetDefault(framedataEnable, defaultConfig.Enable)
+	viper.SetDefault(framedataAddress, defaultConfig.Address)
+	viper.SetDefault(framedataMaxRecvMsgSize, defaultConfig.MaxRecvMsgSize)
+	viper.SetDefault(framedataTarget, defaultConfig.Target)
+	viper.SetDefault(framedataQueueSize, defaultConfig.QueueSize)
+	viper.SetDefault(framedataStreamFrames, defaultConfig.StreamFrames)
+	viper.SetDefault(framedataFlushInterval, defaultConfig.FlushInterval)
+	viper.SetDefault(framedataReconnectInterval, defaultConfig.ReconnectInterval)
+	viper.SetDefault(framedataMaxReconnectInterval, defaultConfig.MaxReconnectInterval)
+}
+
+// GetConfig -
+func GetConfig() *Config {
+	return &Config{
+		Enable:               viper.GetBool(framedataEnable),
+		Address:              viper.GetString(framedataAddress),
+		MaxRecvMsgSize:       viper.GetInt(framedataMaxRecvMsgSize),
+		Target:               viper.GetString(framedataTarget),
+		QueueSize:            viper.GetInt(framedataQueueSize),
+		StreamFrames:         viper.GetInt(framedataStreamFrames),
+		FlushInterval:        viper.GetInt(framedataFlushInterval),
+		ReconnectInterval:    viper.GetInt(framedataReconnectInterval),
+		MaxReconnectInterval: viper.GetInt(framedataMaxReconnectInterval),
+	}
+}
@@ -0,0 +1,159 @@
+package framedata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	grpcPrometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/protobuf/pb"
+	"github.com/kiga-hub/arc/tracing"
+)
+
+// operationName is the span operation of FrameData streams
+const operationName = "FrameData/FrameDataCallback"
+
+// Handler handles a key/value pair received by the server, the stream fails if it returns an error
+// and the client sends the frames of the stream again. It is called by the goroutines of streams concurrently.
+type Handler func(ctx context.Context, key, value []byte) error
+
+// ServerStats is the stats of the server
+type ServerStats struct {
+	Address       string `json:"address"`
+	ActiveStreams int64  `json:"active_streams"`
+	Streams       uint64 `json:"streams"`        // streams accepted
+	Frames        uint64 `json:"frames"`         // frames handled
+	HandlerErrors uint64 `json:"handler_errors"` // streams failed by the handler
+}
+
+// Server serves the FrameData service
+type Server struct {
+	config        *Config
+	logger        func() logging.ILogger
+	handler       Handler
+	tracer        opentracing.Tracer
+	grpc          *grpc.Server
+	listener      net.Listener
+	activeStreams atomic.Int64
+	streams       atomic.Uint64
+	frames        atomic.Uint64
+	handlerErrors atomic.Uint64
+}
+
+// NewServer create a Server
+func NewServer(config *Config, logger func() logging.ILogger) *Server {
+	s := &Server{
+		config: config,
+		logger: logger,
+	}
+	opts := []grpc.ServerOption{
+		grpc.StreamInterceptor(grpcPrometheus.StreamServerInterceptor),
+	}
+	if config.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(config.MaxRecvMsgSize))
+	}
+	s.grpc = grpc.NewServer(opts...)
+	pb.RegisterFrameDataServer(s.grpc, s)
+	return s
+}
+
+// SetHandler sets the handler of key/value pairs, it must be called before Start
+func (s *Server) SetHandler(handler Handler) {
+	s.handler = handler
+}
+
+// SetTracer sets the tracer to continue the spans of clients, it must be called before Start
+func (s *Server) SetTracer(tracer opentracing.Tracer) {
+	s.tracer = tracer
+}
+
+// Start listens and serves in background
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	go func() {
+		err := s.grpc.Serve(l)
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.logger().Errorw("serve frame data", "error", err)
+		}
+	}()
+	s.logger().Infow("start frame data server", "addr", l.Addr().String())
+	return nil
+}
+
+// Addr returns the listen address, nil if it is not started
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// FrameDataCallback implements pb.FrameDataServer
+func (s *Server) FrameDataCallback(stream pb.FrameData_FrameDataCallbackServer) error {
+	if s.handler == nil {
+		return status.Error(codes.Unimplemented, "no frame data handler")
+	}
+	s.streams.Inc()
+	s.activeStreams.Inc()
+	defer s.activeStreams.Dec()
+
+	ctx, finish, logger := tracing.GetGRPCServerSpan(stream.Context(), s.logger(), s.tracer, operationName, false)
+	defer finish()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&pb.FrameDataResponse{Successed: true})
+		}
+		if err != nil {
+			return err
+		}
+		err = s.handler(ctx, req.GetKey(), req.GetValue())
+		if err != nil {
+			s.handlerErrors.Inc()
+			logger.Warnw("handle frame data", "error", err)
+			return status.Error(codes.Internal, err.Error())
+		}
+		s.frames.Inc()
+	}
+}
+
+// Stats returns the stats of the server
+func (s *Server) Stats() *ServerStats {
+	stats := &ServerStats{
+		ActiveStreams: s.activeStreams.Load(),
+		Streams:       s.streams.Load(),
+		Frames:        s.frames.Load(),
+		HandlerErrors: s.handlerErrors.Load(),
+	}
+	if addr := s.Addr(); addr != nil {
+		stats.Address = addr.String()
+	}
+	return stats
+}
+
+// Close stops the server gracefully, streams are closed at once if ctx is done before they end
+func (s *Server) Close(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+		<-done
+	}
+}
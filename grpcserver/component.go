@@ -0,0 +1,100 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/kiga-hub/arc/micro"
+	"github.com/kiga-hub/arc/tracing"
+)
+
+// ElementKey is ElementKey for the gRPC server
+var ElementKey = micro.ElementKey("GRPCServerComponent")
+
+// Component is Component for the gRPC server.
+// Components register services in Init on the *Server element, they declare ElementKey in DependsOnElements
+// so the server is created first. The element is not registered if the server is disabled.
+type Component struct {
+	micro.EmptyComponent
+	server *Server
+	tracer func() opentracing.Tracer
+}
+
+// Name of the component
+func (c *Component) Name() string {
+	return "GRPCServer"
+}
+
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"grpc"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey}
+}
+
+// PreInit called before Init()
+func (c *Component) PreInit(ctx context.Context) error {
+	_ = ctx
+	// load config
+	SetDefaultConfig()
+	return nil
+}
+
+// Init the component
+func (c *Component) Init(server *micro.Server) error {
+	conf := GetConfig()
+	if !conf.Enable {
+		return nil
+	}
+	// the tracing component may be initialized after this one
+	c.tracer = func() opentracing.Tracer {
+		tracer, _ := server.GetElement(&tracing.ElementKey).(opentracing.Tracer)
+		return tracer
+	}
+	c.server = New(conf, micro.GenerateLoggerForModule(server, "grpc"))
+	server.RegisterElement(&ElementKey, c.server)
+	return nil
+}
+
+// Status of the component
+func (c *Component) Status() *micro.ComponentStatus {
+	status := c.EmptyComponent.Status()
+	if c.server != nil {
+		if addr := c.server.Addr(); addr != nil {
+			status.Params["address"] = addr.String()
+		}
+		status.Params["services"] = c.server.Services()
+	}
+	return status
+}
+
+// Start the component
+func (c *Component) Start(ctx context.Context) error {
+	_ = ctx
+	if c.server == nil {
+		return nil
+	}
+	c.server.SetTracer(c.tracer())
+	return c.server.Start()
+}
+
+// PreStop called before Stop(), the health service reports not serving while other components stop
+func (c *Component) PreStop(ctx context.Context) error {
+	_ = ctx
+	if c.server != nil {
+		c.server.Shutdown()
+	}
+	return nil
+}
+
+// Stop the component, calls are canceled if they do not end before ctx is done
+func (c *Component) Stop(ctx context.Context) error {
+	if c.server != nil {
+		c.server.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package grpcserver
+
+import "github.com/spf13/viper"
+
+const (
+	grpcEnable         = "grpc.enable"
+	grpcAddress        = "grpc.address"
+	grpcMaxRecvMsgSize = "grpc.maxRecvMsgSize"
+	grpcMaxSendMsgSize = "grpc.maxSendMsgSize"
+	grpcReflection     = "grpc.reflection"
+	grpcHealthCheck    = "grpc.healthCheck"
+)
+
+var defaultConfig = Config{
+	Enable:         false,
+	Address:        ":9090",
+	MaxRecvMsgSize: 4 * 1024 * 1024,
+	MaxSendMsgSize: 4 * 1024 * 1024,
+	Reflection:     true,
+	HealthCheck:    true,
+}
+
+// Config gRPC server configuration
+type Config struct {
+	Enable         bool   `toml:"enable"`
+	Address        string `toml:"address"`        // listen address
+	MaxRecvMsgSize int    `toml:"maxRecvMsgSize"` // bytes
+	MaxSendMsgSize int    `toml:"maxSendMsgSize"` // bytes
+	Reflection     bool   `toml:"reflection"`     // register the reflection service
+	HealthCheck    bool   `toml:"healthCheck"`    // register the grpc.health.v1 service
+}
+
+// SetDefaultConfig -
+func SetDefaultConfig() {
+	viper.SetDefault(grpcEnable, defaultConfig.Enable)
+	viper.SetDefault(grpcAddress, defaultConfig.Address)
+	viper.SetDefault(grpcMaxRecvMsgSize, defaultConfig.MaxRecvMsgSize)
+	viper.SetDefault(grpcMaxSendMsgSize, defaultConfig.MaxSendMsgSize)
+	viper.SetDefault(grpcReflection, defaultConfig.Reflection)
+	viper.SetDefault(grpcHealthCheck, defaultConfig.HealthCheck)
+}
+
+// GetConfig -
+func GetConfig() *Config {
+	return &Config{
+		Enable:         viper.GetBool(grpcEnable),
+		Address:        viper.GetString(grpcAddress),
+		MaxRecvMsgSize: viper.GetInt(grpcMaxRecvMsgSize),
+		MaxSendMsgSize: viper.GetInt(grpcMaxSendMsgSize),
+		Reflection:     viper.GetBool(grpcReflection),
+		HealthCheck:    viper.GetBool(grpcHealthCheck),
+	}
+}
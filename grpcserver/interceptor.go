@@ -0,0 +1,67 @@
+package grpcserver
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/tracing"
+)
+
+// unaryInterceptor starts a span, logs the call and recovers panics of unary handlers
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	start := time.Now()
+	ctx, finish, logger := tracing.GetGRPCServerSpan(ctx, s.logger(), s.tracer, info.FullMethod, false)
+	defer finish()
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(logger, info.FullMethod, r)
+		}
+		done(ctx, logger, info.FullMethod, start, err)
+	}()
+	return handler(ctx, req)
+}
+
+// streamInterceptor starts a span, logs the call and recovers panics of stream handlers
+func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	ctx, finish, logger := tracing.GetGRPCServerSpan(ss.Context(), s.logger(), s.tracer, info.FullMethod, false)
+	defer finish()
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(logger, info.FullMethod, r)
+		}
+		done(ctx, logger, info.FullMethod, start, err)
+	}()
+	wrapped := grpcMiddleware.WrapServerStream(ss)
+	wrapped.WrappedContext = ctx
+	return handler(srv, wrapped)
+}
+
+// recovered logs the panic of a handler and returns the error to the client without its details
+func recovered(logger logging.ILogger, method string, r interface{}) error {
+	logger.Errorw("grpc panic", "method", method, "panic", r, "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
+
+// done logs the call and marks the span as failed if err is not nil
+func done(ctx context.Context, logger logging.ILogger, method string, start time.Time, err error) {
+	duration := time.Since(start)
+	if err == nil {
+		logger.Debugw("grpc call", "method", method, "duration", duration)
+		return
+	}
+	code := status.Code(err)
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetTag("grpc.code", code.String())
+		tracing.ErrorToSpan(span, err)
+	}
+	logger.Warnw("grpc call", "method", method, "duration", duration, "code", code.String(), "error", err)
+}
@@ -0,0 +1,149 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+
+	grpcPrometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+// Server is a gRPC server with interceptors for logging, tracing, metrics and panic recovery.
+// Services are registered before Start, e.g. pb.RegisterXServer(s, impl) or pb.RegisterXServer(s.GRPC(), impl)
+// for code generated by old plugins.
+type Server struct {
+	config   *Config
+	logger   func() logging.ILogger
+	tracer   opentracing.Tracer
+	grpc     *grpc.Server
+	health   *health.Server
+	listener net.Listener
+}
+
+// New create a Server, opts are appended to the options of config and interceptors
+func New(config *Config, logger func() logging.ILogger, opts ...grpc.ServerOption) *Server {
+	s := &Server{
+		config: config,
+		logger: logger,
+	}
+	// metrics are outermost so recovered panics are counted as Internal
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcPrometheus.UnaryServerInterceptor, s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(grpcPrometheus.StreamServerInterceptor, s.streamInterceptor),
+	}
+	if config.MaxRecvMsgSize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(config.MaxRecvMsgSize))
+	}
+	if config.MaxSendMsgSize > 0 {
+		options = append(options, grpc.MaxSendMsgSize(config.MaxSendMsgSize))
+	}
+	s.grpc = grpc.NewServer(append(options, opts...)...)
+	if config.HealthCheck {
+		s.health = health.NewServer()
+		healthpb.RegisterHealthServer(s.grpc, s.health)
+	}
+	if config.Reflection {
+		reflection.Register(s.grpc)
+	}
+	return s
+}
+
+// RegisterService implements grpc.ServiceRegistrar, it must be called before Start
+func (s *Server) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	s.grpc.RegisterService(desc, impl)
+}
+
+// GRPC returns the underlying grpc.Server
+func (s *Server) GRPC() *grpc.Server {
+	return s.grpc
+}
+
+// SetTracer sets the tracer of spans of calls, it must be called before Start
+func (s *Server) SetTracer(tracer opentracing.Tracer) {
+	s.tracer = tracer
+}
+
+// Services returns the names of registered services
+func (s *Server) Services() []string {
+	var services []string
+	for name := range s.grpc.GetServiceInfo() {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// SetServingStatus sets the status of a service reported by the health service, "" is the status of the server.
+// All services are serving after Start.
+func (s *Server) SetServingStatus(service string, serving bool) {
+	if s.health == nil {
+		return
+	}
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	s.health.SetServingStatus(service, status)
+}
+
+// Start listens and serves in background
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	grpcPrometheus.Register(s.grpc)
+	s.SetServingStatus("", true)
+	for _, service := range s.Services() {
+		s.SetServingStatus(service, true)
+	}
+	go func() {
+		err := s.grpc.Serve(l)
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.logger().Errorw("serve grpc", "error", err)
+		}
+	}()
+	s.logger().Infow("start grpc server", "addr", l.Addr().String(), "services", s.Services())
+	return nil
+}
+
+// Addr returns the listen address, nil if it is not started
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Shutdown reports all services not serving, so clients checking health stop sending new calls
+func (s *Server) Shutdown() {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+}
+
+// Close stops the server gracefully, calls are canceled at once if ctx is done before they end
+func (s *Server) Close(ctx context.Context) {
+	s.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger().Warnw("stop grpc server", "error", ctx.Err())
+		s.grpc.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,167 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+// echoServer echoes strings, it panics on "panic"
+type echoServer struct{}
+
+func (echoServer) echo(in *wrapperspb.StringValue) *wrapperspb.StringValue {
+	if in.GetValue() == "panic" {
+		panic("echo panic")
+	}
+	return in
+}
+
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := &wrapperspb.StringValue{}
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(echoServer).echo(req.(*wrapperspb.StringValue)), nil
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Echo"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Repeat",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			in := &wrapperspb.StringValue{}
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			for i := 0; i < 2; i++ {
+				if err := stream.SendMsg(srv.(echoServer).echo(in)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}},
+}
+
+func startServer(t *testing.T) (*Server, *mocktracer.MockTracer, *grpc.ClientConn) {
+	config := defaultConfig
+	config.Address = "127.0.0.1:0"
+	logger := zap.NewNop().Sugar()
+	s := New(&config, func() logging.ILogger { return logger })
+	s.RegisterService(&echoServiceDesc, echoServer{})
+	tracer := mocktracer.New()
+	s.SetTracer(tracer)
+	assert.Nil(t, s.Start())
+
+	conn, err := grpc.Dial(s.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	return s, tracer, conn
+}
+
+func repeat(ctx context.Context, conn *grpc.ClientConn, value string) ([]string, error) {
+	stream, err := conn.NewStream(ctx, &echoServiceDesc.Streams[0], "/test.Echo/Repeat")
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(wrapperspb.String(value)); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		out := &wrapperspb.StringValue{}
+		err := stream.RecvMsg(out)
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return values, err
+		}
+		values = append(values, out.GetValue())
+	}
+}
+
+func TestServer(t *testing.T) {
+	s, tracer, conn := startServer(t)
+	defer conn.Close()
+	ctx := context.Background()
+	assert.Equal(t, []string{"grpc.health.v1.Health", "grpc.reflection.v1alpha.ServerReflection", "test.Echo"}, s.Services())
+
+	out := &wrapperspb.StringValue{}
+	assert.Nil(t, conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("hi"), out))
+	assert.Equal(t, "hi", out.GetValue())
+	values, err := repeat(ctx, conn, "hi")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hi", "hi"}, values)
+
+	// panics are recovered and the server keeps serving
+	err = conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("panic"), out)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	_, err = repeat(ctx, conn, "panic")
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Nil(t, conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("hi"), out))
+
+	var spans []string
+	for _, span := range tracer.FinishedSpans() {
+		spans = append(spans, fmt.Sprint(span.OperationName, " ", span.Tag("error")))
+	}
+	assert.Equal(t, []string{
+		"/test.Echo/Echo <nil>",
+		"/test.Echo/Repeat <nil>",
+		"/test.Echo/Echo true",
+		"/test.Echo/Repeat true",
+		"/test.Echo/Echo <nil>",
+	}, spans)
+
+	health := healthpb.NewHealthClient(conn)
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: "test.Echo"})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	s.Shutdown()
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+	closeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	s.Close(closeCtx)
+	assert.NotNil(t, conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("hi"), out))
+}
+
+func TestServerCloseTimeout(t *testing.T) {
+	s, _, conn := startServer(t)
+	defer conn.Close()
+	// an open stream blocks graceful stop
+	stream, err := conn.NewStream(context.Background(), &echoServiceDesc.Streams[0], "/test.Echo/Repeat")
+	assert.Nil(t, err)
+	assert.Nil(t, stream.SendMsg(wrapperspb.String("hi")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	s.Close(ctx)
+	assert.True(t, time.Since(start) < time.Second)
+}
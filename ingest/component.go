@@ -0,0 +1,82 @@
+package ingest
+
+import (
+	"context"
+
+	"github.com/kiga-hub/arc/micro"
+)
+
+// ElementKey is ElementKey for ingest
+var ElementKey = micro.ElementKey("IngestComponent")
+
+// Component is Component for ingest, it receives frame streams of collectors.
+// The handler is set by Handler, or by components calling (*Server).SetHandler in Init,
+// otherwise items are read from (*Server).Items.
+type Component struct {
+	micro.EmptyComponent
+	Handler Handler
+	server  *Server
+}
+
+// Name of the component
+func (c *Component) Name() string {
+	return "Ingest"
+}
+
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"ingest"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey}
+}
+
+// PreInit called before Init()
+func (c *Component) PreInit(ctx context.Context) error {
+	_ = ctx
+	// load config
+	SetDefaultConfig()
+	return nil
+}
+
+// Init the component
+func (c *Component) Init(server *micro.Server) error {
+	conf := GetConfig()
+	if !conf.Enable {
+		return nil
+	}
+	c.server = New(conf, micro.GenerateLoggerForModule(server, "ingest"))
+	if c.Handler != nil {
+		c.server.SetHandler(c.Handler)
+	}
+	server.RegisterElement(&ElementKey, c.server)
+	return nil
+}
+
+// Status of the component
+func (c *Component) Status() *micro.ComponentStatus {
+	status := c.EmptyComponent.Status()
+	if c.server != nil {
+		status.Params["ingest"] = c.server.Stats()
+	}
+	return status
+}
+
+// Start the component
+func (c *Component) Start(ctx context.Context) error {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.Start(ctx)
+}
+
+// Stop the component
+func (c *Component) Stop(ctx context.Context) error {
+	_ = ctx
+	if c.server == nil {
+		return nil
+	}
+	return c.server.Close()
+}
@@ -0,0 +1,78 @@
+package ingest
+
+import "github.com/spf13/viper"
+
+const (
+	ingestEnable        = "ingest.enable"
+	ingestAddress       = "ingest.address"
+	ingestMaxConns      = "ingest.maxConns"
+	ingestIdleTimeout   = "ingest.idleTimeout"
+	ingestCrcCheck      = "ingest.crcCheck"
+	ingestQueueSize     = "ingest.queueSize"
+	ingestWorkers       = "ingest.workers"
+	ingestCollectorType = "ingest.collectorType"
+	ingestInterval      = "ingest.interval"
+	ingestReorderWindow = "ingest.reorderWindow"
+	ingestReorderDelay  = "ingest.reorderDelay"
+)
+
+var defaultConfig = Config{
+	Enable:        false,
+	Address:       ":8972",
+	MaxConns:      1024,
+	IdleTimeout:   60000,
+	CrcCheck:      true,
+	QueueSize:     1024,
+	Workers:       1,
+	CollectorType: "arc",
+	Interval:      0,
+	ReorderWindow: 0,
+	ReorderDelay:  1000,
+}
+
+// Config ingest configuration
+type Config struct {
+	Enable        bool   `toml:"enable"`
+	Address       string `toml:"address"`       // listen address of frame streams
+	MaxConns      int    `toml:"maxConns"`      // max collector connections, new ones are closed if exceeded, 0 means no limit
+	IdleTimeout   int    `toml:"idleTimeout"`   // ms, close the connection if nothing is read, 0 means no timeout
+	CrcCheck      bool   `toml:"crcCheck"`      // check crc of frames
+	QueueSize     int    `toml:"queueSize"`     // items queued for the handler, connections stop reading if it is full
+	Workers       int    `toml:"workers"`       // goroutines calling the handler
+	CollectorType string `toml:"collectorType"` // CollectorType of the items
+	Interval      int    `toml:"interval"`      // ms, expected cadence of frames of a collector to detect gaps, 0 means it is learned
+	ReorderWindow int    `toml:"reorderWindow"` // frames held per collector to reorder, 0 disables reordering
+	ReorderDelay  int    `toml:"reorderDelay"`  // ms, max time a frame is held to reorder
+}
+
+// SetDefaultConfig -
+func SetDefaultConfig() {
+	viper.SetDefault(ingestEnable, defaultConfig.Enable)
+	viper.SetDefault(ingestAddress, defaultConfig.Address)
+	viper.SetDefault(ingestMaxConns, defaultConfig.MaxConns)
+	viper.SetDefault(ingestIdleTimeout, defaultConfig.IdleTimeout)
+	viper.SetDefault(ingestCrcCheck, defaultConfig.CrcCheck)
+	viper.SetDefault(ingestQueueSize, defaultConfig.QueueSize)
+	viper.SetDefault(ingestWorkers, defaultConfig.Workers)
+	viper.SetDefault(ingestCollectorType, defaultConfig.CollectorType)
+	viper.SetDefault(ingestInterval, defaultConfig.Interval)
+	viper.SetDefault(ingestReorderWindow, defaultConfig.ReorderWindow)
+	viper.SetDefault(ingestReorderDelay, defaultConfig.ReorderDelay)
+}
+
+// GetConfig -
+func GetConfig() *Config {
+	return &Config{
+		Enable:        viper.GetBool(ingestEnable),
+		Address:       viper.GetString(ingestAddress),
+		MaxConns:      viper.GetInt(ingestMaxConns),
+		IdleTimeout:   viper.GetInt(ingestIdleTimeout),
+		CrcCheck:      viper.GetBool(ingestCrcCheck),
+		QueueSize:     viper.GetInt(ingestQueueSize),
+		Workers:       viper.GetInt(ingestWorkers),
+		CollectorType: viper.GetString(ingestCollectorType),
+		Interval:      viper.GetInt(ingestInterval),
+		ReorderWindow: viper.GetInt(ingestReorderWindow),
+		ReorderDelay:  viper.GetInt(ingestReorderDelay),
+	}
+}
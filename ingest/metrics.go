@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kiga-hub/arc/protocols"
+)
+
+// continuityMetrics exports continuity counters of collectors of the started servers
+type continuityMetrics struct {
+	lock    sync.Mutex
+	servers map[*Server]struct{}
+	descs   []*prometheus.Desc
+}
+
+var metrics = newContinuityMetrics()
+
+func init() {
+	prometheus.MustRegister(metrics)
+}
+
+func newContinuityMetrics() *continuityMetrics {
+	labels := []string{"server", "collector"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("", "ingest", name), help, labels, nil)
+	}
+	return &continuityMetrics{
+		servers: map[*Server]struct{}{},
+		descs: []*prometheus.Desc{
+			desc("frames_total", "Frames released of collectors."),
+			desc("frame_gaps_total", "Timestamp gaps of collectors."),
+			desc("frames_missing_total", "Frames estimated to be lost in gaps."),
+			desc("frame_duplicates_total", "Duplicate frames dropped."),
+			desc("frames_out_of_order_total", "Frames arrived before frames of earlier timestamps."),
+			desc("frames_reordered_total", "Out of order frames put back in order."),
+			desc("frames_late_total", "Out of order frames arrived after the reorder window."),
+		},
+	}
+}
+
+func (m *continuityMetrics) add(s *Server) {
+	m.lock.Lock()
+	m.servers[s] = struct{}{}
+	m.lock.Unlock()
+}
+
+func (m *continuityMetrics) remove(s *Server) {
+	m.lock.Lock()
+	delete(m.servers, s)
+	m.lock.Unlock()
+}
+
+// Describe implements prometheus.Collector
+func (m *continuityMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range m.descs {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (m *continuityMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for s := range m.servers {
+		address := s.Addr().String()
+		for _, c := range s.tracker.Stats().Collectors {
+			for i, v := range counterValues(&c.ContinuityCounters) {
+				ch <- prometheus.MustNewConstMetric(m.descs[i], prometheus.CounterValue, float64(v), address, c.ID)
+			}
+		}
+	}
+}
+
+// counterValues returns counters in the order of continuityMetrics.descs
+func counterValues(c *protocols.ContinuityCounters) []uint64 {
+	return []uint64{c.Frames, c.Gaps, c.Missing, c.Duplicates, c.OutOfOrder, c.Reordered, c.Late}
+}
@@ -0,0 +1,360 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/protocols"
+)
+
+// Handler handles items decoded from frames, it is called by config.Workers goroutines concurrently
+type Handler func(item *protocols.ArcItemRaw)
+
+// ConnStats is the stats of a collector connection
+type ConnStats struct {
+	protocols.ScanStats
+	Remote       string    `json:"remote"`
+	Since        time.Time `json:"since"`
+	Bytes        uint64    `json:"bytes"`         // bytes read
+	Items        uint64    `json:"items"`         // items dispatched
+	DecodeErrors uint64    `json:"decode_errors"` // valid frames failed to decode
+	Blocked      uint64    `json:"blocked"`       // times waiting for the handler because the queue is full
+}
+
+// Stats is the stats of the server
+type Stats struct {
+	Address     string       `json:"address"`
+	Rejected    uint64       `json:"rejected"` // connections closed because of max connections
+	Queued      int          `json:"queued"`   // items waiting for the handler
+	Connections []*ConnStats `json:"connections"`
+
+	Continuity *protocols.ContinuityStats `json:"continuity"`
+}
+
+// connection is a collector connection, read deadline is reset before each read if idle timeout is set
+type connection struct {
+	net.Conn
+	idleTimeout  time.Duration
+	since        time.Time
+	scanner      *protocols.FrameScanner
+	bytes        atomic.Uint64
+	items        atomic.Uint64
+	decodeErrors atomic.Uint64
+	blocked      atomic.Uint64
+}
+
+func (c *connection) Read(p []byte) (int, error) {
+	if c.idleTimeout > 0 {
+		err := c.SetReadDeadline(time.Now().Add(c.idleTimeout))
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := c.Conn.Read(p)
+	c.bytes.Add(uint64(n))
+	return n, err
+}
+
+func (c *connection) stats() *ConnStats {
+	return &ConnStats{
+		ScanStats:    c.scanner.Stats(),
+		Remote:       c.RemoteAddr().String(),
+		Since:        c.since,
+		Bytes:        c.bytes.Load(),
+		Items:        c.items.Load(),
+		DecodeErrors: c.decodeErrors.Load(),
+		Blocked:      c.blocked.Load(),
+	}
+}
+
+// Server receives frame streams of collectors and dispatches ArcItemRaw of arc segments.
+// Items are queued for the handler, or for Items() if no handler is set.
+// Connections stop reading when the queue is full, so slow handlers push back on collectors.
+// Continuity of collectors is tracked across connections, duplicate frames are dropped,
+// and frames are reordered per collector if config.ReorderWindow is set.
+// Items of a collector are queued in order, they are handled in order only if there is one worker.
+type Server struct {
+	config   *Config
+	logger   func() logging.ILogger
+	handler  Handler
+	items    chan *protocols.ArcItemRaw
+	tracker  *protocols.ContinuityTracker[*protocols.ArcItemRaw]
+	listener net.Listener
+	ctx      context.Context
+	cancel   context.CancelFunc
+	lock     sync.Mutex
+	conns    map[*connection]struct{}
+	rejected atomic.Uint64
+	connWG   sync.WaitGroup
+	workerWG sync.WaitGroup
+	close    sync.Once
+}
+
+// New create a Server
+func New(config *Config, logger func() logging.ILogger) *Server {
+	queueSize := config.QueueSize
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &Server{
+		config: config,
+		logger: logger,
+		items:  make(chan *protocols.ArcItemRaw, queueSize),
+		tracker: protocols.NewContinuityTracker[*protocols.ArcItemRaw](protocols.ContinuityConfig{
+			Interval: int64(config.Interval),
+			Window:   config.ReorderWindow,
+			MaxDelay: time.Duration(config.ReorderDelay) * time.Millisecond,
+		}),
+		conns: map[*connection]struct{}{},
+	}
+}
+
+// SetHandler sets the handler of items, it must be called before Start
+func (s *Server) SetHandler(handler Handler) {
+	s.handler = handler
+}
+
+// Items returns the queue of items if no handler is set, it is closed after Close
+func (s *Server) Items() <-chan *protocols.ArcItemRaw {
+	return s.items
+}
+
+// Start listens and serves in background
+func (s *Server) Start(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	if s.handler != nil {
+		workers := s.config.Workers
+		if workers < 1 {
+			workers = 1
+		}
+		for i := 0; i < workers; i++ {
+			s.workerWG.Add(1)
+			go func() {
+				defer s.workerWG.Done()
+				for item := range s.items {
+					s.handler(item)
+				}
+			}()
+		}
+	}
+
+	if s.config.ReorderWindow > 0 {
+		s.connWG.Add(1)
+		go s.expire()
+	}
+	s.connWG.Add(1)
+	go s.accept()
+	metrics.add(s)
+	s.logger().Infow("start ingest server", "addr", l.Addr().String())
+	return nil
+}
+
+// Addr returns the listen address, nil if it is not started
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) accept() {
+	defer s.connWG.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				s.logger().Errorw("accept", "error", err)
+			}
+			return
+		}
+		c := &connection{
+			Conn:        conn,
+			idleTimeout: time.Duration(s.config.IdleTimeout) * time.Millisecond,
+			since:       time.Now(),
+		}
+		c.scanner = protocols.NewFrameScanner(c, s.config.CrcCheck)
+
+		s.lock.Lock()
+		if s.ctx.Err() != nil || (s.config.MaxConns > 0 && len(s.conns) >= s.config.MaxConns) {
+			s.lock.Unlock()
+			s.rejected.Inc()
+			s.logger().Warnw("reject connection", "remote", conn.RemoteAddr().String(), "maxConns", s.config.MaxConns)
+			_ = conn.Close()
+			continue
+		}
+		s.conns[c] = struct{}{}
+		s.connWG.Add(1)
+		s.lock.Unlock()
+
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c *connection) {
+	defer s.connWG.Done()
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, c)
+		s.lock.Unlock()
+		_ = c.Close()
+	}()
+
+	var released []*protocols.ArcItemRaw
+	for c.scanner.Scan() {
+		id, item, err := s.decode(c.scanner)
+		if err != nil {
+			c.decodeErrors.Inc()
+			s.logger().Debugw("decode frame", "remote", c.RemoteAddr().String(), "error", err)
+			continue
+		}
+		released = s.tracker.Track(released[:0], id, item.Ts, item)
+		for _, item := range released {
+			select {
+			case s.items <- item:
+			default:
+				c.blocked.Inc()
+				select {
+				case s.items <- item:
+				case <-s.ctx.Done():
+					return
+				}
+			}
+			c.items.Inc()
+		}
+	}
+	err := c.scanner.Err()
+	if err != nil && s.ctx.Err() == nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			s.logger().Infow("close idle connection", "remote", c.RemoteAddr().String())
+		} else if !errors.Is(err, io.EOF) {
+			s.logger().Warnw("read frames", "remote", c.RemoteAddr().String(), "error", err)
+		}
+	}
+}
+
+// expire queues items held to reorder longer than config.ReorderDelay
+func (s *Server) expire() {
+	defer s.connWG.Done()
+	interval := time.Duration(s.config.ReorderDelay) * time.Millisecond / 2
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var released []*protocols.ArcItemRaw
+	for {
+		select {
+		case now := <-ticker.C:
+			released = s.tracker.Expire(released[:0], now)
+			for _, item := range released {
+				select {
+				case s.items <- item:
+				case <-s.ctx.Done():
+					return
+				}
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// decode returns the collector id and the item of the frame
+func (s *Server) decode(scanner *protocols.FrameScanner) (uint64, *protocols.ArcItemRaw, error) {
+	frame := protocols.AcquireFrame()
+	defer protocols.ReleaseFrame(frame)
+	err := scanner.DecodeFrame(frame, true)
+	if err != nil {
+		return 0, nil, err
+	}
+	segment, err := frame.DataGroup.GetArcSegment()
+	if err != nil {
+		return 0, nil, err
+	}
+	// the frame references the buffer of the scanner, the item is queued so it is copied
+	id := make([]byte, len(frame.ID))
+	copy(id, frame.ID[:])
+	data := make([]byte, len(segment.Data))
+	copy(data, segment.Data)
+	return frame.GetID(), &protocols.ArcItemRaw{
+		CollectorID:   id,
+		CollectorType: s.config.CollectorType,
+		Ts:            frame.Timestamp,
+		Data:          data,
+	}, nil
+}
+
+// Stats returns the stats of the server and its connections
+func (s *Server) Stats() *Stats {
+	stats := &Stats{
+		Rejected:    s.rejected.Load(),
+		Queued:      len(s.items),
+		Connections: []*ConnStats{},
+		Continuity:  s.tracker.Stats(),
+	}
+	if addr := s.Addr(); addr != nil {
+		stats.Address = addr.String()
+	}
+	s.lock.Lock()
+	for c := range s.conns {
+		stats.Connections = append(stats.Connections, c.stats())
+	}
+	s.lock.Unlock()
+	sort.Slice(stats.Connections, func(i, j int) bool {
+		return stats.Connections[i].Since.Before(stats.Connections[j].Since)
+	})
+	return stats
+}
+
+// Close stops listening and closes the connections, the queued items are handled before it returns.
+// Items held to reorder are queued if the queue is not full.
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	var err error
+	s.close.Do(func() {
+		s.cancel()
+		err = s.listener.Close()
+		s.lock.Lock()
+		for c := range s.conns {
+			_ = c.Close()
+		}
+		s.lock.Unlock()
+		s.connWG.Wait()
+		metrics.remove(s)
+		dropped := 0
+		for _, item := range s.tracker.Flush(nil) {
+			select {
+			case s.items <- item:
+			default:
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			s.logger().Warnw("drop items held to reorder", "count", dropped)
+		}
+		close(s.items)
+		s.workerWG.Wait()
+	})
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("close ingest listener: %w", err)
+	}
+	return nil
+}
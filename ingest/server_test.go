@@ -0,0 +1,166 @@
+package ingest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/protocols"
+)
+
+// streamFrame returns a frame of an arc segment
+func streamFrame(id byte, ts int64, data []byte) []byte {
+	st := protocols.NewDefaultSegmentArc()
+	st.SetData(data)
+	g := protocols.NewDefaultDataGroup()
+	g.AppendSegment(st)
+	p := protocols.NewDefaultFrame().SetID(uint64(id)).SetDataGroup(g)
+	p.Timestamp = ts
+	buf := make([]byte, p.Size+protocols.DefaultHeadLength)
+	_, _ = p.Encode(buf)
+	return buf
+}
+
+func newTestServer(t *testing.T, config Config) *Server {
+	config.Address = "127.0.0.1:0"
+	logger := zap.NewNop().Sugar()
+	s := New(&config, func() logging.ILogger { return logger })
+	return s
+}
+
+func dial(t *testing.T, s *Server) net.Conn {
+	conn, err := net.Dial("tcp", s.Addr().String())
+	assert.Nil(t, err)
+	return conn
+}
+
+func TestServerHandler(t *testing.T) {
+	s := newTestServer(t, defaultConfig)
+	items := make(chan *protocols.ArcItemRaw, 10)
+	s.SetHandler(func(item *protocols.ArcItemRaw) { items <- item })
+	assert.Nil(t, s.Start(context.Background()))
+
+	conn := dial(t, s)
+	defer conn.Close()
+	data := append([]byte{0xFC, 0}, streamFrame(1, 100, []byte{1, 2, 3})...)
+	data = append(data, streamFrame(2, 200, []byte{4, 5})...)
+	_, err := conn.Write(data)
+	assert.Nil(t, err)
+
+	item := <-items
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 1}, item.CollectorID)
+	assert.Equal(t, "arc", item.CollectorType)
+	assert.Equal(t, int64(100), item.Ts)
+	assert.Equal(t, []byte{1, 2, 3}, item.Data)
+	item = <-items
+	assert.Equal(t, int64(200), item.Ts)
+	assert.Equal(t, []byte{4, 5}, item.Data)
+
+	stats := s.Stats()
+	assert.Equal(t, 1, len(stats.Connections))
+	assert.Equal(t, uint64(2), stats.Connections[0].Frames)
+	assert.Equal(t, uint64(2), stats.Connections[0].DroppedBytes)
+	assert.Equal(t, uint64(len(data)), stats.Connections[0].Bytes)
+	assert.Nil(t, s.Close())
+}
+
+func TestServerLimits(t *testing.T) {
+	config := defaultConfig
+	config.MaxConns = 1
+	config.IdleTimeout = 100
+	config.QueueSize = 1
+	s := newTestServer(t, config)
+	assert.Nil(t, s.Start(context.Background()))
+
+	conn := dial(t, s)
+	defer conn.Close()
+	for i := 0; i < 3; i++ {
+		_, err := conn.Write(streamFrame(1, int64(i), []byte{byte(i)}))
+		assert.Nil(t, err)
+	}
+	// the queue is full, the connection waits for the items to be read
+	assert.Eventually(t, func() bool {
+		stats := s.Stats()
+		return len(stats.Connections) == 1 && stats.Connections[0].Blocked == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// the second connection is rejected
+	rejected := dial(t, s)
+	defer rejected.Close()
+	_ = rejected.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := rejected.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(1), s.Stats().Rejected)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, int64(i), (<-s.Items()).Ts)
+	}
+
+	// the idle connection is closed
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+	assert.Eventually(t, func() bool {
+		return len(s.Stats().Connections) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, s.Close())
+	_, ok := <-s.Items()
+	assert.False(t, ok)
+}
+
+func TestServerContinuity(t *testing.T) {
+	config := defaultConfig
+	config.ReorderWindow = 2
+	config.ReorderDelay = 60000
+	s := newTestServer(t, config)
+	items := make(chan *protocols.ArcItemRaw, 10)
+	s.SetHandler(func(item *protocols.ArcItemRaw) { items <- item })
+	assert.Nil(t, s.Start(context.Background()))
+
+	conn := dial(t, s)
+	defer conn.Close()
+	var data []byte
+	for _, ts := range []int64{100, 300, 200, 200} {
+		data = append(data, streamFrame(1, ts, []byte{byte(ts)})...)
+	}
+	_, err := conn.Write(data)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), (<-items).Ts)
+
+	var continuity *protocols.CollectorContinuity
+	assert.Eventually(t, func() bool {
+		collectors := s.Stats().Continuity.Collectors
+		if len(collectors) != 1 || collectors[0].Duplicates != 1 {
+			return false
+		}
+		continuity = collectors[0]
+		return true
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "000000000001", continuity.ID)
+	assert.Equal(t, uint64(1), continuity.Reordered)
+	assert.Equal(t, 2, continuity.Buffered)
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.Nil(t, err)
+	var duplicates float64
+	for _, family := range families {
+		if family.GetName() == "ingest_frame_duplicates_total" {
+			for _, m := range family.GetMetric() {
+				duplicates += m.GetCounter().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, 1.0, duplicates)
+
+	// held items are handled on close
+	assert.Nil(t, s.Close())
+	assert.Equal(t, int64(200), (<-items).Ts)
+	assert.Equal(t, int64(300), (<-items).Ts)
+}
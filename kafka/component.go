@@ -21,6 +21,16 @@ func (c *Component) Name() string {
 	return "Kafka"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"kafka"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey}
+}
+
 // PreInit called before Init()
 func (c *Component) PreInit(ctx context.Context) error {
 	// load config
@@ -28,6 +38,11 @@ func (c *Component) PreInit(ctx context.Context) error {
 	return nil
 }
 
+// DependsOnElements returns keys of elements which must be registered before this one is initialized
+func (c *Component) DependsOnElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&micro.LoggingElementKey}
+}
+
 // Init the component
 func (c *Component) Init(server *micro.Server) error {
 	// init
@@ -49,6 +64,21 @@ func (c *Component) Start(ctx context.Context) error {
 	return nil
 }
 
+// HealthChecks returns checks of the component, called after Init()
+// kafka client reconnects by itself, so a failing broker only degrades the status
+func (c *Component) HealthChecks() []micro.HealthCheck {
+	if c.server == nil {
+		return nil
+	}
+	return []micro.HealthCheck{
+		{
+			Name:  "metadata",
+			Level: micro.HealthLevelWarning,
+			Check: c.server.CheckBroker,
+		},
+	}
+}
+
 // PostStop called after Stop()
 func (c *Component) PostStop(ctx context.Context) error {
 	// post stop
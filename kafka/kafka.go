@@ -1,6 +1,10 @@
 package kafka
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/kiga-hub/arc/logging"
 	"go.uber.org/atomic"
@@ -28,6 +32,19 @@ func New(config *Config, logger logging.ILogger) *Kafka {
 	}
 }
 
+// CheckBroker checks the brokers are reachable by fetching metadata of the topic
+func (k *Kafka) CheckBroker(ctx context.Context) error {
+	if k.producer == nil {
+		return errors.New("kafka producer not created")
+	}
+	timeout := 3 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	_, err := k.producer.GetMetadata(&k.config.Topic, false, int(timeout/time.Millisecond))
+	return err
+}
+
 // Close -
 func (k *Kafka) Close() {
 	k.isClose.Store(true)
@@ -15,6 +15,12 @@ import (
 // ErrNotFound means item not found in nacos
 var ErrNotFound = errors.New("item not found")
 
+// ErrNotLeader means the elector does not hold the lease
+var ErrNotLeader = errors.New("not the leader")
+
+// ErrConflict means the record was changed by another candidate since it was observed by the lock
+var ErrConflict = errors.New("record changed by another candidate")
+
 const (
 	jitterFactor = 1.2
 )
@@ -33,15 +39,18 @@ type LeaderElectionRecord struct {
 	LeaderTransitions    int    `json:"leaderTransitions"`
 }
 
-// ILock is a holder for LeaderElectionRecord
+// ILock is a holder for LeaderElectionRecord.
+// Writes are compare-and-swap on the record last observed by the lock, so of candidates racing on the same
+// record only one succeeds, and the others get ErrConflict.
 type ILock interface {
-	// Get returns the LeaderElectionRecord
+	// Get returns the LeaderElectionRecord and observes it for the next Update
 	Get(ctx context.Context) (*LeaderElectionRecord, []byte, error)
 
-	// Create attempts to create a LeaderElectionRecord
+	// Create attempts to create a LeaderElectionRecord, it returns ErrConflict if the record exists
 	Create(ctx context.Context, ler LeaderElectionRecord) error
 
-	// Update will update and existing LeaderElectionRecord
+	// Update will update and existing LeaderElectionRecord, it returns ErrConflict if the record is not
+	// the one observed by the last Get, Create or Update of the lock
 	Update(ctx context.Context, ler LeaderElectionRecord) error
 
 	// RecordEvent is used to record events
@@ -81,6 +90,9 @@ func NewLeaderElector(lec LeaderElectionConfig, logger logging.ILogger) (*Leader
 	if lec.Lock == nil {
 		return nil, fmt.Errorf("the Lock must not be nil")
 	}
+	if lec.Clock == nil {
+		lec.Clock = realClock{}
+	}
 	le := LeaderElector{
 		config: lec,
 		logger: logger,
@@ -131,6 +143,20 @@ type LeaderElectionConfig struct {
 
 	// Name is the name of the resource lock for debugging
 	Name string
+
+	// Clock is the clock to check leases, time.Now if nil
+	Clock Clock
+}
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
 }
 
 // LeaderCallbacks are callbacks that are triggered during certain
@@ -165,6 +191,21 @@ type LeaderElector struct {
 
 	// used to lock the observedRecord
 	observedRecordLock sync.Mutex
+
+	// the term of Run holding the lease, it is ended by StepDown
+	termLock      sync.Mutex
+	termCancel    context.CancelFunc
+	termDone      chan struct{}
+	steppingDown  bool
+	stepDownUntil time.Time
+}
+
+type fencingTokenKey struct{}
+
+// FencingTokenFromContext returns the fencing token of the term of the context passed to OnStartedLeading
+func FencingTokenFromContext(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
 }
 
 // Run starts the leader election loop. Run will not return
@@ -181,10 +222,92 @@ func (le *LeaderElector) Run(ctx context.Context) {
 	}
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	go le.config.Callbacks.OnStartedLeading(ctx)
+	token, _ := le.FencingToken()
+	done := make(chan struct{})
+	le.termLock.Lock()
+	le.termCancel, le.termDone, le.steppingDown = cancel, done, false
+	le.termLock.Unlock()
+	go le.config.Callbacks.OnStartedLeading(context.WithValue(ctx, fencingTokenKey{}, token))
 	le.renew(ctx)
+	le.termLock.Lock()
+	le.termCancel, le.termDone = nil, nil
+	le.termLock.Unlock()
+	close(done)
 	fmt.Println("=== STOP LEADER ELECTION LOOP ===")
-	le.config.Callbacks.OnStopRunning()
+	if le.config.Callbacks.OnStopRunning != nil {
+		le.config.Callbacks.OnStopRunning()
+	}
+}
+
+// StepDown releases the lease voluntarily and ends the term of Run, so another candidate can take over.
+// The elector does not try to acquire the lease again within LeaseDuration.
+// Returns ErrNotLeader if the elector is not leading.
+func (le *LeaderElector) StepDown(ctx context.Context) error {
+	le.termLock.Lock()
+	cancel, done := le.termCancel, le.termDone
+	if cancel == nil || !le.IsLeader() {
+		le.termLock.Unlock()
+		return ErrNotLeader
+	}
+	le.steppingDown = true
+	le.termLock.Unlock()
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if le.IsLeader() {
+		return fmt.Errorf("failed to release lease %v", le.config.Lock.Describe())
+	}
+	return nil
+}
+
+// FencingToken returns the fencing token of the lease if this client is leading.
+// The token is LeaderTransitions of the record, it increases each time the lease changes hands, so resources
+// guarded by the lease can reject writes of an earlier leader carrying a smaller token.
+func (le *LeaderElector) FencingToken() (int64, bool) {
+	record := le.getObservedRecord()
+	if record.HolderIdentity != le.config.Lock.Identity() {
+		return 0, false
+	}
+	return int64(record.LeaderTransitions), true
+}
+
+// LeaderElectionStatus is the status of a LeaderElector
+type LeaderElectionStatus struct {
+	Name         string               `json:"name"`
+	Identity     string               `json:"identity"`
+	Lock         string               `json:"lock"`
+	IsLeader     bool                 `json:"isLeader"`
+	FencingToken int64                `json:"fencingToken"`
+	Record       LeaderElectionRecord `json:"record"`
+	ObservedTime time.Time            `json:"observedTime"`
+}
+
+// Status returns the last observed record and the state of this client
+func (le *LeaderElector) Status() LeaderElectionStatus {
+	le.observedRecordLock.Lock()
+	record, observedTime := le.observedRecord, le.observedTime
+	le.observedRecordLock.Unlock()
+	token, isLeader := le.FencingToken()
+	return LeaderElectionStatus{
+		Name:         le.name(),
+		Identity:     le.config.Lock.Identity(),
+		Lock:         le.config.Lock.Describe(),
+		IsLeader:     isLeader,
+		FencingToken: token,
+		Record:       record,
+		ObservedTime: observedTime,
+	}
+}
+
+// name returns the name of the election in metrics
+func (le *LeaderElector) name() string {
+	if le.config.Name != "" {
+		return le.config.Name
+	}
+	return le.config.Lock.Describe()
 }
 
 // RunOrDie starts a client with the provided config or panics if the config
@@ -221,6 +344,13 @@ func (le *LeaderElector) acquire(ctx context.Context) bool {
 	desc := le.config.Lock.Describe()
 	le.logger.Debugf("attempting to acquire leader lease %v...", desc)
 	wait.JitterUntil(func() {
+		le.termLock.Lock()
+		backoff := le.config.Clock.Now().Before(le.stepDownUntil)
+		le.termLock.Unlock()
+		if backoff {
+			le.logger.Debugf("stepped down from lease %v, not acquiring", desc)
+			return
+		}
 		succeeded = le.tryAcquireOrRenew(ctx)
 		le.maybeReportTransition()
 		if !succeeded {
@@ -257,7 +387,13 @@ func (le *LeaderElector) renew(ctx context.Context) {
 	}, le.config.RetryPeriod, ctx.Done())
 
 	// if we hold the lease, give it up
-	if le.config.ReleaseOnCancel {
+	le.termLock.Lock()
+	steppingDown := le.steppingDown
+	if steppingDown {
+		le.stepDownUntil = le.config.Clock.Now().Add(le.config.LeaseDuration)
+	}
+	le.termLock.Unlock()
+	if le.config.ReleaseOnCancel || steppingDown {
 		le.release()
 	}
 }
@@ -267,7 +403,7 @@ func (le *LeaderElector) release() bool {
 	if !le.IsLeader() {
 		return true
 	}
-	now := time.Now().UTC()
+	now := le.config.Clock.Now().UTC()
 	leaderElectionRecord := LeaderElectionRecord{
 		LeaderTransitions:    le.observedRecord.LeaderTransitions,
 		LeaseDurationSeconds: 1,
@@ -280,14 +416,29 @@ func (le *LeaderElector) release() bool {
 	}
 
 	le.setObservedRecord(&leaderElectionRecord)
+	le.observeMetrics(0)
 	return true
 }
 
+// TryAcquireOrRenew runs a single round of Run, it tries to acquire a leader lease if it is not already acquired,
+// else it tries to renew the lease if it has already been acquired. Returns true on success else returns false.
+func (le *LeaderElector) TryAcquireOrRenew(ctx context.Context) bool {
+	return le.tryAcquireOrRenew(ctx)
+}
+
 // tryAcquireOrRenew tries to acquire a leader lease if it is not already acquired,
 // else it tries to renew the lease if it has already been acquired. Returns true
 // on success else returns false.
-func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) bool {
-	now := time.Now().UTC()
+func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) (succeeded bool) {
+	start := time.Now()
+	defer func() {
+		var duration time.Duration
+		if succeeded {
+			duration = time.Since(start)
+		}
+		le.observeMetrics(duration)
+	}()
+	now := le.config.Clock.Now().UTC()
 	leaderElectionRecord := LeaderElectionRecord{
 		HolderIdentity:       le.config.Lock.Identity(),
 		LeaseDurationSeconds: int(le.config.LeaseDuration / time.Second),
@@ -305,9 +456,18 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) bool {
 		}
 
 		if err = le.config.Lock.Create(ctx, leaderElectionRecord); err != nil {
+			if errors.Is(err, ErrConflict) {
+				le.logger.Debugf("leader election record %v created by another candidate", le.config.Lock.Describe())
+				return false
+			}
 			le.logger.Errorf("error initially creating leader election record: %v", err)
 			return false
 		}
+		// read it back, a lock may complete the record, e.g. the transitions of a record created again after expiry
+		if record, raw, err := le.config.Lock.Get(ctx); err == nil && record.HolderIdentity == leaderElectionRecord.HolderIdentity {
+			leaderElectionRecord = *record
+			le.observedRawRecord = raw
+		}
 
 		le.setObservedRecord(&leaderElectionRecord)
 
@@ -339,6 +499,10 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) bool {
 
 	// update the lock itself
 	if err = le.config.Lock.Update(ctx, leaderElectionRecord); err != nil {
+		if errors.Is(err, ErrConflict) {
+			le.logger.Debugf("lock %v updated by another candidate", le.config.Lock.Describe())
+			return false
+		}
 		le.logger.Errorf("Failed to update lock: %v", err)
 		return false
 	}
@@ -366,7 +530,7 @@ func (le *LeaderElector) Check(maxTolerableExpiredLease time.Duration) error {
 	// If we are more than timeout seconds after the lease duration that is past the timeout
 	// on the lease renew. Time to start reporting ourselves as unhealthy. We should have
 	// died but conditions like deadlock can prevent this. (See #70819)
-	if time.Since(le.observedTime) > le.config.LeaseDuration+maxTolerableExpiredLease {
+	if le.config.Clock.Now().Sub(le.observedTime) > le.config.LeaseDuration+maxTolerableExpiredLease {
 		return fmt.Errorf("failed election to renew leadership on lease %s", le.config.Name)
 	}
 
@@ -380,7 +544,7 @@ func (le *LeaderElector) setObservedRecord(observedRecord *LeaderElectionRecord)
 	defer le.observedRecordLock.Unlock()
 
 	le.observedRecord = *observedRecord
-	le.observedTime = time.Now().UTC()
+	le.observedTime = le.config.Clock.Now().UTC()
 }
 
 // getObservedRecord returns observersRecord.
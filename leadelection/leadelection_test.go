@@ -0,0 +1,75 @@
+package leadelection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/kiga-hub/arc/leadelection"
+)
+
+func TestStepDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &configStore{configs: map[string]string{}}
+	tokens := make(chan int64, 2)
+	newElector := func(identity string) *leadelection.LeaderElector {
+		le, err := leadelection.NewLeaderElector(leadelection.LeaderElectionConfig{
+			Lock:          leadelection.NewNacosLock("DEFAULT_GROUP", "lead-test", identity, store),
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   50 * time.Millisecond,
+			Callbacks: leadelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					token, ok := leadelection.FencingTokenFromContext(ctx)
+					assert.True(t, ok)
+					tokens <- token
+				},
+				OnStoppedLeading: func() {},
+			},
+			Name: "test",
+		}, zap.NewNop().Sugar())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return le
+	}
+	a, b := newElector("a"), newElector("b")
+	assert.ErrorIs(t, a.StepDown(ctx), leadelection.ErrNotLeader)
+
+	doneA := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(doneA)
+	}()
+	tokenA := <-tokens
+	assert.True(t, a.IsLeader())
+	status := a.Status()
+	assert.True(t, status.IsLeader)
+	assert.Equal(t, "a", status.Record.HolderIdentity)
+	assert.Equal(t, tokenA, status.FencingToken)
+
+	doneB := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(doneB)
+	}()
+	assert.Nil(t, a.StepDown(ctx))
+	assert.False(t, a.IsLeader())
+	<-doneA
+
+	// b takes over the released lease before it expires
+	select {
+	case tokenB := <-tokens:
+		assert.Greater(t, tokenB, tokenA)
+	case <-time.After(900 * time.Millisecond):
+		t.Fatal("b did not take over")
+	}
+	assert.True(t, b.IsLeader())
+	assert.ErrorIs(t, a.StepDown(ctx), leadelection.ErrNotLeader)
+	cancel()
+	<-doneB
+}
@@ -0,0 +1,251 @@
+// Package locktest is a conformance suite of leadelection.ILock implementations
+package locktest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/kiga-hub/arc/leadelection"
+)
+
+const leaseDuration = 10 * time.Second
+
+// Factory creates a backend for a test and returns a function creating locks of the same record on it
+type Factory func(t *testing.T) func(identity string) leadelection.ILock
+
+// Clock is a leadelection.Clock set by tests
+type Clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// NewClock returns a Clock at now
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now implements leadelection.Clock
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Step moves the clock by d
+func (c *Clock) Step(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// hookLock runs hook before the next Update, to interleave another candidate between Get and Update
+type hookLock struct {
+	leadelection.ILock
+	hook func()
+}
+
+func (l *hookLock) Update(ctx context.Context, ler leadelection.LeaderElectionRecord) error {
+	if hook := l.hook; hook != nil {
+		l.hook = nil
+		hook()
+	}
+	return l.ILock.Update(ctx, ler)
+}
+
+func newElector(t *testing.T, lock leadelection.ILock, clock leadelection.Clock) *leadelection.LeaderElector {
+	le, err := leadelection.NewLeaderElector(leadelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: 5 * time.Second,
+		RetryPeriod:   time.Second,
+		Callbacks: leadelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {},
+			OnStoppedLeading: func() {},
+		},
+		Clock: clock,
+	}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return le
+}
+
+func record(identity string, now time.Time) leadelection.LeaderElectionRecord {
+	return leadelection.LeaderElectionRecord{
+		HolderIdentity:       identity,
+		LeaseDurationSeconds: int(leaseDuration / time.Second),
+		AcquireTime:          now.Unix(),
+		RenewTime:            now.Unix(),
+	}
+}
+
+// Run runs the suite on locks of factory
+func Run(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	t.Run("CreateGet", func(t *testing.T) {
+		newLock := factory(t)
+		a, b := newLock("a"), newLock("b")
+		assert.Equal(t, "a", a.Identity())
+		_, _, err := a.Get(ctx)
+		assert.ErrorIs(t, err, leadelection.ErrNotFound)
+		_, _, err = b.Get(ctx)
+		assert.ErrorIs(t, err, leadelection.ErrNotFound)
+
+		assert.Nil(t, a.Create(ctx, record("a", now)))
+		got, raw, err := b.Get(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, record("a", now), *got)
+		assert.NotEmpty(t, raw)
+		// b observed no record before
+		assert.ErrorIs(t, b.Create(ctx, record("b", now)), leadelection.ErrConflict)
+	})
+
+	t.Run("UpdateObserved", func(t *testing.T) {
+		newLock := factory(t)
+		a, b, c := newLock("a"), newLock("b"), newLock("c")
+		assert.Nil(t, a.Create(ctx, record("a", now)))
+		_, raw, err := b.Get(ctx)
+		assert.Nil(t, err)
+		assert.Nil(t, a.Update(ctx, record("a", now.Add(time.Second))))
+		assert.ErrorIs(t, b.Update(ctx, record("b", now)), leadelection.ErrConflict)
+		// a lock observing nothing can not update
+		assert.ErrorIs(t, c.Update(ctx, record("c", now)), leadelection.ErrConflict)
+
+		got, raw2, err := b.Get(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, record("a", now.Add(time.Second)), *got)
+		assert.NotEqual(t, raw, raw2)
+		assert.Nil(t, b.Update(ctx, record("b", now.Add(2*time.Second))))
+		assert.ErrorIs(t, a.Update(ctx, record("a", now.Add(2*time.Second))), leadelection.ErrConflict)
+		got, _, err = c.Get(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "b", got.HolderIdentity)
+	})
+
+	t.Run("ConcurrentAcquire", func(t *testing.T) {
+		newLock := factory(t)
+		clock := NewClock(now)
+		var electors []*leadelection.LeaderElector
+		for _, identity := range []string{"a", "b", "c", "d", "e"} {
+			electors = append(electors, newElector(t, newLock(identity), clock))
+		}
+		for round := 0; round < 3; round++ {
+			var wg sync.WaitGroup
+			var lock sync.Mutex
+			succeeded := 0
+			start := make(chan struct{})
+			for _, le := range electors {
+				wg.Add(1)
+				go func(le *leadelection.LeaderElector) {
+					defer wg.Done()
+					<-start
+					if le.TryAcquireOrRenew(ctx) {
+						lock.Lock()
+						succeeded++
+						lock.Unlock()
+					}
+				}(le)
+			}
+			close(start)
+			wg.Wait()
+			assert.Equal(t, 1, succeeded, "round %d", round)
+			clock.Step(time.Second)
+		}
+		leaders := 0
+		for _, le := range electors {
+			if le.IsLeader() {
+				leaders++
+			}
+		}
+		assert.Equal(t, 1, leaders)
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		newLock := factory(t)
+		clockA, clockB := NewClock(now), NewClock(now)
+		a, b := newElector(t, newLock("a"), clockA), newElector(t, newLock("b"), clockB)
+		assert.True(t, a.TryAcquireOrRenew(ctx))
+		assert.False(t, b.TryAcquireOrRenew(ctx))
+		assert.Equal(t, "a", b.GetLeader())
+
+		// a stops renewing, b takes over after the lease
+		clockB.Step(leaseDuration + time.Second)
+		assert.True(t, b.TryAcquireOrRenew(ctx))
+		assert.True(t, b.IsLeader())
+		clockA.Step(2 * time.Second)
+		assert.False(t, a.TryAcquireOrRenew(ctx))
+		assert.False(t, a.IsLeader())
+		assert.Equal(t, "b", a.GetLeader())
+	})
+
+	t.Run("FencingToken", func(t *testing.T) {
+		newLock := factory(t)
+		clock := NewClock(now)
+		a, b := newElector(t, newLock("a"), clock), newElector(t, newLock("b"), clock)
+		assert.True(t, a.TryAcquireOrRenew(ctx))
+		tokenA, ok := a.FencingToken()
+		assert.True(t, ok)
+		_, ok = b.FencingToken()
+		assert.False(t, ok)
+
+		// renewing keeps the token, each takeover increases it
+		clock.Step(time.Second)
+		assert.True(t, a.TryAcquireOrRenew(ctx))
+		token, _ := a.FencingToken()
+		assert.Equal(t, tokenA, token)
+		assert.False(t, b.TryAcquireOrRenew(ctx))
+		clock.Step(leaseDuration + time.Second)
+		assert.True(t, b.TryAcquireOrRenew(ctx))
+		tokenB, ok := b.FencingToken()
+		assert.True(t, ok)
+		assert.Greater(t, tokenB, tokenA)
+		assert.False(t, a.TryAcquireOrRenew(ctx))
+		_, ok = a.FencingToken()
+		assert.False(t, ok)
+		clock.Step(leaseDuration + time.Second)
+		assert.True(t, a.TryAcquireOrRenew(ctx))
+		token, _ = a.FencingToken()
+		assert.Greater(t, token, tokenB)
+	})
+
+	t.Run("SplitBrainTakeoverDuringRenew", func(t *testing.T) {
+		newLock := factory(t)
+		// the clock of b is ahead, so it takes over while a renews in time
+		clockA, clockB := NewClock(now), NewClock(now.Add(leaseDuration+time.Second))
+		lockA := &hookLock{ILock: newLock("a")}
+		a, b := newElector(t, lockA, clockA), newElector(t, newLock("b"), clockB)
+		assert.True(t, a.TryAcquireOrRenew(ctx))
+
+		clockA.Step(time.Second)
+		var bAcquired bool
+		lockA.hook = func() { bAcquired = b.TryAcquireOrRenew(ctx) }
+		assert.False(t, a.TryAcquireOrRenew(ctx))
+		assert.True(t, bAcquired)
+		assert.True(t, b.IsLeader())
+		assert.False(t, a.TryAcquireOrRenew(ctx))
+		assert.False(t, a.IsLeader())
+	})
+
+	t.Run("SplitBrainRenewDuringTakeover", func(t *testing.T) {
+		newLock := factory(t)
+		clockA, clockB := NewClock(now), NewClock(now.Add(leaseDuration+time.Second))
+		lockB := &hookLock{ILock: newLock("b")}
+		a, b := newElector(t, newLock("a"), clockA), newElector(t, lockB, clockB)
+		assert.True(t, a.TryAcquireOrRenew(ctx))
+
+		clockA.Step(time.Second)
+		var aRenewed bool
+		lockB.hook = func() { aRenewed = a.TryAcquireOrRenew(ctx) }
+		assert.False(t, b.TryAcquireOrRenew(ctx))
+		assert.True(t, aRenewed)
+		assert.True(t, a.IsLeader())
+		assert.False(t, b.IsLeader())
+	})
+}
@@ -0,0 +1,44 @@
+package leadelection
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	isLeaderGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "leader_election",
+		Name:      "is_leader",
+		Help:      "1 if the elector holds the lease, else 0.",
+	}, []string{"name"})
+	transitionsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "leader_election",
+		Name:      "transitions",
+		Help:      "Leader transitions of the observed record, the fencing token of the current leader.",
+	}, []string{"name"})
+	renewDurationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "leader_election",
+		Name:      "renew_duration_seconds",
+		Help:      "Duration of the last successful round acquiring or renewing the lease.",
+	}, []string{"name"})
+)
+
+func init() {
+	prometheus.MustRegister(isLeaderGauge, transitionsGauge, renewDurationGauge)
+}
+
+// observeMetrics sets gauges from the observed record, duration is the last successful round, 0 if it failed
+func (le *LeaderElector) observeMetrics(duration time.Duration) {
+	name := le.name()
+	record := le.getObservedRecord()
+	leader := 0.0
+	if record.HolderIdentity == le.config.Lock.Identity() {
+		leader = 1
+	}
+	isLeaderGauge.WithLabelValues(name).Set(leader)
+	transitionsGauge.WithLabelValues(name).Set(float64(record.LeaderTransitions))
+	if duration > 0 {
+		renewDurationGauge.WithLabelValues(name).Set(duration.Seconds())
+	}
+}
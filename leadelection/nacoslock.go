@@ -4,20 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/kiga-hub/arc/configuration"
 )
 
-// NacosLock is a lock using nacos as backend
+// NacosConfigStore is the config API of nacos used by NacosLock, it is implemented by *configuration.NacosClient
+type NacosConfigStore interface {
+	Get(dataID, group string) (string, error)
+	PublishCas(dataID, group, content, casMd5 string) (bool, error)
+}
+
+// emptyMd5 is the md5 of empty content, publishing with it creates the record only if it does not exist
+var emptyMd5 = configuration.ContentMd5("")
+
+// NacosLock is a lock using nacos as backend, writes are published with the md5 of the observed record (CAS)
 type NacosLock struct {
-	client   *configuration.NacosClient
+	client   NacosConfigStore
 	group    string
 	dataID   string
 	identity string
+
+	lock        sync.Mutex
+	observedMd5 string // md5 of the record last read or written, empty if none
 }
 
 // NewNacosLock return a new NacosLock
-func NewNacosLock(group, dataID, identity string, client *configuration.NacosClient) *NacosLock {
+func NewNacosLock(group, dataID, identity string, client NacosConfigStore) *NacosLock {
 	return &NacosLock{
 		client:   client,
 		group:    group,
@@ -27,14 +40,14 @@ func NewNacosLock(group, dataID, identity string, client *configuration.NacosCli
 }
 
 // Get returns the LeaderElectionRecord
-func (lock NacosLock) Get(ctx context.Context) (*LeaderElectionRecord, []byte, error) {
+func (lock *NacosLock) Get(ctx context.Context) (*LeaderElectionRecord, []byte, error) {
 	_ = ctx
 	var record LeaderElectionRecord
-	var err error
 	recordStr, err := lock.client.Get(lock.dataID, lock.group)
 	if err != nil {
 		return nil, nil, err
 	}
+	lock.setObserved(recordStr)
 	if recordStr == "" {
 		return nil, nil, ErrNotFound
 	}
@@ -46,30 +59,64 @@ func (lock NacosLock) Get(ctx context.Context) (*LeaderElectionRecord, []byte, e
 }
 
 // Create attempts to create a LeaderElectionRecord
-func (lock NacosLock) Create(ctx context.Context, ler LeaderElectionRecord) error {
+func (lock *NacosLock) Create(ctx context.Context, ler LeaderElectionRecord) error {
 	_ = ctx
+	return lock.publish(ler, emptyMd5)
+}
+
+// Update will update and existing LeaderElectionRecord
+func (lock *NacosLock) Update(ctx context.Context, ler LeaderElectionRecord) error {
+	_ = ctx
+	lock.lock.Lock()
+	observed := lock.observedMd5
+	lock.lock.Unlock()
+	if observed == "" || observed == emptyMd5 {
+		return ErrConflict
+	}
+	return lock.publish(ler, observed)
+}
+
+// publish writes the record if the md5 of the record on the server is casMd5
+func (lock *NacosLock) publish(ler LeaderElectionRecord, casMd5 string) error {
 	recordBytes, err := json.Marshal(ler)
 	if err != nil {
 		return err
 	}
-	_, err = lock.client.Publish(lock.dataID, lock.group, string(recordBytes))
-	return err
+	content := string(recordBytes)
+	ok, err := lock.client.PublishCas(lock.dataID, lock.group, content, casMd5)
+	if err != nil {
+		// the response may be lost after the record is written
+		if current, getErr := lock.client.Get(lock.dataID, lock.group); getErr == nil && current == content {
+			lock.setObserved(content)
+			return nil
+		}
+		return err
+	}
+	if !ok {
+		return ErrConflict
+	}
+	lock.setObserved(content)
+	return nil
 }
 
-// Update will update and existing LeaderElectionRecord
-func (lock NacosLock) Update(ctx context.Context, ler LeaderElectionRecord) error {
-	return lock.Create(ctx, ler)
+func (lock *NacosLock) setObserved(content string) {
+	lock.lock.Lock()
+	defer lock.lock.Unlock()
+	lock.observedMd5 = ""
+	if content != "" {
+		lock.observedMd5 = configuration.ContentMd5(content)
+	}
 }
 
 // RecordEvent is used to record events
-func (lock NacosLock) RecordEvent(string) {}
+func (lock *NacosLock) RecordEvent(string) {}
 
 // Identity will return the locks Identity
-func (lock NacosLock) Identity() string {
+func (lock *NacosLock) Identity() string {
 	return lock.identity
 }
 
 // Describe is used to convert details on current resource lock into a string
-func (lock NacosLock) Describe() string {
+func (lock *NacosLock) Describe() string {
 	return fmt.Sprintf("%v/%v", lock.group, lock.dataID)
 }
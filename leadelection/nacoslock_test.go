@@ -0,0 +1,42 @@
+package leadelection_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kiga-hub/arc/configuration"
+	"github.com/kiga-hub/arc/leadelection"
+	"github.com/kiga-hub/arc/leadelection/locktest"
+)
+
+// configStore publishes configs with CAS like nacos
+type configStore struct {
+	lock    sync.Mutex
+	configs map[string]string
+}
+
+func (s *configStore) Get(dataID, group string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.configs[group+"/"+dataID], nil
+}
+
+func (s *configStore) PublishCas(dataID, group, content, casMd5 string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := group + "/" + dataID
+	if current, ok := s.configs[key]; ok && configuration.ContentMd5(current) != casMd5 {
+		return false, nil
+	}
+	s.configs[key] = content
+	return true, nil
+}
+
+func TestNacosLock(t *testing.T) {
+	locktest.Run(t, func(t *testing.T) func(identity string) leadelection.ILock {
+		store := &configStore{configs: map[string]string{}}
+		return func(identity string) leadelection.ILock {
+			return leadelection.NewNacosLock("DEFAULT_GROUP", "lead-test", identity, store)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"sync"
 
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
@@ -47,7 +48,12 @@ func CreateLogger(basicConfig *microConf.BasicConfig, logConfig *conf.LogConfig)
 	if err != nil {
 		return nil, fmt.Errorf("fatal to parse logger level: %s", err)
 	}
+	return CreateLoggerWithLevel(basicConfig, logConfig, level)
+}
 
+// CreateLoggerWithLevel create a logger whose level can be changed in runtime by the given level, logConfig.Level is ignored
+func CreateLoggerWithLevel(basicConfig *microConf.BasicConfig, logConfig *conf.LogConfig, level zap.AtomicLevel) (*zap.Logger, error) {
+	var err error
 	var cores []zapcore.Core
 
 	// graylog -
@@ -139,7 +145,7 @@ type LoggerGroup struct {
 	moduleLevel   *sync.Map
 	levelLogger   *sync.Map
 	defaultLogger ILogger
-	defaultLevel  string
+	defaultLevel  *atomic.String
 }
 
 const (
@@ -186,29 +192,34 @@ func CreateLoggerGroup(basicConfig *microConf.BasicConfig, logConfig *conf.LogGr
 		moduleLevel:   &sync.Map{},
 		levelLogger:   &levelLogger,
 		defaultLogger: &NoopLogger{},
-		defaultLevel:  LevelInfo,
+		defaultLevel:  atomic.NewString(LevelInfo),
 	}
 
 	if logConfig != nil {
-		group.defaultLevel = logConfig.Level
-		l, ok := levelLogger.Load(strings.ToUpper(logConfig.Level))
-		if ok {
-			group.defaultLogger = l.(*zap.SugaredLogger)
-		}
-		for l, modules := range logConfig.Levels {
-			for _, module := range modules {
-				group.SetLevel(module, l)
-			}
-		}
+		group.SetLevels(logConfig.Level, logConfig.Levels)
 	}
 	return group, nil
 }
 
+// SetLevels replaces the default level and levels of all modules, e.g. when the config file is reloaded
+func (l *LoggerGroup) SetLevels(defaultLevel string, levels map[string][]string) {
+	l.defaultLevel.Store(strings.ToUpper(defaultLevel))
+	l.moduleLevel.Range(func(key, value interface{}) bool {
+		l.moduleLevel.Delete(key)
+		return true
+	})
+	for level, modules := range levels {
+		for _, module := range modules {
+			l.SetLevel(module, level)
+		}
+	}
+}
+
 // M return the logger for the module, will return logger w/ INFO level if level is not set for the module
 func (l *LoggerGroup) M(module string) ILogger {
 	level, ok := l.moduleLevel.Load(strings.ToUpper(module))
 	if !ok {
-		return l.defaultLogger
+		level = l.defaultLevel.Load()
 	}
 	logger, ok := l.levelLogger.Load(level)
 	if !ok {
@@ -230,7 +241,7 @@ func (l *LoggerGroup) SetLevel(module, level string) {
 		if ok {
 			return
 		}
-		level = l.defaultLevel
+		level = l.defaultLevel.Load()
 	}
 	l.moduleLevel.Store(strings.ToUpper(module), strings.ToUpper(level))
 }
@@ -0,0 +1,99 @@
+package micro
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/pangpanglabs/echoswagger/v2"
+	"golang.org/x/net/netutil"
+
+	"github.com/kiga-hub/arc/micro/conf"
+)
+
+// IAdminHandler is implemented by components having control or debug endpoints,
+// they are served on the admin port if it is set, otherwise on the api port
+type IAdminHandler interface {
+	SetupAdminHandler(root echoswagger.ApiRoot, base string) error
+}
+
+// newSwagger create the swagger api root of e
+func (m *Server) newSwagger(e *echo.Echo, basicConf *conf.BasicConfig, swaggerURI, swaggerAssetsPath string) echoswagger.ApiRoot {
+	e.Static(swaggerAssetsPath, "./swagger")
+	// swagger spec path is `/swagger/swagger.json`
+	root := echoswagger.New(e, swaggerURI, &echoswagger.Info{
+		Title:   basicConf.AppName,
+		Version: basicConf.AppVersion,
+	}).SetRequestContentType("application/json").
+		SetResponseContentType("application/json").
+		SetUI(echoswagger.UISetting{
+			CDN: swaggerAssetsPath,
+		})
+	addSecurityDefinitions(root)
+	return root
+}
+
+// setupAdmin create the admin server if admin port is set, it shares host, network, tls and auth with the api server.
+// The api server and its swagger are returned if admin port is not set.
+func (m *Server) setupAdmin(basicConf *conf.BasicConfig, swaggerURI, swaggerAssetsPath string) (*echo.Echo, echoswagger.ApiRoot, error) {
+	if basicConf.AdminPort <= 0 {
+		return m.e, m.apiRoot, nil
+	}
+	if basicConf.AdminPort == basicConf.APIPort {
+		return nil, nil, fmt.Errorf("admin port %d is the same as api port", basicConf.AdminPort)
+	}
+	_, addr, err := listenAddress(basicConf, basicConf.AdminPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	admin := echo.New()
+	admin.Use(zapLoggerEchoMiddleware(m.loggerHTTPFunc, !basicConf.IsDevMode))
+	admin.Use(m.auth.Handle(swaggerURI, swaggerAssetsPath+"/*"))
+	admin.Use(middleware.Recover())
+	root := m.newSwagger(admin, basicConf, swaggerURI, swaggerAssetsPath)
+
+	m.adminServer = &http.Server{
+		Addr:    addr,
+		Handler: admin,
+	}
+	if m.certs != nil {
+		m.adminServer.TLSConfig, err = m.certs.TLSConfig(basicConf.TLSClientAuth)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return admin, root, nil
+}
+
+// serve listens on the address of server and serves it in background, at most maxConns connections are accepted if it is positive
+func (m *Server) serve(server *http.Server, maxConns int) error {
+	l, err := net.Listen(m.network, server.Addr)
+	if err != nil {
+		return err
+	}
+	ln := l
+	if maxConns > 0 {
+		ln = netutil.LimitListener(l, maxConns)
+	}
+	go func() {
+		m.loggerHTTPFunc().Infow("start http server", "network", m.network, "addr", server.Addr, "tls", m.certs != nil)
+		var err error
+		if m.certs != nil {
+			err = server.ServeTLS(ln, "", "")
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			m.loggerHTTPFunc().Error(err)
+		}
+		err = ln.Close()
+		if err != nil {
+			m.loggerHTTPFunc().Error(err)
+		}
+		m.loggerHTTPFunc().Infow("stop http server", "addr", server.Addr)
+	}()
+	return nil
+}
@@ -0,0 +1,343 @@
+package micro
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc/micro/conf"
+	"github.com/kiga-hub/arc/utils"
+)
+
+const (
+	// ScopeAdmin is the scope of control and debug endpoints, e.g. pprof, log levels and gossip kv changes
+	ScopeAdmin = "admin"
+
+	// SecurityAPIKey is the name of the api key security definition in swagger
+	SecurityAPIKey = "X-API-Key"
+	// SecurityBearer is the name of the json web token security definition in swagger
+	SecurityBearer = "Authorization"
+
+	// AuthMethodAPIKey means the identity is authenticated by an api key
+	AuthMethodAPIKey = "apikey"
+	// AuthMethodJWT means the identity is authenticated by a json web token
+	AuthMethodJWT = "jwt"
+	// AuthMethodMTLS means the identity is authenticated by a client certificate
+	AuthMethodMTLS = "mtls"
+
+	identityContextKey = "micro.identity"
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	Name   string   `json:"name"`
+	Method string   `json:"method"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// HasScopes returns whether the identity is granted all the scopes
+func (i *Identity) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		granted := false
+		for _, s := range i.Scopes {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
+
+// GetIdentity returns the identity authenticated for the request, nil if the route is not secured
+func GetIdentity(c echo.Context) *Identity {
+	identity, _ := c.Get(identityContextKey).(*Identity)
+	return identity
+}
+
+// routeScopes keeps the scopes declared for routes, method + " " + path - []string
+var routeScopes sync.Map
+
+func routeScopesKey(method, path string) string {
+	return method + " " + path
+}
+
+// SecureRoute declares a route requiring an authenticated identity granted all the scopes
+func SecureRoute(method, path string, scopes ...string) {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	routeScopes.Store(routeScopesKey(method, path), scopes)
+}
+
+// Secure declares the route of the api requires an authenticated identity granted all the scopes,
+// and documents it in swagger. No scope means any authenticated identity.
+func Secure(api echoswagger.Api, scopes ...string) echoswagger.Api {
+	route := api.Route()
+	SecureRoute(route.Method, route.Path, scopes...)
+	return api.
+		SetSecurityWithScope(map[string][]string{SecurityAPIKey: scopes}).
+		SetSecurityWithScope(map[string][]string{SecurityBearer: scopes})
+}
+
+// addSecurityDefinitions documents the ways of authentication in swagger
+func addSecurityDefinitions(root echoswagger.ApiRoot) {
+	root.AddSecurityAPIKey(SecurityAPIKey, "static api key", echoswagger.SecurityInHeader).
+		AddSecurityAPIKey(SecurityBearer, "json web token, Bearer {token}", echoswagger.SecurityInHeader)
+}
+
+// authenticator authenticates requests by api keys, json web tokens and client certificates
+type authenticator struct {
+	config  *conf.AuthConfig
+	apiKeys []conf.AuthIdentity
+	clients map[string][]string // common name - scopes
+	jwtKeys map[string]interface{}
+	parser  *jwt.Parser
+}
+
+func newAuthenticator(config *conf.AuthConfig) (*authenticator, error) {
+	a := &authenticator{
+		config:  config,
+		clients: map[string][]string{},
+		jwtKeys: map[string]interface{}{},
+	}
+	for _, k := range config.APIKeys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("api key of %s is empty", k.Name)
+		}
+		a.apiKeys = append(a.apiKeys, k)
+	}
+	for _, c := range config.Clients {
+		a.clients[c.Name] = c.Scopes
+	}
+	var methods []string
+	if config.JWT.Secret != "" {
+		a.jwtKeys[""] = []byte(config.JWT.Secret)
+	}
+	if config.JWT.JWKS != "" {
+		keys, err := loadJWKS(config.JWT.JWKS)
+		if err != nil {
+			return nil, err
+		}
+		for kid, key := range keys {
+			a.jwtKeys[kid] = key
+		}
+	}
+	for _, key := range a.jwtKeys {
+		switch key.(type) {
+		case []byte:
+			methods = append(methods, "HS256", "HS384", "HS512")
+		case *rsa.PublicKey:
+			methods = append(methods, "RS256", "RS384", "RS512")
+		}
+	}
+	a.parser = &jwt.Parser{ValidMethods: methods}
+	return a, nil
+}
+
+// jsonWebKey is a key in JWKS, only RSA and oct keys are supported
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+func loadJWKS(file string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	err = json.Unmarshal(data, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwks %s: %s", file, err)
+	}
+	keys := map[string]interface{}{}
+	for _, k := range jwks.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("invalid n of key %s: %s", k.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("invalid e of key %s: %s", k.Kid, err)
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return nil, fmt.Errorf("invalid k of key %s: %s", k.Kid, err)
+			}
+			keys[k.Kid] = secret
+		default:
+			return nil, fmt.Errorf("unsupported kty %s of key %s", k.Kty, k.Kid)
+		}
+	}
+	return keys, nil
+}
+
+// keyFunc selects the key by kid, the type of key must match the signing method
+func (a *authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := a.jwtKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %s", kid)
+	}
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if _, ok = key.([]byte); ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodRSA:
+		if _, ok = key.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("key %s does not match signing method %s", kid, token.Method.Alg())
+}
+
+func (a *authenticator) authenticateJWT(raw string) (*Identity, error) {
+	if len(a.jwtKeys) == 0 {
+		return nil, fmt.Errorf("json web token is not accepted")
+	}
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, a.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if a.config.JWT.Issuer != "" && !claims.VerifyIssuer(a.config.JWT.Issuer, true) {
+		return nil, fmt.Errorf("invalid issuer")
+	}
+	if a.config.JWT.Audience != "" && !claims.VerifyAudience(a.config.JWT.Audience, true) {
+		return nil, fmt.Errorf("invalid audience")
+	}
+	identity := &Identity{Method: AuthMethodJWT}
+	identity.Name, _ = claims["sub"].(string)
+	switch v := claims[a.config.JWT.ScopeClaim].(type) {
+	case string:
+		identity.Scopes = strings.Fields(v)
+	case []interface{}:
+		for _, s := range v {
+			if scope, ok := s.(string); ok {
+				identity.Scopes = append(identity.Scopes, scope)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// authenticate returns the identity of the request, client certificate takes precedence over api key and token
+func (a *authenticator) authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if scopes, ok := a.clients[name]; ok {
+			return &Identity{Name: name, Method: AuthMethodMTLS, Scopes: scopes}, nil
+		}
+	}
+	if key := r.Header.Get(SecurityAPIKey); key != "" {
+		for _, k := range a.apiKeys {
+			if subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) == 1 {
+				return &Identity{Name: k.Name, Method: AuthMethodAPIKey, Scopes: k.Scopes}, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid api key")
+	}
+	if auth := r.Header.Get(SecurityBearer); auth != "" {
+		const prefix = "Bearer "
+		if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+			return nil, fmt.Errorf("invalid authorization header")
+		}
+		return a.authenticateJWT(auth[len(prefix):])
+	}
+	return nil, fmt.Errorf("no credentials")
+}
+
+// AuthMiddleware authenticates and authorizes requests of routes declared by Secure, the config can be updated in runtime
+type AuthMiddleware struct {
+	auth atomic.Value
+}
+
+// NewAuthMiddleware create an AuthMiddleware
+func NewAuthMiddleware(config *conf.AuthConfig) (*AuthMiddleware, error) {
+	m := &AuthMiddleware{}
+	err := m.Update(config)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Update replaces the config, the jwks file is loaded again
+func (m *AuthMiddleware) Update(config *conf.AuthConfig) error {
+	a, err := newAuthenticator(config)
+	if err != nil {
+		return err
+	}
+	m.auth.Store(a)
+	return nil
+}
+
+// Handle is the echo.MiddlewareFunc, public paths are never authenticated
+func (m *AuthMiddleware) Handle(public ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			a := m.auth.Load().(*authenticator)
+			if !a.config.Enable {
+				return next(c)
+			}
+			path := c.Path()
+			for _, p := range public {
+				if path == p {
+					return next(c)
+				}
+			}
+			var scopes []string
+			if v, ok := routeScopes.Load(routeScopesKey(c.Request().Method, path)); ok {
+				scopes = v.([]string)
+			} else if !a.config.AuthenticateAll {
+				return next(c)
+			}
+
+			identity, err := a.authenticate(c.Request())
+			if err != nil {
+				c.Response().Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				return c.JSON(http.StatusUnauthorized, utils.ResponseV2{
+					Code: http.StatusUnauthorized,
+					Msg:  http.StatusText(http.StatusUnauthorized) + ":" + err.Error(),
+				})
+			}
+			if !identity.HasScopes(scopes...) {
+				return c.JSON(http.StatusForbidden, utils.ResponseV2{
+					Code: http.StatusForbidden,
+					Msg:  http.StatusText(http.StatusForbidden) + ":" + strings.Join(scopes, " "),
+				})
+			}
+			c.Set(identityContextKey, identity)
+			return next(c)
+		}
+	}
+}
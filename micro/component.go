@@ -74,6 +74,21 @@ func (c *EmptyComponent) OnServiceChanged(services []model.SubscribeService, err
 	_ = err
 }
 
+// DependsOn returns names of components which must be set up before this one
+func (c *EmptyComponent) DependsOn() []string {
+	return nil
+}
+
+// DependsOnElements returns keys of elements which must be registered before this one is initialized
+func (c *EmptyComponent) DependsOnElements() []*ElementKey {
+	return nil
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *EmptyComponent) ProvidesElements() []*ElementKey {
+	return nil
+}
+
 // SetupHandler of echo if the component need
 func (c *EmptyComponent) SetupHandler(root echoswagger.ApiRoot, base string) error {
 	_ = root
@@ -0,0 +1,155 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/micro"
+	"github.com/kiga-hub/arc/utils/wait"
+)
+
+const (
+	clusterStateJoining  = "joining"
+	clusterStateJoined   = "joined"
+	clusterStateDegraded = "degraded"
+)
+
+// errNotJoined means the component has not joined a cluster yet
+var errNotJoined = errors.New("not joined the cluster")
+
+// clusterJoin tracks joining a cluster of a swarm component, the component is degraded if it does not join before
+// the timeout, and it keeps retrying instead of crashing the service
+type clusterJoin struct {
+	lock    sync.Mutex
+	start   time.Time // startup, or the time the component was last joined
+	timeout time.Duration
+	joined  bool
+	err     error              // last error of joining
+	cancel  context.CancelFunc // cancels the attempt in progress, e.g. joining the previous leader
+}
+
+func newClusterJoin(timeout time.Duration) *clusterJoin {
+	return &clusterJoin{
+		start:   time.Now(),
+		timeout: timeout,
+	}
+}
+
+// begin starts an attempt to join, e.g. when a new leader is found. The previous attempt is cancelled, and the
+// component is not joined until the attempt succeeds. The timeout is not restarted by new attempts,
+// so a component which keeps failing to join is degraded though leaders change
+func (j *clusterJoin) begin(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.cancel = cancel
+	j.leave()
+	j.err = nil
+	return ctx, cancel
+}
+
+// leave marks the component not joined with the lock held, the timeout is measured from now if it was joined
+func (j *clusterJoin) leave() {
+	if j.joined {
+		j.start = time.Now()
+	}
+	j.joined = false
+}
+
+func (j *clusterJoin) setJoined() {
+	j.lock.Lock()
+	j.joined, j.err = true, nil
+	j.lock.Unlock()
+}
+
+func (j *clusterJoin) setError(err error) {
+	j.lock.Lock()
+	j.leave()
+	j.err = err
+	j.lock.Unlock()
+}
+
+// state returns the state of joining and the last error
+func (j *clusterJoin) state() (string, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	switch {
+	case j.joined:
+		return clusterStateJoined, nil
+	case time.Since(j.start) < j.timeout:
+		return clusterStateJoining, j.err
+	default:
+		return clusterStateDegraded, j.err
+	}
+}
+
+// setStatus sets params of the state to status, it is not ok if degraded
+func (j *clusterJoin) setStatus(status *micro.ComponentStatus) {
+	state, err := j.state()
+	status.Params["cluster"] = state
+	if err != nil {
+		status.Params["clusterError"] = err.Error()
+	}
+	status.IsOK = status.IsOK && state != clusterStateDegraded
+}
+
+// healthCheck fails readiness if degraded
+func (j *clusterJoin) healthCheck() micro.HealthCheck {
+	return micro.HealthCheck{
+		Name:  "cluster",
+		Level: micro.HealthLevelCritical,
+		Check: func(context.Context) error {
+			state, err := j.state()
+			if state != clusterStateDegraded {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w in %s: %v", errNotJoined, j.timeout, err)
+			}
+			return fmt.Errorf("%w in %s", errNotJoined, j.timeout)
+		},
+	}
+}
+
+// watch logs once if the component does not join before the timeout
+func (j *clusterJoin) watch(ctx context.Context, logger logging.ILogger) {
+	timer := time.NewTimer(j.timeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+		if state, err := j.state(); state == clusterStateDegraded {
+			logger.Errorf("join cluster timeout after %s, keep retrying: %v", j.timeout, err)
+		}
+	}
+}
+
+// retry runs join with exponential backoff until it succeeds or ctx is done, returns true if it succeeds.
+// ctx should be of begin, so a cancelled attempt does not change the state
+func (j *clusterJoin) retry(ctx context.Context, logger logging.ILogger, join func() error) (joined bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	backoff := wait.NewExponentialBackoffManager(time.Second, 30*time.Second, 2, 0.2)
+	wait.BackoffUntil(func() {
+		err := join()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			j.setError(err)
+			logger.Warnf("failed to join cluster: %v", err)
+			return
+		}
+		j.setJoined()
+		joined = true
+		cancel()
+	}, backoff, true, ctx.Done())
+	return joined
+}
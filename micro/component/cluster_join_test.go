@@ -0,0 +1,50 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestClusterJoin(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	j := newClusterJoin(50 * time.Millisecond)
+	ctx, cancel := j.begin(context.Background())
+	assert.True(t, j.retry(ctx, logger, func() error { return nil }))
+	cancel()
+	state, err := j.state()
+	assert.Equal(t, clusterStateJoined, state)
+	assert.Nil(t, err)
+
+	// a new leader cancels the previous attempt and the component is not joined until it joins again
+	first, cancel := j.begin(context.Background())
+	defer cancel()
+	second, cancel := j.begin(context.Background())
+	defer cancel()
+	assert.NotNil(t, first.Err())
+	assert.Nil(t, second.Err())
+	state, _ = j.state()
+	assert.Equal(t, clusterStateJoining, state)
+
+	failed := errors.New("leader down")
+	go j.retry(second, logger, func() error { return failed })
+	assert.Eventually(t, func() bool {
+		state, err := j.state()
+		return state == clusterStateDegraded && errors.Is(err, failed)
+	}, time.Second, 10*time.Millisecond)
+	assert.NotNil(t, j.healthCheck().Check(context.Background()))
+
+	// another leader does not restart the timeout of a component not joined
+	third, cancel := j.begin(context.Background())
+	defer cancel()
+	assert.NotNil(t, second.Err())
+	state, _ = j.state()
+	assert.Equal(t, clusterStateDegraded, state)
+	assert.True(t, j.retry(third, logger, func() error { return nil }))
+	state, _ = j.state()
+	assert.Equal(t, clusterStateJoined, state)
+}
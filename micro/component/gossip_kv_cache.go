@@ -25,6 +25,7 @@ import (
 	"github.com/kiga-hub/arc/micro"
 	microConf "github.com/kiga-hub/arc/micro/conf"
 	"github.com/kiga-hub/arc/utils"
+	"github.com/kiga-hub/arc/utils/wait"
 
 	_ "go.uber.org/automaxprocs" // Used to get the actual number of CPU's in the container.
 )
@@ -50,10 +51,12 @@ type GossipKVCacheComponent struct {
 	ctx           context.Context
 	ctxCancel     context.CancelFunc
 	leaderElector *leadelection.LeaderElector
-	isSetuped     bool
+	join          *clusterJoin
 	list          *memberlist.Memberlist
 	opsLock       sync.Mutex
 	broadcasts    *memberlist.TransmitLimitedQueue
+	// trackConnection registers proxied websockets to be drained when server shuts down
+	trackConnection func() (context.Context, func())
 
 	// OnJoinCluster will be called when find a new leader or/and join a cluster, it might be called multiple times.
 	OnJoinCluster func()
@@ -61,23 +64,64 @@ type GossipKVCacheComponent struct {
 	OnNodeJoin func(*GossipKVCacheNodeMeta)
 	// OnNodeLeave will be called when a node leave the cluster
 	OnNodeLeave func(*GossipKVCacheNodeMeta)
+	// OnSensorOrphaned will be called with sensor ids assigned to this node after their owner left the cluster for
+	// OrphanGracePeriod, it returns ids claimed by this node, the others are released
+	OnSensorOrphaned func(sensorIDs []string) []string
 
 	InMachineMode bool
 	ClusterName   string
 	inClusterIP   string
 	Port          int
-
-	metadata []byte
-	meta     *GossipKVCacheNodeMeta
-	metas    map[string]*GossipKVCacheNodeMeta
-	items    map[string]string //key-value e.g. sensor id-cluster
+	// TombstoneTTL is how long deleted items are remembered, a member partitioned longer may bring them back, 24h if 0
+	TombstoneTTL time.Duration
+	// OrphanGracePeriod is how long sensors of a node left are kept before they are handed over, 30s if 0
+	OrphanGracePeriod time.Duration
+
+	metadata  []byte
+	meta      *GossipKVCacheNodeMeta
+	metas     map[string]*GossipKVCacheNodeMeta
+	leftNodes map[string]*leftNode // nodes left, by name
+	items     *kvStore             //key-value e.g. sensor id-cluster
 }
 
+const defaultTombstoneTTL = 24 * time.Hour
+
 // Name of the component
 func (c *GossipKVCacheComponent) Name() string {
 	return "KVCache"
 }
 
+// DependsOnElements returns keys of elements which must be registered before this one is initialized,
+// the nacos client is registered by the server if dynamic config is used, and it is only needed in swarm
+func (c *GossipKVCacheComponent) DependsOnElements() []*micro.ElementKey {
+	if !microConf.GetBasicConfig().InSwarm {
+		return nil
+	}
+	return []*micro.ElementKey{&micro.NacosClientElementKey}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *GossipKVCacheComponent) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&micro.GossipKVCacheElementKey}
+}
+
+// Status of the component, it is not ok if it does not join the cluster in time
+func (c *GossipKVCacheComponent) Status() *micro.ComponentStatus {
+	status := c.EmptyComponent.Status()
+	if c.join != nil {
+		c.join.setStatus(status)
+	}
+	return status
+}
+
+// HealthChecks returns checks of the component, called after Init()
+func (c *GossipKVCacheComponent) HealthChecks() []micro.HealthCheck {
+	if c.join == nil {
+		return nil
+	}
+	return []micro.HealthCheck{c.join.healthCheck()}
+}
+
 // Init the component
 func (c *GossipKVCacheComponent) Init(server *micro.Server) error {
 	basicConf := microConf.GetBasicConfig()
@@ -91,9 +135,11 @@ func (c *GossipKVCacheComponent) Init(server *micro.Server) error {
 	c.l = micro.GenerateLoggerForModule(server, c.Name())
 	c.nacosClient = server.GetElement(&micro.NacosClientElementKey).(*configuration.NacosClient)
 	c.ctx, c.ctxCancel = context.WithCancel(context.Background())
+	c.trackConnection = server.TrackConnection
 	c.opsLock = sync.Mutex{}
-	c.items = map[string]string{}
 	c.metas = map[string]*GossipKVCacheNodeMeta{}
+	c.leftNodes = map[string]*leftNode{}
+	c.join = newClusterJoin(time.Duration(basicConf.JoinTimeout) * time.Millisecond)
 
 	var workLoad int
 	if basicConf.WorkLoad > 0 {
@@ -120,6 +166,13 @@ func (c *GossipKVCacheComponent) Init(server *micro.Server) error {
 	}
 	c.metadata = bs
 	c.metas[name] = c.meta
+	c.items = newKVStore(name)
+	if c.TombstoneTTL <= 0 {
+		c.TombstoneTTL = defaultTombstoneTTL
+	}
+	if c.OrphanGracePeriod <= 0 {
+		c.OrphanGracePeriod = defaultOrphanGracePeriod
+	}
 
 	if c.InMachineMode {
 		c.inClusterIP = server.PrivateIP.String()
@@ -180,20 +233,7 @@ func (c *GossipKVCacheComponent) PostStart(ctx context.Context) error {
 					return
 				}
 				c.l().Debug("OnNewLeader " + identity)
-				c.isSetuped = true
-
-				if identity == c.inClusterIP {
-					c.l().Debug("OnNewLeader it's me")
-					return
-				}
-				_, err = c.list.Join([]string{fmt.Sprintf("%s:%d", identity, c.Port)})
-				if err != nil {
-					panic("Failed to join cluster: " + err.Error())
-				}
-				if c.OnJoinCluster != nil {
-					c.OnJoinCluster()
-				}
-				c.l().Debug("OnNewLeader done")
+				c.joinCluster(identity)
 			},
 			OnStopRunning: func() {
 				go c.leaderElector.Run(c.ctx)
@@ -210,27 +250,37 @@ func (c *GossipKVCacheComponent) PostStart(ctx context.Context) error {
 	}
 	go c.leaderElector.Run(c.ctx)
 
-	// wait until join cluster or timeout
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
-		stop := time.NewTimer(time.Second * 30)
-		defer stop.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				if c.isSetuped {
-					return
-				}
-			case <-stop.C:
-				c.l().Debug("join cluster timeout")
-				panic("cannot build cluster")
-			}
-		}
-	}()
+	// report degraded if not joined in time, e.g. nacos is down
+	go c.join.watch(c.ctx, c.l())
+	go wait.Until(c.collectTombstones, time.Minute, c.ctx.Done())
+	go wait.Until(c.releaseOrphans, time.Second, c.ctx.Done())
 	return nil
 }
 
+// joinCluster joins the cluster of leader, it retries with backoff until it joins, another leader is found or the
+// component stops
+func (c *GossipKVCacheComponent) joinCluster(leader string) {
+	ctx, cancel := c.join.begin(c.ctx)
+	defer cancel()
+
+	if leader == c.inClusterIP {
+		c.l().Debug("OnNewLeader it's me")
+		c.join.setJoined()
+		return
+	}
+	joined := c.join.retry(ctx, c.l(), func() error {
+		_, err := c.list.Join([]string{fmt.Sprintf("%s:%d", leader, c.Port)})
+		return err
+	})
+	if !joined {
+		return
+	}
+	if c.OnJoinCluster != nil {
+		c.OnJoinCluster()
+	}
+	c.l().Debug("OnNewLeader done")
+}
+
 // PreStop called before Stop()
 func (c *GossipKVCacheComponent) PreStop(ctx context.Context) error {
 	_ = ctx
@@ -245,13 +295,14 @@ func (c *GossipKVCacheComponent) PreStop(ctx context.Context) error {
 }
 
 type broadcast struct {
+	key    string // key of a versioned update, which is invalidated by a later update of the key
 	msg    []byte
 	notify chan<- struct{}
 }
 
 func (b *broadcast) Invalidates(other memberlist.Broadcast) bool {
-	_ = other
-	return false
+	o, ok := other.(*broadcast)
+	return ok && b.key != "" && b.key == o.key
 }
 
 func (b *broadcast) Message() []byte {
@@ -264,52 +315,72 @@ func (b *broadcast) Finished() {
 	}
 }
 
-const (
-	updateActionAdd = "add"
-	updateActionDel = "del"
-)
-
-type update struct {
-	Action string // add, del
-	Data   map[string]string
-}
+// msgVersionedUpdate is the type of broadcasts, members before versioning sent 'd' which is ignored
+const msgVersionedUpdate = 'v' // kvState
 
 const (
 	urlGroupGossip = "Gossip Cluster"
 	urlKVCache     = "/kv"
 	urlNode        = "/node"
 	urlGossip      = "/gossip"
+	urlGroupLeader = "Leader Election"
+	urlLeader      = "/leader"
 )
 
+// errNoLeaderElection means the component is not in swarm, so there is no leader election
+var errNoLeaderElection = errors.New("leader election is not running")
+
+// SetupAdminHandler of echo, cluster members are served on the admin port if it is set
+func (c *GossipKVCacheComponent) SetupAdminHandler(root echoswagger.ApiRoot, base string) error {
+	_ = base
+	g := root.Group(urlGroupGossip, urlNode)
+	g.GET("", c.getMembersHandler).
+		AddResponse(http.StatusOK, "successful operation", []*memberlist.Node{}, nil).
+		SetOperationId("getAll").
+		SetSummary("get all cluster members")
+
+	g = root.Group(urlGroupLeader, urlLeader)
+	g.GET("", c.getLeaderHandler).
+		AddResponse(http.StatusOK, "successful operation", leadelection.LeaderElectionStatus{}, nil).
+		SetOperationId("getLeader").
+		SetSummary("get the observed leader election record, and the fencing token if this node is leading")
+	micro.Secure(g.POST("/stepdown", c.stepDownHandler).
+		AddResponse(http.StatusOK, "successful operation", "", nil).
+		SetOperationId("stepDown").
+		SetSummary("release the lease if this node is leading, so another node takes over"),
+		micro.ScopeAdmin)
+	return nil
+}
+
 // SetupHandler of echo if the component need
 func (c *GossipKVCacheComponent) SetupHandler(root echoswagger.ApiRoot, base string) error {
 	_ = base
 	basicConf := microConf.GetBasicConfig()
 
 	g := root.Group(urlGroupGossip, urlKVCache)
-	g.POST("", c.addHandler).
+	micro.Secure(g.POST("", c.addHandler).
 		AddParamQuery("", "key", "key", true).
 		AddParamQuery("", "value", "value", false).
 		AddResponse(http.StatusOK, "successful operation", "", nil).
 		SetOperationId("add").
-		SetSummary("add a key-value pair, if value is not set, kv will use consider is a sensorid-cluster item and use its cluster name as value")
+		SetSummary("add a key-value pair, if value is not set, kv will use consider is a sensorid-cluster item and use its cluster name as value"),
+		micro.ScopeAdmin)
 	g.GET("", c.getHandler).
 		AddParamQuery("", "key", "key", false).
 		AddResponse(http.StatusOK, "successful operation", "", nil).
 		AddResponse(http.StatusNotFound, "key is set but does not exist", "", nil).
 		SetOperationId("get").
 		SetSummary("get value, only one item if key is set")
-	g.DELETE("", c.deleteHandler).
+	g.GET("/digest", c.getDigestHandler).
+		AddResponse(http.StatusOK, "successful operation", KVDigest{}, nil).
+		SetOperationId("digest").
+		SetSummary("get the digest of items, members hold the same items if their hashes are equal")
+	micro.Secure(g.DELETE("", c.deleteHandler).
 		AddParamQuery("", "key", "key", true).
 		AddResponse(http.StatusOK, "successful operation", "", nil).
 		SetOperationId("delete").
-		SetSummary("delte a key")
-
-	g = root.Group(urlGroupGossip, urlNode)
-	g.GET("", c.getMembersHandler).
-		AddResponse(http.StatusOK, "successful operation", []*memberlist.Node{}, nil).
-		SetOperationId("getAll").
-		SetSummary("get all cluster members")
+		SetSummary("delte a key"),
+		micro.ScopeAdmin)
 
 	g = root.Group(urlGroupGossip, urlGossip)
 	g.GET("/demo1", c.SensorIDHandlerWrapper(basicConf.Service, c.demoHandler, false)).
@@ -394,63 +465,48 @@ func (c *GossipKVCacheComponent) deleteHandler(ctx echo.Context) error {
 	return utils.GetJSONResponse(ctx, nil, "OK")
 }
 
+func (c *GossipKVCacheComponent) getDigestHandler(ctx echo.Context) error {
+	return utils.GetJSONResponse(ctx, nil, c.Digest())
+}
+
 func (c *GossipKVCacheComponent) getMembersHandler(ctx echo.Context) error {
 	members := c.GetMembers()
 	return utils.GetJSONResponse(ctx, nil, members)
 }
 
-// Add a key-value to store
-func (c *GossipKVCacheComponent) Add(key, val string) error {
-	c.opsLock.Lock()
-	c.items[key] = val
-	c.opsLock.Unlock()
-
-	b, err := json.Marshal([]*update{
-		{
-			Action: updateActionAdd,
-			Data: map[string]string{
-				key: val,
-			},
-		},
-	})
+func (c *GossipKVCacheComponent) getLeaderHandler(ctx echo.Context) error {
+	if c.leaderElector == nil {
+		return utils.GetJSONResponse(ctx, errNoLeaderElection, nil)
+	}
+	return utils.GetJSONResponse(ctx, nil, c.leaderElector.Status())
+}
 
-	if err != nil {
-		return err
+func (c *GossipKVCacheComponent) stepDownHandler(ctx echo.Context) error {
+	if c.leaderElector == nil {
+		return utils.GetJSONResponse(ctx, errNoLeaderElection, nil)
 	}
+	err := c.leaderElector.StepDown(ctx.Request().Context())
+	return utils.GetJSONResponse(ctx, err, nil)
+}
 
-	c.broadcasts.QueueBroadcast(&broadcast{
-		msg:    append([]byte("d"), b...),
-		notify: nil,
-	})
-	return nil
+// Add a key-value to store
+func (c *GossipKVCacheComponent) Add(key, val string) error {
+	return c.AddKeys([]string{key}, val)
 }
 
 // AddKeys add a lot of keys with same value to store
 func (c *GossipKVCacheComponent) AddKeys(keys []string, sameValue string) error {
 	c.opsLock.Lock()
+	entries := make([]*kvEntry, 0, len(keys))
 	for _, key := range keys {
-		c.items[key] = sameValue
+		entries = append(entries, c.items.set(key, sameValue))
 	}
 	c.opsLock.Unlock()
 
-	for _, key := range keys {
-		b, err := json.Marshal([]*update{
-			{
-				Action: updateActionAdd,
-				Data: map[string]string{
-					key: sameValue,
-				},
-			},
-		})
-
-		if err != nil {
+	for i, key := range keys {
+		if err := c.broadcastEntry(key, entries[i]); err != nil {
 			return err
 		}
-
-		c.broadcasts.QueueBroadcast(&broadcast{
-			msg:    append([]byte("d"), b...),
-			notify: nil,
-		})
 	}
 	return nil
 }
@@ -458,24 +514,20 @@ func (c *GossipKVCacheComponent) AddKeys(keys []string, sameValue string) error
 // Delete a key-value from store
 func (c *GossipKVCacheComponent) Delete(key string) error {
 	c.opsLock.Lock()
-	delete(c.items, key)
+	e := c.items.delete(key, time.Now())
 	c.opsLock.Unlock()
+	return c.broadcastEntry(key, e)
+}
 
-	b, err := json.Marshal([]*update{
-		{
-			Action: updateActionDel,
-			Data: map[string]string{
-				key: "",
-			},
-		},
-	})
-
+// broadcastEntry queues a versioned update of key, it replaces a queued update of the same key
+func (c *GossipKVCacheComponent) broadcastEntry(key string, e *kvEntry) error {
+	b, err := json.Marshal(&kvState{Items: map[string]*kvEntry{key: e}})
 	if err != nil {
 		return err
 	}
-
 	c.broadcasts.QueueBroadcast(&broadcast{
-		msg:    append([]byte("d"), b...),
+		key:    key,
+		msg:    append([]byte{msgVersionedUpdate}, b...),
 		notify: nil,
 	})
 	return nil
@@ -485,19 +537,31 @@ func (c *GossipKVCacheComponent) Delete(key string) error {
 func (c *GossipKVCacheComponent) Get(key string) (string, bool) {
 	c.opsLock.Lock()
 	defer c.opsLock.Unlock()
-	val, ok := c.items[key]
-	return val, ok
+	return c.items.get(key)
 }
 
 // GetAll key-value pairs from store
 func (c *GossipKVCacheComponent) GetAll() (map[string]string, error) {
 	c.opsLock.Lock()
 	defer c.opsLock.Unlock()
-	result := map[string]string{}
-	for k, v := range c.items {
-		result[k] = v
+	return c.items.all(), nil
+}
+
+// Digest returns the digest of items to compare with other members
+func (c *GossipKVCacheComponent) Digest() *KVDigest {
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+	return c.items.digest()
+}
+
+// collectTombstones removes tombstones older than TombstoneTTL
+func (c *GossipKVCacheComponent) collectTombstones() {
+	c.opsLock.Lock()
+	removed := c.items.collect(time.Now(), c.TombstoneTTL)
+	c.opsLock.Unlock()
+	if removed > 0 {
+		c.l().Debugf("collected %d tombstones", removed)
 	}
-	return result, nil
 }
 
 // GetMembers return all members in cluster
@@ -531,22 +595,13 @@ func (c *GossipKVCacheComponent) NotifyMsg(b []byte) {
 	}
 
 	switch b[0] {
-	case 'd': // data
-		var updates []*update
-		if err := json.Unmarshal(b[1:], &updates); err != nil {
+	case msgVersionedUpdate:
+		var state kvState
+		if err := json.Unmarshal(b[1:], &state); err != nil {
 			return
 		}
 		c.opsLock.Lock()
-		for _, u := range updates {
-			for k, v := range u.Data {
-				switch u.Action {
-				case updateActionAdd:
-					c.items[k] = v
-				case updateActionDel:
-					delete(c.items, k)
-				}
-			}
-		}
+		c.items.mergeState(&state)
 		c.opsLock.Unlock()
 	}
 }
@@ -568,7 +623,7 @@ func (c *GossipKVCacheComponent) GetBroadcasts(overhead, limit int) [][]byte {
 func (c *GossipKVCacheComponent) LocalState(join bool) []byte {
 	_ = join
 	c.opsLock.Lock()
-	b, _ := json.Marshal(c.items)
+	b, _ := json.Marshal(c.items.state())
 	c.opsLock.Unlock()
 	return b
 }
@@ -578,24 +633,23 @@ func (c *GossipKVCacheComponent) LocalState(join bool) []byte {
 // remote side's LocalState call. The 'join'
 // boolean indicates this is for a join instead of a push/pull.
 func (c *GossipKVCacheComponent) MergeRemoteState(buf []byte, join bool) {
+	_ = join
 	if len(buf) == 0 {
 		return
 	}
 
-	if !join {
-		return
-	}
-
-	var m map[string]string
-	if err := json.Unmarshal(buf, &m); err != nil {
+	// every push/pull is merged, so members converge after partitions
+	var state kvState
+	if err := json.Unmarshal(buf, &state); err != nil {
 		c.l().Error(err)
 		return
 	}
 	c.opsLock.Lock()
-	for k, v := range m {
-		c.items[k] = v
-	}
+	merged := c.items.mergeState(&state)
 	c.opsLock.Unlock()
+	if merged > 0 {
+		c.l().Debugf("merged %d items of remote state", merged)
+	}
 }
 
 // memeberlist.EventDelegate interface
@@ -621,6 +675,7 @@ func (c *GossipKVCacheComponent) addNodeMeta(node *memberlist.Node) {
 	}
 	c.opsLock.Lock()
 	c.metas[meta.Name] = meta
+	delete(c.leftNodes, meta.Name)
 	c.opsLock.Unlock()
 	if c.OnNodeJoin != nil {
 		c.OnNodeJoin(meta)
@@ -633,6 +688,9 @@ func (c *GossipKVCacheComponent) removeNodeMeta(node *memberlist.Node) {
 	}
 	c.opsLock.Lock()
 	delete(c.metas, meta.Name)
+	if meta.Name != c.meta.Name {
+		c.leftNodes[meta.Name] = &leftNode{meta: meta, at: time.Now()}
+	}
 	c.opsLock.Unlock()
 	if c.OnNodeLeave != nil {
 		c.OnNodeLeave(meta)
@@ -958,7 +1016,12 @@ func (c *GossipKVCacheComponent) wrapperWebsocket(selfServiceName string, ctx ec
 			return true
 		},
 	}
-	cancelCtx, cancelFunc := context.WithCancel(context.Background())
+	drainCtx, release := context.Background(), func() {}
+	if c.trackConnection != nil {
+		drainCtx, release = c.trackConnection()
+	}
+	defer release()
+	cancelCtx, cancelFunc := context.WithCancel(drainCtx)
 	defer cancelFunc()
 	wg := sync.WaitGroup{}
 	mainConn, err := upgrader.Upgrade(ctx.Response().Writer, ctx.Request(), nil)
@@ -971,6 +1034,21 @@ func (c *GossipKVCacheComponent) wrapperWebsocket(selfServiceName string, ctx ec
 			c.l().Error(err)
 		}
 	}()
+	// say goodbye to the client when server is draining, the blocked read loop returns after that
+	go func() {
+		<-cancelCtx.Done()
+		if drainCtx.Err() == nil {
+			return
+		}
+		deadline := time.Now().Add(time.Second)
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+		if err := mainConn.WriteControl(websocket.CloseMessage, msg, deadline); err != nil {
+			c.l().Debug(err)
+		}
+		if err := mainConn.UnderlyingConn().SetReadDeadline(deadline); err != nil {
+			c.l().Debug(err)
+		}
+	}()
 	conns := map[string]chan wsmsg{} // ip-conn
 
 	// c.l().Debugf("websocket %s", mainConn.RemoteAddr().String())
@@ -0,0 +1,172 @@
+package component
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// kvEntry is a versioned item of the gossip kv store. Writes are ordered by the lamport clock, ties are broken by the
+// writer node, so every member keeps the same entry whatever order updates arrive in.
+// A deleted item is kept as a tombstone until it is collected, so an earlier write can not resurrect it.
+type kvEntry struct {
+	Value     string `json:"v,omitempty"`
+	Clock     uint64 `json:"c"`           // lamport clock of the write
+	Node      string `json:"n,omitempty"` // name of the writer node
+	Deleted   bool   `json:"d,omitempty"`
+	DeletedAt int64  `json:"t,omitempty"` // unix ms the tombstone was created, for collecting it
+}
+
+// newer returns true if e is written after o
+func (e *kvEntry) newer(o *kvEntry) bool {
+	if e.Clock != o.Clock {
+		return e.Clock > o.Clock
+	}
+	if e.Node != o.Node {
+		return e.Node > o.Node
+	}
+	// the same write
+	return false
+}
+
+// kvState is the state of LocalState and MergeRemoteState, and the data of versioned broadcasts
+type kvState struct {
+	Items map[string]*kvEntry `json:"items"`
+}
+
+// KVDigest is a summary of the gossip kv store, members hold the same items if their hashes are equal
+type KVDigest struct {
+	Node       string `json:"node"`
+	Clock      uint64 `json:"clock"`
+	Count      int    `json:"count"`
+	Tombstones int    `json:"tombstones"`
+	Hash       string `json:"hash"` // sha256 of items which are not deleted
+}
+
+// kvStore is the versioned items of the gossip kv store, it is not safe for concurrent use
+type kvStore struct {
+	node  string
+	clock uint64
+	items map[string]*kvEntry
+}
+
+func newKVStore(node string) *kvStore {
+	return &kvStore{
+		node:  node,
+		items: map[string]*kvEntry{},
+	}
+}
+
+// set writes key locally and returns the entry to broadcast
+func (s *kvStore) set(key, value string) *kvEntry {
+	s.clock++
+	e := &kvEntry{Value: value, Clock: s.clock, Node: s.node}
+	s.items[key] = e
+	return e
+}
+
+// delete writes a tombstone of key locally and returns the entry to broadcast
+func (s *kvStore) delete(key string, now time.Time) *kvEntry {
+	s.clock++
+	e := &kvEntry{Clock: s.clock, Node: s.node, Deleted: true, DeletedAt: now.UnixMilli()}
+	s.items[key] = e
+	return e
+}
+
+func (s *kvStore) get(key string) (string, bool) {
+	e, ok := s.items[key]
+	if !ok || e.Deleted {
+		return "", false
+	}
+	return e.Value, true
+}
+
+func (s *kvStore) all() map[string]string {
+	result := map[string]string{}
+	for k, e := range s.items {
+		if !e.Deleted {
+			result[k] = e.Value
+		}
+	}
+	return result
+}
+
+// ownedBy returns sorted keys of items written by node with value
+func (s *kvStore) ownedBy(node, value string) []string {
+	var keys []string
+	for k, e := range s.items {
+		if !e.Deleted && e.Node == node && e.Value == value {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writtenBy returns true if key is not deleted and its last write is of node
+func (s *kvStore) writtenBy(key, node string) bool {
+	e, ok := s.items[key]
+	return ok && !e.Deleted && e.Node == node
+}
+
+// merge applies a remote entry if it is newer than the local one, returns true if it is applied
+func (s *kvStore) merge(key string, e *kvEntry) bool {
+	s.clock = max(s.clock, e.Clock)
+	if local, ok := s.items[key]; ok && !e.newer(local) {
+		return false
+	}
+	s.items[key] = e
+	return true
+}
+
+// mergeState applies remote entries, returns the count of applied ones
+func (s *kvStore) mergeState(state *kvState) int {
+	merged := 0
+	for k, e := range state.Items {
+		if e != nil && s.merge(k, e) {
+			merged++
+		}
+	}
+	return merged
+}
+
+func (s *kvStore) state() *kvState {
+	return &kvState{Items: s.items}
+}
+
+// collect removes tombstones older than ttl, returns the count of removed ones
+func (s *kvStore) collect(now time.Time, ttl time.Duration) int {
+	removed := 0
+	deadline := now.Add(-ttl).UnixMilli()
+	for k, e := range s.items {
+		if e.Deleted && e.DeletedAt < deadline {
+			delete(s.items, k)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *kvStore) digest() *KVDigest {
+	d := &KVDigest{Node: s.node, Clock: s.clock}
+	keys := make([]string, 0, len(s.items))
+	for k, e := range s.items {
+		if e.Deleted {
+			d.Tombstones++
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d.Count = len(keys)
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, k := range keys {
+		e := s.items[k]
+		_ = enc.Encode([]interface{}{k, e.Value, e.Clock, e.Node})
+	}
+	d.Hash = hex.EncodeToString(h.Sum(nil))
+	return d
+}
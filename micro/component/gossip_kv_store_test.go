@@ -0,0 +1,81 @@
+package component
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exchange runs a push/pull between stores through json like memberlist
+func exchange(t *testing.T, a, b *kvStore) {
+	for _, pair := range [][2]*kvStore{{a, b}, {b, a}} {
+		buf, err := json.Marshal(pair[0].state())
+		assert.Nil(t, err)
+		var state kvState
+		assert.Nil(t, json.Unmarshal(buf, &state))
+		pair[1].mergeState(&state)
+	}
+}
+
+func TestKVStoreConflict(t *testing.T) {
+	a, b := newKVStore("a"), newKVStore("b")
+	ea := a.set("s1", "c1")
+	eb := b.set("s1", "c2")
+	// the same clock, the greater node wins on both sides whatever order
+	assert.True(t, a.merge("s1", eb))
+	assert.False(t, b.merge("s1", ea))
+	va, _ := a.get("s1")
+	vb, _ := b.get("s1")
+	assert.Equal(t, "c2", va)
+	assert.Equal(t, va, vb)
+
+	// a write after seeing the remote one wins
+	e := a.set("s1", "c3")
+	assert.Greater(t, e.Clock, eb.Clock)
+	assert.True(t, b.merge("s1", e))
+	assert.Equal(t, a.digest().Hash, b.digest().Hash)
+}
+
+func TestKVStoreTombstone(t *testing.T) {
+	now := time.Now()
+	a, b := newKVStore("a"), newKVStore("b")
+	stale := a.set("s1", "c1")
+	exchange(t, a, b)
+	del := b.delete("s1", now)
+
+	// the delete arrives, then a late copy of the write does not resurrect the item
+	assert.True(t, a.merge("s1", del))
+	assert.False(t, a.merge("s1", stale))
+	_, ok := a.get("s1")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{}, a.all())
+	d := a.digest()
+	assert.Equal(t, 0, d.Count)
+	assert.Equal(t, 1, d.Tombstones)
+
+	assert.Equal(t, 0, a.collect(now.Add(time.Minute), time.Hour))
+	assert.Equal(t, 1, a.collect(now.Add(2*time.Hour), time.Hour))
+	assert.Equal(t, 0, a.digest().Tombstones)
+}
+
+func TestKVStorePartition(t *testing.T) {
+	a, b := newKVStore("a"), newKVStore("b")
+	a.set("s1", "c1")
+	a.set("s2", "c1")
+	exchange(t, a, b)
+	assert.Equal(t, a.digest().Hash, b.digest().Hash)
+
+	// writes on both sides of a partition
+	a.set("s1", "c2")
+	a.delete("s2", time.Now())
+	b.set("s2", "c3")
+	b.set("s3", "c3")
+	b.set("s3", "c4")
+	assert.NotEqual(t, a.digest().Hash, b.digest().Hash)
+
+	exchange(t, a, b)
+	assert.Equal(t, a.digest(), &KVDigest{Node: "a", Clock: a.clock, Count: 2, Tombstones: 1, Hash: b.digest().Hash})
+	assert.Equal(t, map[string]string{"s1": "c2", "s3": "c4"}, b.all())
+}
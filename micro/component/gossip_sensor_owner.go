@@ -0,0 +1,136 @@
+package component
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+const defaultOrphanGracePeriod = 30 * time.Second
+
+// leftNode is a member which has left, its sensors are orphaned after the grace period
+type leftNode struct {
+	meta *GossipKVCacheNodeMeta
+	at   time.Time
+}
+
+// orphanScore is the weighted rendezvous score of a node for a sensor, a sensor is assigned to the node of the
+// highest score, so nodes get sensors in proportion to their work load and every member computes the same assignment
+func orphanScore(sensorID string, meta *GossipKVCacheNodeMeta) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(sensorID))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(meta.Name))
+	// fnv differs little in high bits for names differing in the last byte, mix it like splitmix64
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	u := (float64(x>>11) + 0.5) / (1 << 53) // uniform in (0, 1)
+	return float64(max(meta.WorkLoad, 1)) / -math.Log(u)
+}
+
+// assignOrphans assigns sensors to candidates by work load, returns sensors of each candidate name
+func assignOrphans(sensorIDs []string, candidates []*GossipKVCacheNodeMeta) map[string][]string {
+	assignment := map[string][]string{}
+	if len(candidates) == 0 {
+		return assignment
+	}
+	for _, id := range sensorIDs {
+		var best *GossipKVCacheNodeMeta
+		bestScore := -1.0
+		for _, meta := range candidates {
+			if score := orphanScore(id, meta); score > bestScore {
+				best, bestScore = meta, score
+			}
+		}
+		assignment[best.Name] = append(assignment[best.Name], id)
+	}
+	return assignment
+}
+
+// orphanCandidates returns live members of the service of the left node, or all live members if there is none.
+// It must be called with opsLock held.
+func (c *GossipKVCacheComponent) orphanCandidates(left *GossipKVCacheNodeMeta) []*GossipKVCacheNodeMeta {
+	var same, all []*GossipKVCacheNodeMeta
+	for _, meta := range c.metas {
+		all = append(all, meta)
+		if meta.ServiceName == left.ServiceName {
+			same = append(same, meta)
+		}
+	}
+	if len(same) > 0 {
+		return same
+	}
+	return all
+}
+
+// releaseOrphans hands over sensors of members left longer than OrphanGracePeriod
+func (c *GossipKVCacheComponent) releaseOrphans() {
+	c.releaseOrphansAt(time.Now())
+}
+
+// releaseOrphansAt offers sensors assigned to this member to OnSensorOrphaned, the claimed ones are owned by this
+// member and the others are deleted. A left member is forgotten once all its sensors are handed over, so if the
+// assigned member leaves too, the sensors are assigned again.
+func (c *GossipKVCacheComponent) releaseOrphansAt(now time.Time) {
+	type orphans struct {
+		owner   string
+		sensors []string
+	}
+	var assigned []orphans
+	c.opsLock.Lock()
+	for name, left := range c.leftNodes {
+		if now.Sub(left.at) < c.OrphanGracePeriod {
+			continue
+		}
+		sensors := c.items.ownedBy(name, left.meta.PrivateCluster)
+		if len(sensors) == 0 {
+			delete(c.leftNodes, name)
+			continue
+		}
+		if mine := assignOrphans(sensors, c.orphanCandidates(left.meta))[c.meta.Name]; len(mine) > 0 {
+			assigned = append(assigned, orphans{owner: name, sensors: mine})
+		}
+	}
+	c.opsLock.Unlock()
+
+	for _, o := range assigned {
+		var claimed []string
+		if c.OnSensorOrphaned != nil {
+			claimed = c.OnSensorOrphaned(o.sensors)
+		}
+		if err := c.takeOverSensors(o.owner, o.sensors, claimed, now); err != nil {
+			c.l().Error(err)
+		}
+		c.l().Infof("sensors of %s orphaned: %d, claimed: %d", o.owner, len(o.sensors), len(claimed))
+	}
+}
+
+// takeOverSensors owns claimed sensors and deletes the other ones, a sensor is skipped if it is no longer of owner
+func (c *GossipKVCacheComponent) takeOverSensors(owner string, sensorIDs, claimed []string, now time.Time) error {
+	claimedSet := map[string]bool{}
+	for _, id := range claimed {
+		claimedSet[id] = true
+	}
+	c.opsLock.Lock()
+	entries := map[string]*kvEntry{}
+	for _, id := range sensorIDs {
+		if !c.items.writtenBy(id, owner) {
+			continue
+		}
+		if claimedSet[id] {
+			entries[id] = c.items.set(id, c.meta.PrivateCluster)
+		} else {
+			entries[id] = c.items.delete(id, now)
+		}
+	}
+	c.opsLock.Unlock()
+
+	for id, e := range entries {
+		if err := c.broadcastEntry(id, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
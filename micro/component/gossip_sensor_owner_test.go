@@ -0,0 +1,101 @@
+package component
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+func newTestNode(t *testing.T, meta *GossipKVCacheNodeMeta) *memberlist.Node {
+	b, err := json.Marshal(meta)
+	assert.Nil(t, err)
+	return &memberlist.Node{Name: meta.Name, Meta: b}
+}
+
+func TestAssignOrphans(t *testing.T) {
+	var ids []string
+	for i := 0; i < 4000; i++ {
+		ids = append(ids, fmt.Sprintf("sensor%d", i))
+	}
+	candidates := []*GossipKVCacheNodeMeta{{Name: "a", WorkLoad: 1}, {Name: "b", WorkLoad: 3}}
+	assignment := assignOrphans(ids, candidates)
+	assert.Equal(t, len(ids), len(assignment["a"])+len(assignment["b"]))
+	assert.InDelta(t, 1000, len(assignment["a"]), 150)
+	// the same on every member
+	assert.Equal(t, assignment, assignOrphans(ids, []*GossipKVCacheNodeMeta{candidates[1], candidates[0]}))
+	assert.Empty(t, assignOrphans(ids, nil))
+}
+
+func TestReleaseOrphans(t *testing.T) {
+	self := &GossipKVCacheNodeMeta{Name: "svc.c1", ServiceName: "svc", PrivateCluster: "c1", WorkLoad: 1}
+	peer := &GossipKVCacheNodeMeta{Name: "svc.c3", ServiceName: "svc", PrivateCluster: "c3", WorkLoad: 1}
+	dead := &GossipKVCacheNodeMeta{Name: "svc.c2", ServiceName: "svc", PrivateCluster: "c2", WorkLoad: 1}
+	var offered []string
+	c := &GossipKVCacheComponent{
+		l: func() logging.ILogger { return zap.NewNop().Sugar() },
+		broadcasts: &memberlist.TransmitLimitedQueue{
+			NumNodes:       func() int { return 3 },
+			RetransmitMult: 3,
+		},
+		OrphanGracePeriod: time.Minute,
+		OnSensorOrphaned: func(sensorIDs []string) []string {
+			offered = append(offered, sensorIDs...)
+			return sensorIDs[:len(sensorIDs)/2]
+		},
+		meta:      self,
+		metas:     map[string]*GossipKVCacheNodeMeta{self.Name: self, peer.Name: peer, dead.Name: dead},
+		leftNodes: map[string]*leftNode{},
+		items:     newKVStore(self.Name),
+	}
+	owner := newKVStore(dead.Name)
+	var sensors []string
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("sensor%d", i)
+		sensors = append(sensors, id)
+		c.items.merge(id, owner.set(id, dead.PrivateCluster))
+	}
+	c.items.merge("other", owner.set("other", "not an owner"))
+
+	// a node back within the grace period keeps its sensors
+	c.NotifyLeave(newTestNode(t, dead))
+	c.NotifyJoin(newTestNode(t, dead))
+	c.releaseOrphansAt(time.Now().Add(2 * time.Minute))
+	assert.Empty(t, offered)
+
+	c.NotifyLeave(newTestNode(t, dead))
+	c.releaseOrphansAt(time.Now())
+	assert.Empty(t, offered)
+	c.releaseOrphansAt(time.Now().Add(2 * time.Minute))
+	mine := assignOrphans(sensors, []*GossipKVCacheNodeMeta{self, peer})[self.Name]
+	assert.NotEmpty(t, mine)
+	assert.ElementsMatch(t, mine, offered)
+	for i, id := range offered {
+		v, ok := c.Get(id)
+		if i < len(offered)/2 {
+			assert.Equal(t, "c1", v)
+		} else {
+			assert.False(t, ok)
+		}
+	}
+	// sensors of the peer are left to it
+	assert.Len(t, c.items.ownedBy(dead.Name, dead.PrivateCluster), len(sensors)-len(mine))
+	v, _ := c.Get("other")
+	assert.Equal(t, "not an owner", v)
+	assert.Greater(t, c.broadcasts.NumQueued(), 0)
+
+	// the peer leaves before handing over, the rest is assigned again
+	offered = nil
+	c.NotifyLeave(newTestNode(t, peer))
+	c.releaseOrphansAt(time.Now().Add(2 * time.Minute))
+	assert.Len(t, offered, len(sensors)-len(mine))
+	assert.Empty(t, c.items.ownedBy(dead.Name, dead.PrivateCluster))
+	c.releaseOrphansAt(time.Now().Add(2 * time.Minute))
+	assert.NotContains(t, c.leftNodes, dead.Name)
+}
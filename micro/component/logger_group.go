@@ -21,8 +21,9 @@ import (
 // LoggerGroupComponent is Component for logging
 type LoggerGroupComponent struct {
 	micro.EmptyComponent
-	group  *logging.LoggerGroup
-	enable bool
+	group   *logging.LoggerGroup
+	logConf conf.LogConfig // config read in Init
+	enable  bool
 }
 
 // Name of the component
@@ -30,6 +31,11 @@ func (c *LoggerGroupComponent) Name() string {
 	return "LoggerGroup"
 }
 
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *LoggerGroupComponent) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&micro.LoggerGroupElementKey}
+}
+
 // PreInit called before Init()
 func (c *LoggerGroupComponent) PreInit(ctx context.Context) error {
 	_ = ctx
@@ -55,11 +61,29 @@ func (c *LoggerGroupComponent) Init(server *micro.Server) error {
 	if err != nil {
 		return err
 	}
+	c.logConf = logConf.LogConfig
 
 	if !c.enable {
 		logConf.GraylogAddr = ""
 		logConf.LokiAddr = ""
 	}
+	setLevelsFromEnv(logConf)
+
+	// spew.Dump(logConf)
+	c.group, err = logging.CreateLoggerGroup(basicConf, logConf)
+	if err != nil {
+		return err
+	}
+	server.RegisterElement(&micro.LoggerGroupElementKey, c.group)
+
+	if basicConf.IsDevMode {
+		spew.Dump(logConf)
+	}
+	return nil
+}
+
+// setLevelsFromEnv overrides module levels by environment variables, e.g. LOG_LEVELS_DEBUG=micro,http
+func setLevelsFromEnv(logConf *conf.LogGroupConfig) {
 	str := viper.GetString("LOG_LEVELS_DEBUG")
 	if str != "" {
 		logConf.Levels[logging.LevelDebug] = strings.Split(str, ",")
@@ -76,17 +100,33 @@ func (c *LoggerGroupComponent) Init(server *micro.Server) error {
 	if str != "" {
 		logConf.Levels[logging.LevelError] = strings.Split(str, ",")
 	}
+}
 
-	// spew.Dump(logConf)
-	c.group, err = logging.CreateLoggerGroup(basicConf, logConf)
+// ConfigSections returns top level keys of the config file read by the component
+func (c *LoggerGroupComponent) ConfigSections() []string {
+	return []string{"log"}
+}
+
+// ValidateConfigReload called before the reloaded config is applied, only levels take effect without restart
+func (c *LoggerGroupComponent) ValidateConfigReload(old, new *microConf.BasicConfig) error {
+	_, _ = old, new
+	logConf, err := conf.GetLogGroupConfig()
 	if err != nil {
 		return err
 	}
-	server.RegisterElement(&micro.LoggerGroupElementKey, c.group)
+	return checkReloadedLogConfig(&c.logConf, &logConf.LogConfig)
+}
 
-	if basicConf.IsDevMode {
-		spew.Dump(logConf)
+// OnConfigReloaded called when the config file changed, levels set through the api are replaced by the file
+func (c *LoggerGroupComponent) OnConfigReloaded(old, new *microConf.BasicConfig) error {
+	_, _ = old, new
+	logConf, err := conf.GetLogGroupConfig()
+	if err != nil {
+		return err
 	}
+	c.logConf.Level = logConf.Level
+	setLevelsFromEnv(logConf)
+	c.group.SetLevels(logConf.Level, logConf.Levels)
 	return nil
 }
 
@@ -95,16 +135,17 @@ const (
 	urlLogLevel = "/log/level"
 )
 
-// SetupHandler of echo if the component need
-func (c *LoggerGroupComponent) SetupHandler(root echoswagger.ApiRoot, base string) error {
+// SetupAdminHandler of echo, log levels are served on the admin port if it is set
+func (c *LoggerGroupComponent) SetupAdminHandler(root echoswagger.ApiRoot, base string) error {
 	_ = base
 	g := root.Group(urlGroupLog, urlLogLevel)
-	g.POST("", c.setHandler).
+	micro.Secure(g.POST("", c.setHandler).
 		AddParamQuery("", "module", "module", true).
 		AddParamQuery("", "level", "level", true).
 		AddResponse(http.StatusOK, "successful operation", "", nil).
 		SetOperationId("add").
-		SetSummary("set log level for given module")
+		SetSummary("set log level for given module"),
+		micro.ScopeAdmin)
 	g.GET("", c.getHandler).
 		AddResponse(http.StatusOK, "successful operation", "", nil).
 		SetOperationId("get").
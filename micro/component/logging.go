@@ -2,10 +2,12 @@ package component
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	platformConf "github.com/kiga-hub/arc/conf"
 	"github.com/kiga-hub/arc/logging"
@@ -17,8 +19,10 @@ import (
 // LoggingComponent is Component for logging
 type LoggingComponent struct {
 	micro.EmptyComponent
-	zlog   *zap.Logger
-	enable bool
+	zlog    *zap.Logger
+	level   zap.AtomicLevel
+	logConf conf.LogConfig // config read in Init
+	enable  bool
 }
 
 // Name of the component
@@ -26,6 +30,11 @@ func (c *LoggingComponent) Name() string {
 	return "Logger"
 }
 
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *LoggingComponent) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&micro.LoggingElementKey}
+}
+
 // PreInit called before Init()
 func (c *LoggingComponent) PreInit(ctx context.Context) error {
 	_ = ctx
@@ -49,13 +58,19 @@ func (c *LoggingComponent) Init(server *micro.Server) error {
 	basicConf := microConf.GetBasicConfig()
 	// spew.Dump(basicConf)
 	logConf := conf.GetLogConfig()
+	c.logConf = *logConf
 	if !c.enable {
 		logConf.GraylogAddr = ""
 		logConf.LokiAddr = ""
 	}
 
 	// spew.Dump(logConf)
-	c.zlog, err = logging.CreateLogger(basicConf, logConf)
+	c.level = zap.NewAtomicLevel()
+	err = c.level.UnmarshalText([]byte(logConf.Level))
+	if err != nil {
+		return fmt.Errorf("fatal to parse logger level: %s", err)
+	}
+	c.zlog, err = logging.CreateLoggerWithLevel(basicConf, logConf, c.level)
 	if err != nil {
 		return err
 	}
@@ -69,6 +84,39 @@ func (c *LoggingComponent) Init(server *micro.Server) error {
 	return nil
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *LoggingComponent) ConfigSections() []string {
+	return []string{"log"}
+}
+
+// ValidateConfigReload called before the reloaded config is applied, only the log level takes effect without restart
+func (c *LoggingComponent) ValidateConfigReload(old, new *microConf.BasicConfig) error {
+	_, _ = old, new
+	return checkReloadedLogConfig(&c.logConf, conf.GetLogConfig())
+}
+
+// OnConfigReloaded called when the config file changed, only the log level takes effect without restart
+func (c *LoggingComponent) OnConfigReloaded(old, new *microConf.BasicConfig) error {
+	_, _ = old, new
+	logConf := conf.GetLogConfig()
+	c.logConf.Level = logConf.Level
+	return c.level.UnmarshalText([]byte(logConf.Level))
+}
+
+// checkReloadedLogConfig returns an error if a field other than the level changed or the level is invalid
+func checkReloadedLogConfig(old, new *conf.LogConfig) error {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(new.Level)); err != nil {
+		return fmt.Errorf("invalid log level %s: %s", new.Level, err)
+	}
+	changed := *new
+	changed.Level = old.Level
+	if changed != *old {
+		return fmt.Errorf("only log.level and log.levels take effect without restart")
+	}
+	return nil
+}
+
 // PostStop called after Stop()
 func (c *LoggingComponent) PostStop(ctx context.Context) error {
 	_ = ctx
@@ -53,6 +53,7 @@ type RaftClusterComponent struct {
 	ClusterID     uint64
 	leaderElector *leadelection.LeaderElector
 	opsLock       sync.Mutex
+	join          *clusterJoin
 }
 
 // Name of the component
@@ -60,6 +61,25 @@ func (c *RaftClusterComponent) Name() string {
 	return "LeaderRaftClusterComponent"
 }
 
+// DependsOnElements returns keys of elements which must be registered before this one is initialized
+func (c *RaftClusterComponent) DependsOnElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&micro.LoggingElementKey, &micro.NacosClientElementKey}
+}
+
+// Status of the component, it is not ok if it does not join the cluster in time
+func (c *RaftClusterComponent) Status() *micro.ComponentStatus {
+	status := c.EmptyComponent.Status()
+	if c.join != nil {
+		c.join.setStatus(status)
+	}
+	return status
+}
+
+// HealthChecks returns checks of the component, called after Init()
+func (c *RaftClusterComponent) HealthChecks() []micro.HealthCheck {
+	return []micro.HealthCheck{c.join.healthCheck()}
+}
+
 // PreInit called before Init()
 func (c *RaftClusterComponent) PreInit(ctx context.Context) error {
 	_ = ctx
@@ -86,6 +106,7 @@ func (c *RaftClusterComponent) Init(server *micro.Server) error {
 	}
 	c.raftNode = map[uint64]*dragonboat.NodeHost{}
 	c.opsLock = sync.Mutex{}
+	c.join = newClusterJoin(time.Duration(microConf.GetBasicConfig().JoinTimeout) * time.Millisecond)
 	return os.MkdirAll("/raft", os.ModePerm)
 }
 
@@ -98,25 +119,26 @@ func (c *RaftClusterComponent) PostStart(ctx context.Context) error {
 	c.leaderElector, err = leadelection.NewLeaderElector(leadelection.LeaderElectionConfig{
 		Lock: leadelection.NewNacosLock("DEFAULT_GROUP", fmt.Sprintf("lead-%d", c.ClusterID), c.ip.String(), c.nacosClient),
 		Callbacks: leadelection.LeaderCallbacks{
-			OnStartedLeading: func(context.Context) {
+			OnStartedLeading: func(ctx context.Context) {
 				fmt.Println("OnStartedLeading")
-				for len(c.raftNode) == 0 {
-					time.Sleep(time.Second)
-					c.startRaftCluster()
-				}
+				ctx, cancel := c.join.begin(ctx)
+				defer cancel()
+				c.join.retry(ctx, c.logger, c.startRaftCluster)
 			},
 			OnStoppedLeading: func() {
 				fmt.Println("OnStoppedLeading")
 			},
 			OnNewLeader: func(identity string) {
-				if identity == c.ip.String() {
+				if identity == "" || identity == c.ip.String() {
 					return
 				}
 				fmt.Println("OnNewLeader " + identity)
-				time.Sleep(time.Second)
-				for len(c.raftNode) == 0 {
-					c.joinRaftCluster(identity)
-				}
+				// joining the previous leader is cancelled
+				ctx, cancel := c.join.begin(c.ctx)
+				defer cancel()
+				c.join.retry(ctx, c.logger, func() error {
+					return c.joinRaftCluster(identity)
+				})
 			},
 		},
 		LeaseDuration:   time.Second * 5,
@@ -130,44 +152,35 @@ func (c *RaftClusterComponent) PostStart(ctx context.Context) error {
 	}
 	go c.leaderElector.Run(c.ctx)
 
-	// wait until join cluster or timeout
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
-		stop := time.NewTimer(time.Second * 30)
-		defer stop.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				if len(c.raftNode) > 0 {
-					return
-				}
-			case <-stop.C:
-				fmt.Println("超时")
-				panic("cannot build cluster")
-			}
-		}
-	}()
+	// report degraded if not joined in time
+	go c.join.watch(c.ctx, c.logger)
 	return nil
 }
 
-func (c *RaftClusterComponent) startRaftCluster() {
+// startRaftCluster starts raft nodes, it fails if none of them is started
+func (c *RaftClusterComponent) startRaftCluster() error {
 	c.opsLock.Lock()
 	defer c.opsLock.Unlock()
+	var err error
 	for _, nodeID := range c.raftNodeID {
 		time.Sleep(time.Second)
-		c.startOneRaftCluster(nodeID)
+		if e := c.startOneRaftCluster(nodeID); e != nil {
+			err = e
+		}
+	}
+	if len(c.raftNode) == 0 {
+		return err
 	}
+	return nil
 }
 
-func (c *RaftClusterComponent) startOneRaftCluster(nodeID uint64) {
+func (c *RaftClusterComponent) startOneRaftCluster(nodeID uint64) error {
 	_, ok := c.raftNode[nodeID]
 	if ok {
-		return
+		return nil
 	}
 
 	fmt.Printf("start raft node %d\n", nodeID)
-	var err error
 	rc := config.Config{
 		// ClusterID and NodeID of the raft node
 		NodeID:             nodeID,
@@ -186,15 +199,18 @@ func (c *RaftClusterComponent) startOneRaftCluster(nodeID uint64) {
 		RTTMillisecond: 200,
 		RaftAddress:    c.raftAddress[nodeID],
 	}
-	c.raftNode[nodeID], err = dragonboat.NewNodeHost(nhc)
+	nh, err := dragonboat.NewNodeHost(nhc)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("new node host %d: %w", nodeID, err)
 	}
-	err = c.raftNode[nodeID].StartCluster(c.raftAddress, false, c.CreateFun, rc)
+	err = nh.StartCluster(c.raftAddress, false, c.CreateFun, rc)
 	if err != nil {
 		c.logger.Error(err)
-		delete(c.raftNode, nodeID)
+		nh.Stop()
+		return err
 	}
+	c.raftNode[nodeID] = nh
+	return nil
 }
 
 /*
@@ -252,24 +268,31 @@ func (c *RaftClusterComponent) submitRequest(nodeID uint64, leadIP string, add b
 	return nil
 }
 
-func (c *RaftClusterComponent) joinRaftCluster(leadIP string) {
+// joinRaftCluster joins raft nodes to the cluster of leader, it fails if none of them joins
+func (c *RaftClusterComponent) joinRaftCluster(leadIP string) error {
 	c.opsLock.Lock()
 	defer c.opsLock.Unlock()
+	var err error
 	for _, nodeID := range c.raftNodeID {
 		time.Sleep(time.Second)
-		c.joinOneRaftCluster(nodeID, leadIP)
+		if e := c.joinOneRaftCluster(nodeID, leadIP); e != nil {
+			err = e
+		}
 	}
+	if len(c.raftNode) == 0 {
+		return err
+	}
+	return nil
 }
 
-func (c *RaftClusterComponent) joinOneRaftCluster(nodeID uint64, leadIP string) {
+func (c *RaftClusterComponent) joinOneRaftCluster(nodeID uint64, leadIP string) error {
 	_, ok := c.raftNode[nodeID]
 	if ok {
-		return
+		return nil
 	}
 
 	fmt.Printf("join raft node %d\n", nodeID)
 
-	var err error
 	dataDir := filepath.Join("/raft", fmt.Sprintf("node%d", nodeID))
 	rc := config.Config{
 		// ClusterID and NodeID of the raft node
@@ -288,23 +311,26 @@ func (c *RaftClusterComponent) joinOneRaftCluster(nodeID uint64, leadIP string)
 		RTTMillisecond: 200,
 		RaftAddress:    c.raftAddress[nodeID],
 	}
-	c.raftNode[nodeID], err = dragonboat.NewNodeHost(nhc)
+	nh, err := dragonboat.NewNodeHost(nhc)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("new node host %d: %w", nodeID, err)
 	}
-	err = c.raftNode[nodeID].StartCluster(map[uint64]string{}, true, c.CreateFun, rc)
+	err = nh.StartCluster(map[uint64]string{}, true, c.CreateFun, rc)
 	if err != nil {
 		c.logger.Error(err)
-		delete(c.raftNode, nodeID)
+		nh.Stop()
+		return err
 	}
 
 	// ask to join if fall means lead down?
 	err = c.submitRequest(nodeID, leadIP, true)
 	if err != nil {
 		c.logger.Error(err)
-		c.raftNode[nodeID].Stop()
-		delete(c.raftNode, nodeID)
+		nh.Stop()
+		return err
 	}
+	c.raftNode[nodeID] = nh
+	return nil
 }
 
 const (
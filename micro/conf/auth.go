@@ -0,0 +1,81 @@
+package conf
+
+import (
+	"github.com/spf13/viper"
+)
+
+const (
+	authEnable          = "auth.enable"
+	authAuthenticateAll = "auth.authenticateAll"
+	authAPIKeys         = "auth.apiKeys"
+	authClients         = "auth.clients"
+	authJWTSecret       = "auth.jwt.secret"
+	authJWTJWKS         = "auth.jwt.jwks"
+	authJWTIssuer       = "auth.jwt.issuer"
+	authJWTAudience     = "auth.jwt.audience"
+	authJWTScopeClaim   = "auth.jwt.scopeClaim"
+)
+
+var defaultAuthConfig = AuthConfig{
+	Enable:          false,
+	AuthenticateAll: false,
+	JWT: JWTConfig{
+		ScopeClaim: "scope",
+	},
+}
+
+// AuthConfig is the authentication config of http api
+type AuthConfig struct {
+	Enable          bool           `toml:"enable" json:"enable,omitempty"`                    // enable authentication and authorization
+	AuthenticateAll bool           `toml:"authenticateAll" json:"authenticate_all,omitempty"` // routes not declared by micro.Secure require authentication too, health probes excluded
+	APIKeys         []AuthIdentity `toml:"apiKeys" json:"-"`                                  // static api keys sent in header X-API-Key
+	Clients         []AuthIdentity `toml:"clients" json:"clients,omitempty"`                  // client certificates of mTLS, identified by common name
+	JWT             JWTConfig      `toml:"jwt" json:"jwt,omitempty"`                          // json web tokens sent in header Authorization: Bearer
+}
+
+// AuthIdentity is an identity with its scopes
+type AuthIdentity struct {
+	Name   string   `toml:"name" json:"name"`               // name of the identity, common name of the certificate for clients
+	Key    string   `toml:"key" json:"-"`                   // the api key, unused for clients
+	Scopes []string `toml:"scopes" json:"scopes,omitempty"` // scopes granted
+}
+
+// JWTConfig is the config of json web tokens, tokens are disabled if neither secret nor jwks is set
+type JWTConfig struct {
+	Secret     string `toml:"secret" json:"-"`                         // secret of HS256/384/512 tokens
+	JWKS       string `toml:"jwks" json:"jwks,omitempty"`              // local JWKS file of RS256/384/512 or HS keys, selected by kid
+	Issuer     string `toml:"issuer" json:"issuer,omitempty"`          // required iss claim if set
+	Audience   string `toml:"audience" json:"audience,omitempty"`      // required aud claim if set
+	ScopeClaim string `toml:"scopeClaim" json:"scope_claim,omitempty"` // claim of scopes, a space separated string or an array
+}
+
+// SetDefaultAuthConfig set default auth config
+func SetDefaultAuthConfig() {
+	viper.SetDefault(authEnable, defaultAuthConfig.Enable)
+	viper.SetDefault(authAuthenticateAll, defaultAuthConfig.AuthenticateAll)
+	viper.SetDefault(authJWTScopeClaim, defaultAuthConfig.JWT.ScopeClaim)
+}
+
+// GetAuthConfig get auth config
+func GetAuthConfig() (*AuthConfig, error) {
+	config := &AuthConfig{
+		Enable:          viper.GetBool(authEnable),
+		AuthenticateAll: viper.GetBool(authAuthenticateAll),
+		JWT: JWTConfig{
+			Secret:     viper.GetString(authJWTSecret),
+			JWKS:       viper.GetString(authJWTJWKS),
+			Issuer:     viper.GetString(authJWTIssuer),
+			Audience:   viper.GetString(authJWTAudience),
+			ScopeClaim: viper.GetString(authJWTScopeClaim),
+		},
+	}
+	err := viper.UnmarshalKey(authAPIKeys, &config.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+	err = viper.UnmarshalKey(authClients, &config.Clients)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
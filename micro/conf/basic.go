@@ -33,6 +33,21 @@ const (
 	basicAPITimeout    = "basic.apiTimeout"
 	basicInSwarm       = "basic.inSwarm"
 	basicWorkLoad      = "basic.workLoad"
+	basicJoinTimeout   = "basic.joinTimeout"
+
+	basicShutdownTimeout = "basic.shutdownTimeout"
+	basicStopTimeout     = "basic.stopTimeout"
+	basicHealthInterval  = "basic.healthInterval"
+	basicHealthTimeout   = "basic.healthTimeout"
+	basicWatchConfig     = "basic.watchConfig"
+	basicAPIHost         = "basic.apiHost"
+	basicNetwork         = "basic.network"
+	basicMaxConns        = "basic.maxConns"
+	basicAdminPort       = "basic.adminPort"
+	basicTLSCert         = "basic.tlsCert"
+	basicTLSKey          = "basic.tlsKey"
+	basicTLSClientCA     = "basic.tlsClientCA"
+	basicTLSClientAuth   = "basic.tlsClientAuth"
 )
 
 var defaultBasicConfig = BasicConfig{
@@ -56,33 +71,58 @@ var defaultBasicConfig = BasicConfig{
 	APITimeout:      15000,
 	InSwarm:         true,
 	WorkLoad:        0,
+	JoinTimeout:     30000,
+	ShutdownTimeout: 30000,
+	StopTimeout:     10000,
+	HealthInterval:  5000,
+	HealthTimeout:   3000,
+	WatchConfig:     true,
+	APIHost:         "",
+	Network:         "tcp4",
+	MaxConns:        128,
+	AdminPort:       0,
+	TLSClientAuth:   "require",
 }
 
 // BasicConfig 基本配置
 type BasicConfig struct {
-	Zone            string  `toml:"zone" json:"zone,omitempty"`                       // deployment environment zone code
-	Node            string  `toml:"node" json:"node,omitempty"`                       // node
-	Machine         string  `toml:"machine" json:"machine,omitempty"`                 // machine
-	Service         string  `toml:"service" json:"service,omitempty"`                 // service
-	Instance        string  `json:"instance,omitempty"`                               // instance
-	AppName         string  `json:"app_name,omitempty"`                               // app name
-	AppVersion      string  `json:"app_version,omitempty"`                            // app version
-	IsDevMode       bool    `toml:"devMode" json:"is_dev_mode,omitempty"`             // dev mode
-	APIRoot         string  `toml:"apiRoot" json:"api_root,omitempty"`                // restful api root path
-	APIPort         int     `toml:"apiPort" json:"api_port,omitempty"`                // api export port
-	IsProf          bool    `toml:"prof" json:"is_prof,omitempty"`                    // open pProf
-	IsDynamicConfig bool    `toml:"dynamicConfig" json:"is_dynamic_config,omitempty"` // use dynamic config
-	CPUCount        int     `toml:"cpu" json:"cpu_count,omitempty"`                   // cpu count
-	IsAPIRate       bool    `toml:"isApiRate" json:"is_api_rate,omitempty"`           // whether to enable rate limit
-	IsAPIBody       bool    `toml:"isApiBody" json:"is_api_body,omitempty"`           // whether to enable body limit
-	IsAPITimeout    bool    `toml:"isApiTimeout" json:"is_api_timeout,omitempty"`     // whether to enable timeout limit
-	APIRate         float64 `toml:"apiRate" json:"rate,omitempty"`                    // rate
-	APIBurst        int     `toml:"apiBurst" json:"burst,omitempty"`                  // burst value. the number if times increased when the request reaches the rate limit.
-	APIExpiresIn    int     `toml:"apiExpires" json:"expires_in,omitempty"`           // expire time
-	APIBodyLimit    string  `toml:"apiBodyLimit" json:"body_limit,omitempty"`         // query body limit. eg: 10MB
-	APITimeout      int     `toml:"apiTimeout" json:"timeout,omitempty"`              // api timeout. ms
-	InSwarm         bool    `toml:"inSwarm" json:"inSwarm,omitempty"`                 // in swarm
-	WorkLoad        int     `toml:"workLoad" json:"work_load,omitempty"`              // work load
+	Zone            string  `toml:"zone" json:"zone,omitempty"`                        // deployment environment zone code
+	Node            string  `toml:"node" json:"node,omitempty"`                        // node
+	Machine         string  `toml:"machine" json:"machine,omitempty"`                  // machine
+	Service         string  `toml:"service" json:"service,omitempty"`                  // service
+	Instance        string  `json:"instance,omitempty"`                                // instance
+	AppName         string  `json:"app_name,omitempty"`                                // app name
+	AppVersion      string  `json:"app_version,omitempty"`                             // app version
+	IsDevMode       bool    `toml:"devMode" json:"is_dev_mode,omitempty"`              // dev mode
+	APIRoot         string  `toml:"apiRoot" json:"api_root,omitempty"`                 // restful api root path
+	APIPort         int     `toml:"apiPort" json:"api_port,omitempty"`                 // api export port
+	IsProf          bool    `toml:"prof" json:"is_prof,omitempty"`                     // open pProf
+	IsDynamicConfig bool    `toml:"dynamicConfig" json:"is_dynamic_config,omitempty"`  // use dynamic config
+	CPUCount        int     `toml:"cpu" json:"cpu_count,omitempty"`                    // cpu count
+	IsAPIRate       bool    `toml:"isApiRate" json:"is_api_rate,omitempty"`            // whether to enable rate limit
+	IsAPIBody       bool    `toml:"isApiBody" json:"is_api_body,omitempty"`            // whether to enable body limit
+	IsAPITimeout    bool    `toml:"isApiTimeout" json:"is_api_timeout,omitempty"`      // whether to enable timeout limit
+	APIRate         float64 `toml:"apiRate" json:"rate,omitempty"`                     // rate
+	APIBurst        int     `toml:"apiBurst" json:"burst,omitempty"`                   // burst value. the number if times increased when the request reaches the rate limit.
+	APIExpiresIn    int     `toml:"apiExpires" json:"expires_in,omitempty"`            // expire time
+	APIBodyLimit    string  `toml:"apiBodyLimit" json:"body_limit,omitempty"`          // query body limit. eg: 10MB
+	APITimeout      int     `toml:"apiTimeout" json:"timeout,omitempty"`               // api timeout. ms
+	InSwarm         bool    `toml:"inSwarm" json:"inSwarm,omitempty"`                  // in swarm
+	WorkLoad        int     `toml:"workLoad" json:"work_load,omitempty"`               // work load
+	JoinTimeout     int     `toml:"joinTimeout" json:"join_timeout,omitempty"`         // swarm components are degraded if they do not join a cluster in time. ms
+	ShutdownTimeout int     `toml:"shutdownTimeout" json:"shutdown_timeout,omitempty"` // total budget of shutdown. ms
+	StopTimeout     int     `toml:"stopTimeout" json:"stop_timeout,omitempty"`         // deadline of each stop step of a component, http draining included. ms
+	HealthInterval  int     `toml:"healthInterval" json:"health_interval,omitempty"`   // interval of active health checks. ms
+	HealthTimeout   int     `toml:"healthTimeout" json:"health_timeout,omitempty"`     // default timeout of one health check. ms
+	WatchConfig     bool    `toml:"watchConfig" json:"watch_config,omitempty"`         // reload the config file when it changes, if dynamic config is not used
+	APIHost         string  `toml:"apiHost" json:"api_host,omitempty"`                 // api listen host, all addresses of the network if empty
+	Network         string  `toml:"network" json:"network,omitempty"`                  // tcp4, tcp6 or tcp(dual-stack)
+	MaxConns        int     `toml:"maxConns" json:"max_conns,omitempty"`               // max concurrent api connections, 0 means no limit
+	AdminPort       int     `toml:"adminPort" json:"admin_port,omitempty"`             // port of metrics, pprof, status and control endpoints, served on apiPort if 0
+	TLSCert         string  `toml:"tlsCert" json:"tls_cert,omitempty"`                 // server certificate file, serve https if set. reloaded when it changes
+	TLSKey          string  `toml:"tlsKey" json:"tls_key,omitempty"`                   // server private key file
+	TLSClientCA     string  `toml:"tlsClientCA" json:"tls_client_ca,omitempty"`        // client CA file, enable mTLS if set
+	TLSClientAuth   string  `toml:"tlsClientAuth" json:"tls_client_auth,omitempty"`    // require or verify(only if a client certificate is given)
 }
 
 // SetDefaultBasicConfig set default basic config
@@ -107,6 +147,17 @@ func SetDefaultBasicConfig() {
 	viper.SetDefault(basicAPITimeout, defaultBasicConfig.APITimeout)
 	viper.SetDefault(basicInSwarm, defaultBasicConfig.InSwarm)
 	viper.SetDefault(basicWorkLoad, defaultBasicConfig.WorkLoad)
+	viper.SetDefault(basicJoinTimeout, defaultBasicConfig.JoinTimeout)
+	viper.SetDefault(basicShutdownTimeout, defaultBasicConfig.ShutdownTimeout)
+	viper.SetDefault(basicStopTimeout, defaultBasicConfig.StopTimeout)
+	viper.SetDefault(basicHealthInterval, defaultBasicConfig.HealthInterval)
+	viper.SetDefault(basicHealthTimeout, defaultBasicConfig.HealthTimeout)
+	viper.SetDefault(basicWatchConfig, defaultBasicConfig.WatchConfig)
+	viper.SetDefault(basicAPIHost, defaultBasicConfig.APIHost)
+	viper.SetDefault(basicNetwork, defaultBasicConfig.Network)
+	viper.SetDefault(basicMaxConns, defaultBasicConfig.MaxConns)
+	viper.SetDefault(basicAdminPort, defaultBasicConfig.AdminPort)
+	viper.SetDefault(basicTLSClientAuth, defaultBasicConfig.TLSClientAuth)
 }
 
 // GetBasicConfig get basic config
@@ -135,5 +186,19 @@ func GetBasicConfig() *BasicConfig {
 		APITimeout:      viper.GetInt(basicAPITimeout),
 		InSwarm:         viper.GetBool(basicInSwarm),
 		WorkLoad:        viper.GetInt(basicWorkLoad),
+		JoinTimeout:     viper.GetInt(basicJoinTimeout),
+		ShutdownTimeout: viper.GetInt(basicShutdownTimeout),
+		StopTimeout:     viper.GetInt(basicStopTimeout),
+		HealthInterval:  viper.GetInt(basicHealthInterval),
+		HealthTimeout:   viper.GetInt(basicHealthTimeout),
+		WatchConfig:     viper.GetBool(basicWatchConfig),
+		APIHost:         viper.GetString(basicAPIHost),
+		Network:         viper.GetString(basicNetwork),
+		MaxConns:        viper.GetInt(basicMaxConns),
+		AdminPort:       viper.GetInt(basicAdminPort),
+		TLSCert:         viper.GetString(basicTLSCert),
+		TLSKey:          viper.GetString(basicTLSKey),
+		TLSClientCA:     viper.GetString(basicTLSClientCA),
+		TLSClientAuth:   viper.GetString(basicTLSClientAuth),
 	}
 }
@@ -0,0 +1,197 @@
+package micro
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IDependentComponent is an optional interface of component which declares what it depends on
+type IDependentComponent interface {
+	// DependsOn returns names of components which must be set up before this one
+	DependsOn() []string
+
+	// DependsOnElements returns keys of elements which must be registered before this one is initialized
+	DependsOnElements() []*ElementKey
+}
+
+// IElementProvider is an optional interface of component which declares the elements it registers
+type IElementProvider interface {
+	// ProvidesElements returns keys of elements registered by the component in Init()
+	ProvidesElements() []*ElementKey
+}
+
+// sortComponents orders components so that every component comes after the ones it depends on,
+// components without dependency between them keep their original order
+func sortComponents(components []IComponent) ([]IComponent, error) {
+	hasDependency := false
+	for _, v := range components {
+		if d, ok := v.(IDependentComponent); ok && (len(d.DependsOn()) > 0 || len(d.DependsOnElements()) > 0) {
+			hasDependency = true
+			break
+		}
+	}
+	if !hasDependency {
+		return components, nil
+	}
+
+	// index components and element providers
+	names := make([]string, len(components))
+	byName := map[string]int{}
+	providers := map[ElementKey]int{}
+	for i, v := range components {
+		names[i] = v.Name()
+		if _, ok := byName[names[i]]; ok {
+			return nil, fmt.Errorf("duplicated component name %s", names[i])
+		}
+		byName[names[i]] = i
+		if p, ok := v.(IElementProvider); ok {
+			for _, key := range p.ProvidesElements() {
+				providers[*key] = i
+			}
+		}
+	}
+
+	// build edges, dependency -> dependent
+	edges := make([][]int, len(components))
+	inDegree := make([]int, len(components))
+	for i, v := range components {
+		d, ok := v.(IDependentComponent)
+		if !ok {
+			continue
+		}
+		deps := map[int]bool{}
+		for _, name := range d.DependsOn() {
+			j, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("component %s depends on missing component %s", names[i], name)
+			}
+			deps[j] = true
+		}
+		// elements without a declared provider are checked when the component is initialized
+		for _, key := range d.DependsOnElements() {
+			if j, ok := providers[*key]; ok {
+				deps[j] = true
+			}
+		}
+		for j := range deps {
+			if j == i {
+				return nil, fmt.Errorf("component %s depends on itself", names[i])
+			}
+			edges[j] = append(edges[j], i)
+			inDegree[i]++
+		}
+	}
+
+	// Kahn's algorithm, always pick the first ready component to keep the original order
+	sorted := make([]IComponent, 0, len(components))
+	done := make([]bool, len(components))
+	for len(sorted) < len(components) {
+		next := -1
+		for i := range components {
+			if !done[i] && inDegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("dependency cycle found: %s", findCycle(names, edges, done))
+		}
+		done[next] = true
+		sorted = append(sorted, components[next])
+		for _, j := range edges[next] {
+			inDegree[j]--
+		}
+	}
+	return sorted, nil
+}
+
+// findCycle returns a readable path of a cycle among components not done yet
+func findCycle(names []string, edges [][]int, done []bool) string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(names))
+	var stack []int
+	var cycle []int
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, j := range edges[i] {
+			if done[j] {
+				continue
+			}
+			if state[j] == visiting {
+				for k := len(stack) - 1; k >= 0; k-- {
+					if stack[k] == j {
+						cycle = append(cycle, stack[k:]...)
+						cycle = append(cycle, j)
+						return true
+					}
+				}
+			}
+			if state[j] == unvisited && visit(j) {
+				return true
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return false
+	}
+	for i := range names {
+		if !done[i] && state[i] == unvisited && visit(i) {
+			break
+		}
+	}
+	path := make([]string, 0, len(cycle))
+	for _, i := range cycle {
+		path = append(path, names[i])
+	}
+	return strings.Join(path, " -> ")
+}
+
+// checkElementDependencies make sure the elements a component depends on are registered
+func (m *Server) checkElementDependencies(c IComponent) error {
+	d, ok := c.(IDependentComponent)
+	if !ok {
+		return nil
+	}
+	for _, key := range d.DependsOnElements() {
+		if m.GetElement(key) == nil {
+			return fmt.Errorf("component %s depends on missing element %s", c.Name(), *key)
+		}
+	}
+	return nil
+}
+
+// componentDependencies returns names of components each component depends on, by name or by provided element.
+// components are already validated by sortComponents, so missing dependencies are ignored
+func componentDependencies(components []IComponent) map[string][]string {
+	providers := map[ElementKey]string{}
+	for _, v := range components {
+		if p, ok := v.(IElementProvider); ok {
+			for _, key := range p.ProvidesElements() {
+				providers[*key] = v.Name()
+			}
+		}
+	}
+	result := map[string][]string{}
+	for _, v := range components {
+		d, ok := v.(IDependentComponent)
+		if !ok {
+			continue
+		}
+		deps := append([]string{}, d.DependsOn()...)
+		for _, key := range d.DependsOnElements() {
+			if name, ok := providers[*key]; ok {
+				deps = append(deps, name)
+			}
+		}
+		if len(deps) > 0 {
+			result[v.Name()] = deps
+		}
+	}
+	return result
+}
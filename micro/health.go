@@ -1,8 +1,10 @@
 package micro
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -52,3 +54,237 @@ func GetStatus(address string) (*Status, error) {
 	}
 	return status, nil
 }
+
+const (
+	defaultHealthInterval = 5 * time.Second
+	defaultHealthTimeout  = 3 * time.Second
+)
+
+// HealthLevel is the criticality of a health check
+type HealthLevel int
+
+const (
+	// HealthLevelCritical failing check makes the service not ready, it is the default level
+	HealthLevelCritical HealthLevel = iota
+	// HealthLevelWarning failing check is only reported in status
+	HealthLevelWarning
+	// HealthLevelFatal failing check makes the service neither ready nor live
+	HealthLevelFatal
+)
+
+// String of the level
+func (l HealthLevel) String() string {
+	switch l {
+	case HealthLevelWarning:
+		return "warning"
+	case HealthLevelFatal:
+		return "fatal"
+	default:
+		return "critical"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (l HealthLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *HealthLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "critical":
+		*l = HealthLevelCritical
+	case "warning":
+		*l = HealthLevelWarning
+	case "fatal":
+		*l = HealthLevelFatal
+	default:
+		return fmt.Errorf("unknown health level %q", text)
+	}
+	return nil
+}
+
+// HealthCheck is an active probe of something the component relies on
+type HealthCheck struct {
+	Name    string                          // name of the check, unique in the component
+	Level   HealthLevel                     // criticality of the check
+	Timeout time.Duration                   // timeout of one probe, basic.healthTimeout is used if zero
+	Check   func(ctx context.Context) error // returns nil if healthy
+}
+
+// IHealthChecker is an optional interface of component which probes its dependencies actively
+type IHealthChecker interface {
+	// HealthChecks returns checks of the component, called after Init()
+	HealthChecks() []HealthCheck
+}
+
+// HealthCheckResult is the cached result of a health check
+type HealthCheckResult struct {
+	IsOK      bool        `json:"is_ok"`
+	Level     HealthLevel `json:"level"`
+	Error     string      `json:"error,omitempty"`
+	Latency   string      `json:"latency,omitempty"`
+	CheckedAt time.Time   `json:"checked_at"`
+}
+
+// Probe is the response of liveness and readiness endpoints
+type Probe struct {
+	IsOK   bool                          `json:"is_ok"`
+	Reason string                        `json:"reason,omitempty"`
+	Checks map[string]*HealthCheckResult `json:"checks,omitempty"`
+}
+
+type namedHealthCheck struct {
+	key string // component/check
+	HealthCheck
+}
+
+// healthChecker runs health checks periodically and caches the results,
+// so that probe endpoints never wait for a dependency
+type healthChecker struct {
+	lock    sync.RWMutex
+	checks  []namedHealthCheck
+	results map[string]*HealthCheckResult
+	timeout time.Duration
+}
+
+func newHealthChecker(components []IComponent, timeout time.Duration) *healthChecker {
+	h := &healthChecker{
+		results: map[string]*HealthCheckResult{},
+		timeout: timeout,
+	}
+	for _, v := range components {
+		c, ok := v.(IHealthChecker)
+		if !ok {
+			continue
+		}
+		for _, check := range c.HealthChecks() {
+			if check.Check == nil {
+				continue
+			}
+			h.checks = append(h.checks, namedHealthCheck{
+				key:         v.Name() + "/" + check.Name,
+				HealthCheck: check,
+			})
+		}
+	}
+	return h
+}
+
+// run checks every interval until ctx is done
+func (h *healthChecker) run(ctx context.Context, interval time.Duration) {
+	if len(h.checks) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthChecker) checkAll(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check namedHealthCheck) {
+			defer wg.Done()
+			result := h.checkOne(ctx, check)
+			h.lock.Lock()
+			h.results[check.key] = result
+			h.lock.Unlock()
+		}(check)
+	}
+	wg.Wait()
+}
+
+func (h *healthChecker) checkOne(ctx context.Context, check namedHealthCheck) *HealthCheckResult {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = h.timeout
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timeout after %s", timeout)
+	}
+	result := &HealthCheckResult{
+		IsOK:      err == nil,
+		Level:     check.Level,
+		Latency:   time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// probe returns the result of checks whose level is in levels, a check never run is only failed if failUnknown
+func (h *healthChecker) probe(failUnknown bool, levels ...HealthLevel) *Probe {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	p := &Probe{
+		IsOK:   true,
+		Checks: map[string]*HealthCheckResult{},
+	}
+	for _, check := range h.checks {
+		matched := false
+		for _, level := range levels {
+			matched = matched || check.Level == level
+		}
+		if !matched {
+			continue
+		}
+		result, ok := h.results[check.key]
+		if !ok {
+			if failUnknown {
+				p.IsOK = false
+				p.Reason = "waiting for first check of " + check.key
+			}
+			continue
+		}
+		p.Checks[check.key] = result
+		if !result.IsOK {
+			p.IsOK = false
+			p.Reason = check.key + ": " + result.Error
+		}
+	}
+	return p
+}
+
+// all returns a copy of all cached results
+func (h *healthChecker) all() map[string]*HealthCheckResult {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	results := make(map[string]*HealthCheckResult, len(h.results))
+	for k, v := range h.results {
+		results[k] = v
+	}
+	return results
+}
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,8 +28,7 @@ import (
 	"github.com/pangpanglabs/echoswagger/v2"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"golang.org/x/net/netutil"
-	"golang.org/x/time/rate"
+	"go.uber.org/atomic"
 
 	platformConf "github.com/kiga-hub/arc/conf"
 	"github.com/kiga-hub/arc/configuration"
@@ -45,18 +46,23 @@ const (
 
 	urlStatus = "/status"
 	urlHealth = "/health"
+	urlLive   = "/health/live"
+	urlReady  = "/health/ready"
 )
 
 var (
 	// ErrNeedRestart will notify micro to restart when onConfigChanged
 	ErrNeedRestart = errors.New("need restart")
+	// ErrRejected means a reloaded config file is rejected and the running config is kept
+	ErrRejected = errors.New("config reload rejected")
 )
 
 // Status is status of micro
 type Status struct {
-	IsOK       bool                        `json:"is_ok,omitempty"`
-	Basic      *conf.BasicConfig           `json:"basic,omitempty"`
-	Components map[string]*ComponentStatus `json:"components,omitempty"`
+	IsOK       bool                          `json:"is_ok,omitempty"`
+	Basic      *conf.BasicConfig             `json:"basic,omitempty"`
+	Components map[string]*ComponentStatus   `json:"components,omitempty"`
+	Checks     map[string]*HealthCheckResult `json:"checks,omitempty"`
 }
 
 // ComponentStatus is status of component
@@ -135,6 +141,24 @@ type Server struct {
 	FlagSet        *pflag.FlagSet
 	stopSignal     chan os.Signal
 
+	drainCtx         context.Context
+	drainCancel      context.CancelFunc
+	hijacked         sync.WaitGroup
+	trackLock        sync.Mutex // guards hijacked.Add against hijacked.Wait of draining
+	shutdownReport   *ShutdownReport
+	health           *healthChecker
+	started          *atomic.Bool
+	reloadLock       sync.Mutex
+	configData       []byte // content of the config file last accepted
+	bodyLimit        *swappableMiddleware
+	timeout          *swappableMiddleware
+	rateLimiter      *RateLimiter
+	dynamicRateLimit atomic.Pointer[platformConf.RateLimitConfig] // set by the listener of dynamic config
+	auth             *AuthMiddleware
+	network          string
+	certs            *CertReloader
+	adminServer      *http.Server
+
 	GzipSkipper func(uri string) bool
 	// APIRateSkipper defime rate limiter skipper
 	APIRateSkipper func(uri string) bool
@@ -149,15 +173,28 @@ type Server struct {
 	loggerMonitorFunc func() logging.ILogger
 }
 
-// NewServer create a MicroServer
+// NewServer create a MicroServer, components are ordered by their declared dependencies
 func NewServer(appname, appversion string, components []IComponent) (*Server, error) {
-	server := &Server{
-		AppName:    appname,
-		AppVersion: appversion,
-		components: components,
-		ctx:        context.WithValue(context.Background(), &regkey, map[interface{}]interface{}{}),
-		stopSignal: make(chan os.Signal, 1),
+	sorted, err := sortComponents(components)
+	if err != nil {
+		return nil, err
 	}
+	noopLogger := func() logging.ILogger {
+		return &logging.NoopLogger{}
+	}
+	server := &Server{
+		AppName:           appname,
+		AppVersion:        appversion,
+		components:        sorted,
+		ctx:               context.WithValue(context.Background(), &regkey, map[interface{}]interface{}{}),
+		stopSignal:        make(chan os.Signal, 1),
+		loggerMicroFunc:   noopLogger,
+		loggerHTTPFunc:    noopLogger,
+		loggerConfigFunc:  noopLogger,
+		loggerMonitorFunc: noopLogger,
+		started:           atomic.NewBool(false),
+	}
+	server.drainCtx, server.drainCancel = context.WithCancel(context.Background())
 	return server, nil
 }
 
@@ -170,17 +207,42 @@ func (m *Server) GetStatus() Status {
 		cs[v.Name()] = status
 		ok = ok && status.IsOK
 	}
-	return Status{
+	status := Status{
 		IsOK:       ok,
 		Basic:      conf.GetBasicConfig(),
 		Components: cs,
 	}
+	if m.health != nil {
+		status.Checks = m.health.all()
+	}
+	return status
+}
+
+// GetLiveness returns the result of fatal health checks, the process should be restarted if it is not ok
+func (m *Server) GetLiveness() *Probe {
+	if m.health == nil {
+		return &Probe{IsOK: true}
+	}
+	return m.health.probe(false, HealthLevelFatal)
+}
+
+// GetReadiness returns the result of critical and fatal health checks, the service should not receive traffic if it is not ok
+func (m *Server) GetReadiness() *Probe {
+	if m.drainCtx.Err() != nil {
+		return &Probe{Reason: "shutting down"}
+	}
+	if !m.started.Load() || m.health == nil {
+		return &Probe{Reason: "starting"}
+	}
+	return m.health.probe(true, HealthLevelCritical, HealthLevelFatal)
 }
 
 func (m *Server) preInit() error {
 	//config
 	// from default
 	conf.SetDefaultBasicConfig()
+	conf.SetDefaultAuthConfig()
+	platformConf.SetDefaultRateLimitConfig()
 	for _, v := range m.components {
 		err := v.PreInit(m.ctx)
 		if err != nil {
@@ -202,6 +264,12 @@ func (m *Server) preInit() error {
 		default:
 			return err
 		}
+	} else {
+		// kept to roll back a rejected reload
+		m.configData, err = ioutil.ReadFile(viper.ConfigFileUsed())
+		if err != nil {
+			return err
+		}
 	}
 
 	// from env
@@ -250,7 +318,7 @@ func (m *Server) preInit() error {
 			if err0 != nil {
 				m.loggerConfigFunc().Error(err0)
 				if err0 == ErrNeedRestart {
-					m.stopSignal <- syscall.SIGINT
+					m.requestStop()
 				}
 			}
 		})
@@ -339,6 +407,7 @@ func (m *Server) setDynamicConfig(data string) error {
 		}
 		return nil
 	}
+	m.dynamicRateLimit.Store(node.RateLimit)
 	for _, v := range m.components {
 		err = v.SetDynamicConfig(&node)
 		if err != nil {
@@ -365,6 +434,12 @@ func (m *Server) onConfigChanged(data string) error {
 	if !ok {
 		return nil
 	}
+	m.dynamicRateLimit.Store(node.RateLimit)
+	// components get the change even if the policies are invalid
+	err = m.updateRateLimiter()
+	if err != nil {
+		m.loggerConfigFunc().Errorw("update rate limiter", "error", err)
+	}
 	for _, v := range m.components {
 		err := v.OnConfigChanged(&node)
 		if err != nil {
@@ -420,6 +495,10 @@ func (m *Server) Init() error {
 		return err
 	}
 	for _, v := range m.components {
+		err = m.checkElementDependencies(v)
+		if err != nil {
+			return err
+		}
 		err = v.Init(m)
 		if err != nil {
 			return err
@@ -463,6 +542,7 @@ func (m *Server) preStart() error {
 	m.loggerMonitorFunc = GenerateLoggerForModule(m, "monitor")
 
 	basicConf := conf.GetBasicConfig()
+	m.health = newHealthChecker(m.components, time.Duration(basicConf.HealthTimeout)*time.Millisecond)
 	swaggerURI := basicConf.APIRoot + "/swagger"
 	swaggerAssetsPath := basicConf.APIRoot + "/static/swagger"
 
@@ -472,7 +552,7 @@ func (m *Server) preStart() error {
 		return func(c echo.Context) error {
 			uri := c.Request().RequestURI
 			if !basicConf.IsDevMode {
-				if uri == urlHealth || uri == constant.URLMetrics || uri == swaggerURI {
+				if uri == urlHealth || uri == urlLive || uri == urlReady || uri == constant.URLMetrics || uri == swaggerURI {
 					return zapLoggerEchoMiddleware(m.loggerHTTPFunc, true)(next)(c)
 				}
 				if strings.Contains(uri, swaggerAssetsPath) {
@@ -483,88 +563,50 @@ func (m *Server) preStart() error {
 		}
 	})
 
+	// authentication of routes declared by Secure, health probes and swagger are public
+	authConf, err := conf.GetAuthConfig()
+	if err != nil {
+		return err
+	}
+	m.auth, err = NewAuthMiddleware(authConf)
+	if err != nil {
+		return err
+	}
+	m.e.Use(m.auth.Handle(urlHealth, urlLive, urlReady, swaggerURI, swaggerAssetsPath+"/*",
+		basicConf.APIRoot+urlHealth, basicConf.APIRoot+urlLive, basicConf.APIRoot+urlReady))
+
 	// enable request body limit middleware
 	if basicConf.IsAPIBody {
-		m.e.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
-			Limit: basicConf.APIBodyLimit,
-			Skipper: func(ctx echo.Context) bool {
-				if m.APIBodySkipper != nil {
-					uri := ctx.Request().RequestURI
-					return m.APIBodySkipper(uri)
-				}
-				return false
-			},
-		}))
+		m.bodyLimit = newSwappableMiddleware(m.bodyLimitMiddleware(basicConf))
+		m.e.Use(m.bodyLimit.Handle)
 	}
 
 	// enable timeout
 	if basicConf.IsAPITimeout {
-		// timeout infortation
-		msgTimeOut, err := json.Marshal(utils.ResponseV2{
-			Code: http.StatusRequestTimeout,
-			Msg:  http.StatusText(http.StatusRequestTimeout),
-		})
+		mw, err := m.timeoutMiddleware(basicConf)
 		if err != nil {
 			return err
 		}
 		// register timeout middleware
-		m.e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
-			Skipper: func(ctx echo.Context) bool {
-				// ignore the verification of websocket
-				if ctx.IsWebSocket() {
-					return true
-				}
-				if m.APITimeOutSkipper != nil {
-					uri := ctx.Request().RequestURI
-					return m.APITimeOutSkipper(uri)
-				}
-				return false
-			},
-			ErrorMessage:               string(msgTimeOut),
-			OnTimeoutRouteErrorHandler: nil,
-			Timeout:                    time.Millisecond * time.Duration(basicConf.APITimeout),
-		}))
+		m.timeout = newSwappableMiddleware(mw)
+		m.e.Use(m.timeout.Handle)
 	}
 
 	// enable the core configuration if the rate limiter
 	if basicConf.IsAPIRate {
-		var identifier string
-		rateLimit := middleware.RateLimiterConfig{
-			Skipper: func(ctx echo.Context) bool {
-				if m.APIRateSkipper != nil {
-					uri := ctx.Request().RequestURI
-					return m.APIRateSkipper(uri)
-				}
-				return false
-			},
-			Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
-				Rate:      rate.Limit(basicConf.APIRate), // request times / second
-				Burst:     basicConf.APIBurst,
-				ExpiresIn: time.Duration(basicConf.APIExpiresIn) * time.Second,
-			}),
-			// use the request parameter sensorid. to limit the read access frequency
-			IdentifierExtractor: func(ctx echo.Context) (string, error) {
-				if identifier = ctx.QueryParam("sensorid"); identifier != "" {
-					return identifier, nil
-				}
-				// if sensorid is empty.then use the client's ip
-				identifier = ctx.RealIP()
-				return identifier, nil
-			},
-			ErrorHandler: func(context echo.Context, err error) error {
-				// The return value of the access identifier is defined as nil.
-				// here you can directly return nil
-				return nil
-			},
-			DenyHandler: func(context echo.Context, identifier string, err error) error {
-				return context.JSON(http.StatusTooManyRequests, utils.ResponseV2{
-					Code: http.StatusTooManyRequests,
-					Msg:  http.StatusText(http.StatusTooManyRequests) + ":" + identifier,
-				})
-			},
+		var err error
+		m.rateLimiter, err = m.newRateLimiter(basicConf)
+		if err != nil {
+			return err
 		}
 		// register request rate limiting middleware
-		m.e.Use(middleware.RateLimiterWithConfig(rateLimit))
+		m.e.Use(m.rateLimiter.Middleware(func(ctx echo.Context) bool {
+			if m.APIRateSkipper != nil {
+				uri := ctx.Request().RequestURI
+				return m.APIRateSkipper(uri)
+			}
+			return false
+		}))
 	}
 
 	m.e.Use(middleware.Recover())
@@ -592,9 +634,9 @@ func (m *Server) preStart() error {
 	//metric
 	p := prometheus.NewPrometheus("echo", func(c echo.Context) bool {
 		uri := c.Request().RequestURI
-		return uri == urlHealth || uri == constant.URLMetrics
+		return uri == urlHealth || uri == urlLive || uri == urlReady || uri == constant.URLMetrics
 	})
-	p.Use(m.e)
+	m.e.Use(p.HandlerFunc)
 
 	//health
 	hf := func(c echo.Context) error {
@@ -606,50 +648,102 @@ func (m *Server) preStart() error {
 	}
 	m.e.GET(urlHealth, hf)
 
-	//status
-	sf := func(c echo.Context) error {
-		return c.JSON(http.StatusOK, m.GetStatus())
+	//liveness and readiness, results are cached so probes stay cheap
+	probeResponse := func(c echo.Context, p *Probe) error {
+		if !p.IsOK {
+			return c.JSON(http.StatusServiceUnavailable, p)
+		}
+		return c.JSON(http.StatusOK, p)
 	}
-	m.e.GET(urlStatus, sf)
-
-	// pprof - pprof
-	if basicConf.IsProf {
-		pprof.Register(m.e, basicConf.APIRoot+"/debug/pprof")
+	lf := func(c echo.Context) error {
+		return probeResponse(c, m.GetLiveness())
+	}
+	rf := func(c echo.Context) error {
+		return probeResponse(c, m.GetReadiness())
 	}
+	m.e.GET(urlLive, lf)
+	m.e.GET(urlReady, rf)
 
 	// api
-	m.e.Static(swaggerAssetsPath, "./swagger")
-	// swagger spec path is `/swagger/swagger.json`
-	m.apiRoot = echoswagger.New(m.e, swaggerURI, &echoswagger.Info{
-		Title:   basicConf.AppName,
-		Version: basicConf.AppVersion,
-	}).SetRequestContentType("application/json").
-		SetResponseContentType("application/json").
-		SetUI(echoswagger.UISetting{
-			CDN: swaggerAssetsPath,
-		})
+	m.apiRoot = m.newSwagger(m.e, basicConf, swaggerURI, swaggerAssetsPath)
+	network, addr, err := listenAddress(basicConf, basicConf.APIPort)
+	if err != nil {
+		return err
+	}
+	m.network = network
 	m.httpServer = http.Server{
-		Addr:    fmt.Sprintf("0.0.0.0:%d", basicConf.APIPort),
+		Addr:    addr,
 		Handler: m.e, // set Echo as handler
 	}
+	if basicConf.TLSCert != "" {
+		m.certs, err = NewCertReloader(basicConf.TLSCert, basicConf.TLSKey, basicConf.TLSClientCA)
+		if err != nil {
+			return err
+		}
+		m.httpServer.TLSConfig, err = m.certs.TLSConfig(basicConf.TLSClientAuth)
+		if err != nil {
+			return err
+		}
+	}
 
-	g := m.apiRoot.Group("Micro", basicConf.APIRoot)
+	// metrics, pprof, status and admin handlers of components are served on the admin port if it is set
+	admin, adminRoot, err := m.setupAdmin(basicConf, swaggerURI, swaggerAssetsPath)
+	if err != nil {
+		return err
+	}
+	p.SetMetricsPath(admin)
+
+	//status
+	sf := func(c echo.Context) error {
+		return c.JSON(http.StatusOK, m.GetStatus())
+	}
+	admin.GET(urlStatus, sf)
+
+	// pprof - pprof
+	if basicConf.IsProf {
+		pprof.Register(admin, basicConf.APIRoot+"/debug/pprof")
+		for _, r := range admin.Routes() {
+			if strings.HasPrefix(r.Path, basicConf.APIRoot+"/debug/pprof") {
+				SecureRoute(r.Method, r.Path, ScopeAdmin)
+			}
+		}
+	}
 
-	g.GET("/status", sf).
+	adminRoot.Group("Micro", basicConf.APIRoot).GET("/status", sf).
 		AddResponse(http.StatusOK, "", Status{}, nil).
 		SetOperationId("getStatus").
 		SetSummary("get service status")
 
+	g := m.apiRoot.Group("Micro", basicConf.APIRoot)
+
 	g.GET("/health", hf).
 		AddResponse(http.StatusOK, "", Health{}, nil).
 		SetOperationId("getHealth").
 		SetSummary("get service health")
 
+	g.GET(urlLive, lf).
+		AddResponse(http.StatusOK, "", Probe{}, nil).
+		AddResponse(http.StatusServiceUnavailable, "fatal check failed", Probe{}, nil).
+		SetOperationId("getLiveness").
+		SetSummary("get service liveness")
+
+	g.GET(urlReady, rf).
+		AddResponse(http.StatusOK, "", Probe{}, nil).
+		AddResponse(http.StatusServiceUnavailable, "critical check failed or service is starting/stopping", Probe{}, nil).
+		SetOperationId("getReadiness").
+		SetSummary("get service readiness")
+
 	for _, v := range m.components {
 		err := v.SetupHandler(m.apiRoot, basicConf.APIRoot)
 		if err != nil {
 			return err
 		}
+		if h, ok := v.(IAdminHandler); ok {
+			err = h.SetupAdminHandler(adminRoot, basicConf.APIRoot)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	if basicConf.IsDevMode {
@@ -685,28 +779,37 @@ func (m *Server) StartServe() error {
 			return err
 		}
 	}
-	l, err := net.Listen("tcp4", m.httpServer.Addr)
-	if err != nil {
-		return err
-	}
-	ln := netutil.LimitListener(l, 128)
-	go func() {
-		m.loggerHTTPFunc().Info("start http server")
-		err = m.httpServer.Serve(ln)
+	basicConf := conf.GetBasicConfig()
+	go m.health.run(m.drainCtx, time.Duration(basicConf.HealthInterval)*time.Millisecond)
+	// dynamic config comes from nacos, otherwise reload the config file when it changes
+	if !basicConf.IsDynamicConfig && basicConf.WatchConfig && viper.ConfigFileUsed() != "" {
+		err = m.watchConfig(viper.ConfigFileUsed())
 		if err != nil {
-			m.loggerHTTPFunc().Error(err)
+			return err
 		}
-		err = ln.Close()
+	}
+	if m.certs != nil {
+		err = m.certs.Watch(m.drainCtx, m.loggerHTTPFunc)
 		if err != nil {
-			m.loggerHTTPFunc().Error(err)
+			return err
 		}
-		err = l.Close()
+	}
+	err = m.serve(&m.httpServer, basicConf.MaxConns)
+	if err != nil {
+		return err
+	}
+	if m.adminServer != nil {
+		err = m.serve(m.adminServer, basicConf.MaxConns)
 		if err != nil {
-			m.loggerHTTPFunc().Error(err)
+			return err
 		}
-		m.loggerHTTPFunc().Info("stop http server")
-	}()
-	return m.postStart()
+	}
+	err = m.postStart()
+	if err != nil {
+		return err
+	}
+	m.started.Store(true)
+	return nil
 }
 
 func (m *Server) postStart() error {
@@ -758,78 +861,82 @@ func (m *Server) postStart() error {
 	return nil
 }
 
-func (m *Server) preStop() error {
+func (m *Server) preStop(sd *shutdown) {
 	basicConf := conf.GetBasicConfig()
 	if basicConf.IsDynamicConfig {
-		doP := vo.DeregisterInstanceParam{
-			Ip:          m.PrivateIP.String(), //required
-			Port:        80,                   //required
-			Ephemeral:   true,
-			ServiceName: basicConf.Service,   //required
-			GroupName:   PlatformConfigGroup, //optional,default:DEFAULT_GROUP
-			Cluster:     m.PrivateCluster,    //optional,default:DEFAULT
-		}
-		err := m.nacosClient.Deregister(doP)
-		if err != nil {
-			spew.Dump(doP)
-			return err
-		}
+		sd.run(m.ctx, "nacos/Deregister", func(ctx context.Context) error {
+			_ = ctx
+			doP := vo.DeregisterInstanceParam{
+				Ip:          m.PrivateIP.String(), //required
+				Port:        80,                   //required
+				Ephemeral:   true,
+				ServiceName: basicConf.Service,   //required
+				GroupName:   PlatformConfigGroup, //optional,default:DEFAULT_GROUP
+				Cluster:     m.PrivateCluster,    //optional,default:DEFAULT
+			}
+			err := m.nacosClient.Deregister(doP)
+			if err != nil {
+				spew.Dump(doP)
+				return err
+			}
 
-		doG := vo.DeregisterInstanceParam{
-			Ip:          m.GlobalIP.String(), //required
-			Port:        80,                  //required
-			Ephemeral:   true,
-			ServiceName: basicConf.Service,   //required
-			GroupName:   PlatformConfigGroup, //optional,default:DEFAULT_GROUP
-			Cluster:     m.GlobalCluster,     //optional,default:DEFAULT
-		}
-		err = m.nacosClient.Deregister(doG)
-		if err != nil {
-			spew.Dump(doG)
-			return err
-		}
+			doG := vo.DeregisterInstanceParam{
+				Ip:          m.GlobalIP.String(), //required
+				Port:        80,                  //required
+				Ephemeral:   true,
+				ServiceName: basicConf.Service,   //required
+				GroupName:   PlatformConfigGroup, //optional,default:DEFAULT_GROUP
+				Cluster:     m.GlobalCluster,     //optional,default:DEFAULT
+			}
+			err = m.nacosClient.Deregister(doG)
+			if err != nil {
+				spew.Dump(doG)
+				return err
+			}
+			return nil
+		})
 	}
 
-	// Stop the service gracefully.
-	err := m.httpServer.Shutdown(context.TODO())
-	if err != nil {
-		return err
-	}
+	// Stop the service gracefully, drain in-flight requests and websockets.
+	sd.run(m.ctx, "http/Shutdown", m.drain)
 
-	for _, v := range m.components {
-		err := v.PreStop(m.ctx)
-		if err != nil {
-			return err
-		}
+	for i := len(m.components) - 1; i >= 0; i-- {
+		sd.runComponent(m.ctx, m.components[i], "PreStop", m.components[i].PreStop)
 	}
-
-	return nil
 }
 
-// StopServe stop the micro server
+// StopServe stop the micro server within the shutdown budget,
+// every step gets a context with its own deadline, a component whose step exceeds it is reported and abandoned
 func (m *Server) StopServe() error {
-	err := m.preStop()
-	if err != nil {
-		return err
+	basicConf := conf.GetBasicConfig()
+	sd := newShutdown(
+		time.Duration(basicConf.ShutdownTimeout)*time.Millisecond,
+		time.Duration(basicConf.StopTimeout)*time.Millisecond,
+		componentDependencies(m.components),
+	)
+	m.preStop(sd)
+
+	for i := len(m.components) - 1; i >= 0; i-- {
+		sd.runComponent(m.ctx, m.components[i], "Stop", m.components[i].Stop)
 	}
 
-	for _, v := range m.components {
-		err = v.Stop(m.ctx)
-		if err != nil {
-			return err
-		}
+	m.postStop(sd)
+
+	m.shutdownReport = sd.finish()
+	if len(m.shutdownReport.Exceeded) > 0 {
+		m.loggerMicroFunc().Warnw("components exceeded stop deadline", "exceeded", m.shutdownReport.Exceeded)
 	}
-	return m.postStop()
+	if len(m.shutdownReport.Skipped) > 0 {
+		m.loggerMicroFunc().Warnw("shutdown steps skipped", "skipped", m.shutdownReport.Skipped)
+	}
+	m.loggerMicroFunc().Infow("server stopped", "duration", m.shutdownReport.Duration.String())
+	return m.shutdownReport.Err()
 }
 
-func (m *Server) postStop() error {
-	for _, v := range m.components {
-		err := v.PostStop(m.ctx)
-		if err != nil {
-			return err
-		}
+func (m *Server) postStop(sd *shutdown) {
+	for i := len(m.components) - 1; i >= 0; i-- {
+		sd.runComponent(m.ctx, m.components[i], "PostStop", m.components[i].PostStop)
 	}
-	return nil
 }
 
 // Run the micro server until fetch SIGINT or SIGTERM signal
@@ -0,0 +1,321 @@
+package micro
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	platformConf "github.com/kiga-hub/arc/conf"
+	"github.com/kiga-hub/arc/micro/conf"
+	"github.com/kiga-hub/arc/utils"
+)
+
+// RateLimitStoreElementKey is ElementKey for the RateLimitStore shared by replicas, e.g. registered by redis component
+var RateLimitStoreElementKey = ElementKey("RateLimitStore")
+
+// defaultRateLimitPolicy is the name of the policy built from basic config for requests matching no policy
+const defaultRateLimitPolicy = "default"
+
+var rateLimitDenied = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: "echo",
+	Name:      "rate_limit_denied_total",
+	Help:      "Requests denied by rate limit policies.",
+}, []string{"policy", "method", "route"})
+
+func init() {
+	prometheus.MustRegister(rateLimitDenied)
+}
+
+// RateLimitStore counts requests of keys against their quota
+type RateLimitStore interface {
+	// Allow returns whether one more request of the key is allowed, quota.Rate is always positive
+	Allow(key string, quota platformConf.RateLimitQuota) (bool, error)
+}
+
+// MemoryRateLimitStore is a RateLimitStore counting requests in memory with token buckets
+type MemoryRateLimitStore struct {
+	lock        sync.Mutex
+	visitors    map[string]*rateVisitor
+	expiresIn   *atomic.Duration
+	lastCleanup time.Time
+}
+
+type rateVisitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewMemoryRateLimitStore create a MemoryRateLimitStore, the bucket of a key is dropped if it is not used for expiresIn
+func NewMemoryRateLimitStore(expiresIn time.Duration) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		visitors:    map[string]*rateVisitor{},
+		expiresIn:   atomic.NewDuration(expiresIn),
+		lastCleanup: time.Now(),
+	}
+}
+
+// SetExpiresIn changes the expiration of unused buckets
+func (s *MemoryRateLimitStore) SetExpiresIn(expiresIn time.Duration) {
+	s.expiresIn.Store(expiresIn)
+}
+
+// Allow implements RateLimitStore, the bucket of the key keeps its tokens when its quota changes
+func (s *MemoryRateLimitStore) Allow(key string, quota platformConf.RateLimitQuota) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	limit := rate.Limit(quota.Rate)
+	v, ok := s.visitors[key]
+	if !ok {
+		v = &rateVisitor{limiter: rate.NewLimiter(limit, quota.Burst)}
+		s.visitors[key] = v
+	} else {
+		if v.limiter.Limit() != limit {
+			v.limiter.SetLimitAt(now, limit)
+		}
+		if v.limiter.Burst() != quota.Burst {
+			v.limiter.SetBurstAt(now, quota.Burst)
+		}
+	}
+	v.lastSeen = now
+	expiresIn := s.expiresIn.Load()
+	if expiresIn > 0 && now.Sub(s.lastCleanup) > expiresIn {
+		for k, visitor := range s.visitors {
+			if now.Sub(visitor.lastSeen) > expiresIn {
+				delete(s.visitors, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+	return v.limiter.AllowN(now, 1), nil
+}
+
+// rateLimitRules are the policies in effect, sorted by length of path descending
+type rateLimitRules struct {
+	fallback platformConf.RateLimitPolicy
+	policies []platformConf.RateLimitPolicy
+}
+
+func (r *rateLimitRules) match(method, path string) *platformConf.RateLimitPolicy {
+	for i := range r.policies {
+		p := &r.policies[i]
+		if p.Method != "" && !strings.EqualFold(p.Method, method) {
+			continue
+		}
+		if strings.HasPrefix(path, p.Path) {
+			return p
+		}
+	}
+	return &r.fallback
+}
+
+func normalizeRateLimitPolicy(p *platformConf.RateLimitPolicy) error {
+	switch p.Key {
+	case "":
+		p.Key = platformConf.RateLimitKeySensor
+	case platformConf.RateLimitKeySensor, platformConf.RateLimitKeyIP, platformConf.RateLimitKeyRoute:
+	default:
+		return fmt.Errorf("rate limit policy %s has unknown key %s", p.Name, p.Key)
+	}
+	if p.Rate < 0 || p.Burst < 0 {
+		return fmt.Errorf("rate limit policy %s has negative rate or burst", p.Name)
+	}
+	if len(p.Sensors) > 0 {
+		// viper lowercases map keys, so do sensor ids in requests when matching
+		sensors := make(map[string]platformConf.RateLimitQuota, len(p.Sensors))
+		for id, quota := range p.Sensors {
+			if quota.Rate < 0 || quota.Burst < 0 {
+				return fmt.Errorf("rate limit policy %s has negative rate or burst for sensor %s", p.Name, id)
+			}
+			sensors[strings.ToLower(id)] = quota
+		}
+		p.Sensors = sensors
+	}
+	return nil
+}
+
+// RateLimiter limits http requests by the policy matching the route, policies can be updated in runtime
+type RateLimiter struct {
+	store RateLimitStore
+	rules atomic.Value
+}
+
+// NewRateLimiter create a RateLimiter, fallback limits requests matching no policy
+func NewRateLimiter(store RateLimitStore, fallback platformConf.RateLimitPolicy, policies []platformConf.RateLimitPolicy) (*RateLimiter, error) {
+	l := &RateLimiter{store: store}
+	err := l.Update(fallback, policies)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update replaces the policies, counters of keys in the store are kept
+func (l *RateLimiter) Update(fallback platformConf.RateLimitPolicy, policies []platformConf.RateLimitPolicy) error {
+	rules := &rateLimitRules{
+		fallback: fallback,
+		policies: make([]platformConf.RateLimitPolicy, len(policies)),
+	}
+	if rules.fallback.Name == "" {
+		rules.fallback.Name = defaultRateLimitPolicy
+	}
+	err := normalizeRateLimitPolicy(&rules.fallback)
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{rules.fallback.Name: true}
+	for i, p := range policies {
+		if p.Path == "" {
+			return fmt.Errorf("rate limit policy %s has no path", p.Name)
+		}
+		if p.Name == "" {
+			p.Name = p.Path
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicated rate limit policy %s", p.Name)
+		}
+		names[p.Name] = true
+		err = normalizeRateLimitPolicy(&p)
+		if err != nil {
+			return err
+		}
+		rules.policies[i] = p
+	}
+	sort.SliceStable(rules.policies, func(i, j int) bool {
+		return len(rules.policies[i].Path) > len(rules.policies[j].Path)
+	})
+	l.rules.Store(rules)
+	return nil
+}
+
+// Policies returns the policies in effect, the fallback one is the last
+func (l *RateLimiter) Policies() []platformConf.RateLimitPolicy {
+	rules := l.rules.Load().(*rateLimitRules)
+	result := make([]platformConf.RateLimitPolicy, 0, len(rules.policies)+1)
+	result = append(result, rules.policies...)
+	return append(result, rules.fallback)
+}
+
+// Middleware returns the echo middleware, requests are allowed if the store fails
+func (l *RateLimiter) Middleware(skipper middleware.Skipper) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skipper != nil && skipper(c) {
+				return next(c)
+			}
+			// route is empty if no route is found, the path is used to match the policy then
+			route := c.Path()
+			path := route
+			if path == "" {
+				path = c.Request().URL.Path
+			}
+			method := c.Request().Method
+			policy := l.rules.Load().(*rateLimitRules).match(method, path)
+
+			quota := platformConf.RateLimitQuota{Rate: policy.Rate, Burst: policy.Burst}
+			var identifier string
+			switch policy.Key {
+			case platformConf.RateLimitKeyRoute:
+			case platformConf.RateLimitKeyIP:
+				identifier = c.RealIP()
+			default:
+				// use the request parameter sensorid. to limit the read access frequency
+				if identifier = strings.ToLower(c.QueryParam("sensorid")); identifier != "" {
+					if q, ok := policy.Sensors[identifier]; ok {
+						quota = q
+					}
+				} else {
+					// if sensorid is empty.then use the client's ip
+					identifier = c.RealIP()
+				}
+			}
+			if quota.Rate <= 0 {
+				return next(c)
+			}
+			if quota.Burst < 1 {
+				quota.Burst = 1
+			}
+
+			allowed, err := l.store.Allow(policy.Name+":"+identifier, quota)
+			if err != nil || allowed {
+				return next(c)
+			}
+			rateLimitDenied.WithLabelValues(policy.Name, method, route).Inc()
+			return c.JSON(http.StatusTooManyRequests, utils.ResponseV2{
+				Code: http.StatusTooManyRequests,
+				Msg:  http.StatusText(http.StatusTooManyRequests) + ":" + identifier,
+			})
+		}
+	}
+}
+
+// rateLimitPolicies returns the fallback policy from basic config and the policies in effect,
+// policies of dynamic config take precedence over the ones in config file
+func (m *Server) rateLimitPolicies(basicConf *conf.BasicConfig) (platformConf.RateLimitPolicy, []platformConf.RateLimitPolicy, error) {
+	fallback := platformConf.RateLimitPolicy{
+		Name:  defaultRateLimitPolicy,
+		Key:   platformConf.RateLimitKeySensor,
+		Rate:  basicConf.APIRate,
+		Burst: basicConf.APIBurst,
+	}
+	if dynamic := m.dynamicRateLimit.Load(); dynamic != nil {
+		return fallback, dynamic.Policies, nil
+	}
+	rateLimitConf, err := platformConf.GetRateLimitConfig()
+	if err != nil {
+		return fallback, nil, err
+	}
+	return fallback, rateLimitConf.Policies, nil
+}
+
+// newRateLimiter create the rate limiter of http api from config
+func (m *Server) newRateLimiter(basicConf *conf.BasicConfig) (*RateLimiter, error) {
+	rateLimitConf, err := platformConf.GetRateLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+	var store RateLimitStore
+	switch rateLimitConf.Store {
+	case "", platformConf.RateLimitStoreMemory:
+		store = NewMemoryRateLimitStore(time.Duration(basicConf.APIExpiresIn) * time.Second)
+	case platformConf.RateLimitStoreRedis:
+		var ok bool
+		store, ok = m.GetElement(&RateLimitStoreElementKey).(RateLimitStore)
+		if !ok {
+			return nil, fmt.Errorf("rate limit store %s is not registered", rateLimitConf.Store)
+		}
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %s", rateLimitConf.Store)
+	}
+	fallback, policies, err := m.rateLimitPolicies(basicConf)
+	if err != nil {
+		return nil, err
+	}
+	return NewRateLimiter(store, fallback, policies)
+}
+
+// updateRateLimiter applies the current policies to the running rate limiter
+func (m *Server) updateRateLimiter() error {
+	if m.rateLimiter == nil {
+		return nil
+	}
+	basicConf := conf.GetBasicConfig()
+	if s, ok := m.rateLimiter.store.(*MemoryRateLimitStore); ok {
+		s.SetExpiresIn(time.Duration(basicConf.APIExpiresIn) * time.Second)
+	}
+	fallback, policies, err := m.rateLimitPolicies(basicConf)
+	if err != nil {
+		return err
+	}
+	return m.rateLimiter.Update(fallback, policies)
+}
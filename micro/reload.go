@@ -0,0 +1,319 @@
+package micro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	gbytes "github.com/labstack/gommon/bytes"
+	"github.com/spf13/viper"
+	"go.uber.org/atomic"
+
+	platformConf "github.com/kiga-hub/arc/conf"
+	"github.com/kiga-hub/arc/micro/conf"
+	"github.com/kiga-hub/arc/utils"
+)
+
+// reloadableBasicFields are toml keys of basic config which take effect without restart,
+// a change to any other field is rejected when the config file is reloaded
+var reloadableBasicFields = map[string]bool{
+	"apiRate":         true,
+	"apiBurst":        true,
+	"apiExpires":      true,
+	"apiBodyLimit":    true,
+	"apiTimeout":      true,
+	"shutdownTimeout": true,
+	"stopTimeout":     true,
+}
+
+// IConfigReloader is an optional interface of component, which is notified after the config file is reloaded.
+// Viper holds the new content of the file when it is called, so the component can get its own config again.
+// Every component validates the change first, it is applied only if all of them accept it.
+type IConfigReloader interface {
+	// ValidateConfigReload called before anything of the change is applied, return an error to reject the whole change
+	// and keep the running config, ErrNeedRestart only if the service must be restarted to apply it
+	ValidateConfigReload(old, new *conf.BasicConfig) error
+
+	// OnConfigReloaded called when the change is accepted by all components
+	OnConfigReloaded(old, new *conf.BasicConfig) error
+}
+
+// IConfigOwner is an optional interface of component which declares the sections of the config file it reads,
+// a change to them is rejected when the config file is reloaded unless the component is an IConfigReloader
+type IConfigOwner interface {
+	// ConfigSections returns top level keys of the config file, e.g. redis
+	ConfigSections() []string
+}
+
+// swappableMiddleware is a middleware which can be replaced in runtime
+type swappableMiddleware struct {
+	mw atomic.Value
+}
+
+func newSwappableMiddleware(mw echo.MiddlewareFunc) *swappableMiddleware {
+	s := &swappableMiddleware{}
+	s.Store(mw)
+	return s
+}
+
+// Store replaces the middleware, requests in flight keep the old one
+func (s *swappableMiddleware) Store(mw echo.MiddlewareFunc) {
+	s.mw.Store(mw)
+}
+
+// Handle is the echo.MiddlewareFunc delegating to the current middleware
+func (s *swappableMiddleware) Handle(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return s.mw.Load().(echo.MiddlewareFunc)(next)(c)
+	}
+}
+
+func (m *Server) bodyLimitMiddleware(basicConf *conf.BasicConfig) echo.MiddlewareFunc {
+	return middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Limit: basicConf.APIBodyLimit,
+		Skipper: func(ctx echo.Context) bool {
+			if m.APIBodySkipper != nil {
+				uri := ctx.Request().RequestURI
+				return m.APIBodySkipper(uri)
+			}
+			return false
+		},
+	})
+}
+
+func (m *Server) timeoutMiddleware(basicConf *conf.BasicConfig) (echo.MiddlewareFunc, error) {
+	// timeout infortation
+	msgTimeOut, err := json.Marshal(utils.ResponseV2{
+		Code: http.StatusRequestTimeout,
+		Msg:  http.StatusText(http.StatusRequestTimeout),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		Skipper: func(ctx echo.Context) bool {
+			// ignore the verification of websocket
+			if ctx.IsWebSocket() {
+				return true
+			}
+			if m.APITimeOutSkipper != nil {
+				uri := ctx.Request().RequestURI
+				return m.APITimeOutSkipper(uri)
+			}
+			return false
+		},
+		ErrorMessage:               string(msgTimeOut),
+		OnTimeoutRouteErrorHandler: nil,
+		Timeout:                    time.Millisecond * time.Duration(basicConf.APITimeout),
+	}), nil
+}
+
+// changedBasicFields returns toml keys of the fields which differ between old and new basic config
+func changedBasicFields(old, new *conf.BasicConfig) []string {
+	var changed []string
+	ov := reflect.ValueOf(old).Elem()
+	nv := reflect.ValueOf(new).Elem()
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("toml")
+		if key == "" {
+			continue
+		}
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+func checkReloadedBasicConfig(old, new *conf.BasicConfig) error {
+	var restart []string
+	for _, key := range changedBasicFields(old, new) {
+		if !reloadableBasicFields[key] {
+			restart = append(restart, key)
+		}
+	}
+	if len(restart) > 0 {
+		return fmt.Errorf("restart required for changed fields: %s", strings.Join(restart, ", "))
+	}
+	if _, err := gbytes.Parse(new.APIBodyLimit); err != nil {
+		return fmt.Errorf("invalid apiBodyLimit %s: %s", new.APIBodyLimit, err)
+	}
+	if new.APIRate < 0 || new.APIBurst < 0 || new.APITimeout < 0 {
+		return fmt.Errorf("apiRate, apiBurst and apiTimeout can not be negative")
+	}
+	return nil
+}
+
+func checkReloadedRateLimitConfig(old *platformConf.RateLimitConfig) error {
+	rateLimitConf, err := platformConf.GetRateLimitConfig()
+	if err != nil {
+		return err
+	}
+	if rateLimitConf.Store != old.Store {
+		return fmt.Errorf("restart required for changed fields: rateLimit.store")
+	}
+	// validate the policies before they are applied
+	_, err = NewRateLimiter(nil, platformConf.RateLimitPolicy{}, rateLimitConf.Policies)
+	return err
+}
+
+// checkReloadedComponents asks every component to validate the change, returns errors of all components.
+// A changed section of a component which is not an IConfigReloader requires restart.
+func (m *Server) checkReloadedComponents(old, new *conf.BasicConfig, oldSettings map[string]interface{}) []error {
+	var errs []error
+	newSettings := viper.AllSettings()
+	for _, v := range m.components {
+		if r, ok := v.(IConfigReloader); ok {
+			if err := r.ValidateConfigReload(old, new); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", v.Name(), err))
+			}
+			continue
+		}
+		o, ok := v.(IConfigOwner)
+		if !ok {
+			continue
+		}
+		var changed []string
+		for _, section := range o.ConfigSections() {
+			// keys of viper are case insensitive
+			key := strings.ToLower(section)
+			if !reflect.DeepEqual(oldSettings[key], newSettings[key]) {
+				changed = append(changed, section)
+			}
+		}
+		if len(changed) > 0 {
+			errs = append(errs, fmt.Errorf("%s: restart required for changed sections: %s", v.Name(), strings.Join(changed, ", ")))
+		}
+	}
+	return errs
+}
+
+func checkReloadedAuthConfig() (*conf.AuthConfig, error) {
+	authConf, err := conf.GetAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+	// validate the config and the jwks file before it is applied
+	_, err = newAuthenticator(authConf)
+	return authConf, err
+}
+
+// ReloadConfig reads the config file again and applies the changes without restart.
+// The change is rejected as a whole and the last accepted content is kept
+// if a field which requires restart changed, e.g. apiPort, or a component rejects it.
+func (m *Server) ReloadConfig() error {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return fmt.Errorf("no config file used")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return m.reloadConfig(data)
+}
+
+func (m *Server) reloadConfig(data []byte) error {
+	m.reloadLock.Lock()
+	defer m.reloadLock.Unlock()
+	if bytes.Equal(data, m.configData) {
+		return nil
+	}
+
+	old := conf.GetBasicConfig()
+	oldRateLimit, err := platformConf.GetRateLimitConfig()
+	if err != nil {
+		return err
+	}
+	oldSettings := viper.AllSettings()
+	var errs []error
+	var authConf *conf.AuthConfig
+	err = viper.ReadConfig(bytes.NewReader(data))
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		basicConf := conf.GetBasicConfig()
+		errs = append(errs, checkReloadedBasicConfig(old, basicConf), checkReloadedRateLimitConfig(oldRateLimit))
+		authConf, err = checkReloadedAuthConfig()
+		errs = append(errs, err)
+		errs = append(errs, m.checkReloadedComponents(old, basicConf, oldSettings)...)
+	}
+	if err = errors.Join(errs...); err != nil {
+		// roll back to the last accepted content
+		if m.configData != nil {
+			if err0 := viper.ReadConfig(bytes.NewReader(m.configData)); err0 != nil {
+				m.loggerConfigFunc().Error(err0)
+			}
+		}
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	m.configData = data
+	basicConf := conf.GetBasicConfig()
+
+	// the change is validated, so everything gets it even if an earlier one fails to apply it
+	errs = nil
+	if m.bodyLimit != nil && old.APIBodyLimit != basicConf.APIBodyLimit {
+		m.bodyLimit.Store(m.bodyLimitMiddleware(basicConf))
+	}
+	if m.timeout != nil && old.APITimeout != basicConf.APITimeout {
+		mw, err := m.timeoutMiddleware(basicConf)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			m.timeout.Store(mw)
+		}
+	}
+	if m.auth != nil {
+		errs = append(errs, m.auth.Update(authConf))
+	}
+	// policies of dynamic config are not replaced by the file
+	errs = append(errs, m.updateRateLimiter())
+	m.loggerConfigFunc().Infow("config reloaded", "file", viper.ConfigFileUsed(), "changed", changedBasicFields(old, basicConf))
+
+	for _, v := range m.components {
+		r, ok := v.(IConfigReloader)
+		if !ok {
+			continue
+		}
+		if err = r.OnConfigReloaded(old, basicConf); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watchConfig reloads the config file when it is written or replaced until the server begins to stop
+func (m *Server) watchConfig(file string) error {
+	return watchFiles(m.drainCtx, []string{file}, func() {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			m.loggerConfigFunc().Error(err)
+			return
+		}
+		err = m.reloadConfig(data)
+		if err != nil {
+			m.loggerConfigFunc().Error(err)
+			if errors.Is(err, ErrNeedRestart) {
+				m.requestStop()
+			}
+		}
+	}, m.loggerConfigFunc)
+}
+
+// requestStop asks Run to stop the server, it does not block if a stop is requested already
+func (m *Server) requestStop() {
+	select {
+	case m.stopSignal <- syscall.SIGINT:
+	default:
+	}
+}
@@ -0,0 +1,222 @@
+package micro
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ShutdownReport is the report of the last shutdown of micro server
+type ShutdownReport struct {
+	Budget   time.Duration     `json:"budget"`             // total shutdown budget
+	Duration time.Duration     `json:"duration"`           // time spent to shut down
+	Exceeded []string          `json:"exceeded,omitempty"` // steps exceeded their deadline, e.g. Redis/Stop
+	Skipped  []string          `json:"skipped,omitempty"`  // steps not run, the budget is used up or a dependent step is abandoned
+	Errors   map[string]string `json:"errors,omitempty"`   // step - error
+}
+
+// Err returns an error summarizing the failed steps, nil if all steps succeeded in time
+func (r *ShutdownReport) Err() error {
+	if len(r.Exceeded) == 0 && len(r.Skipped) == 0 && len(r.Errors) == 0 {
+		return nil
+	}
+	var msgs []string
+	if len(r.Exceeded) > 0 {
+		msgs = append(msgs, "deadline exceeded: "+strings.Join(r.Exceeded, ", "))
+	}
+	if len(r.Skipped) > 0 {
+		msgs = append(msgs, "skipped: "+strings.Join(r.Skipped, ", "))
+	}
+	steps := make([]string, 0, len(r.Errors))
+	for step := range r.Errors {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	for _, step := range steps {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", step, r.Errors[step]))
+	}
+	return fmt.Errorf("shutdown: %s", strings.Join(msgs, "; "))
+}
+
+// shutdown keeps the budget of a running shutdown, zero budget or step timeout means no limit.
+// A step exceeding its deadline keeps running in background, so the component is abandoned:
+// its later steps and the components it depends on are skipped instead of being stopped under it.
+type shutdown struct {
+	lock         sync.Mutex
+	start        time.Time
+	budget       time.Duration
+	stepTimeout  time.Duration
+	dependencies map[string][]string // component - names of components it depends on
+	abandoned    map[string]bool
+	report       *ShutdownReport
+}
+
+func newShutdown(budget, stepTimeout time.Duration, dependencies map[string][]string) *shutdown {
+	return &shutdown{
+		start:        time.Now(),
+		budget:       budget,
+		stepTimeout:  stepTimeout,
+		dependencies: dependencies,
+		abandoned:    map[string]bool{},
+		report: &ShutdownReport{
+			Budget: budget,
+			Errors: map[string]string{},
+		},
+	}
+}
+
+// stepContext returns a context with deadline of one step, bounded by the remaining budget
+func (s *shutdown) stepContext(parent context.Context) (context.Context, context.CancelFunc) {
+	var deadline time.Time
+	if s.stepTimeout > 0 {
+		deadline = time.Now().Add(s.stepTimeout)
+	}
+	if s.budget > 0 {
+		end := s.start.Add(s.budget)
+		if deadline.IsZero() || deadline.After(end) {
+			deadline = end
+		}
+	}
+	if deadline.IsZero() {
+		return context.WithCancel(parent)
+	}
+	return context.WithDeadline(parent, deadline)
+}
+
+// runComponent runs a step of component c, it is skipped if c is abandoned and c is abandoned if the step exceeds
+func (s *shutdown) runComponent(parent context.Context, c IComponent, phase string, f func(ctx context.Context) error) {
+	name := c.Name()
+	step := name + "/" + phase
+	s.lock.Lock()
+	abandoned := s.abandoned[name]
+	s.lock.Unlock()
+	if abandoned {
+		s.skip(step)
+		return
+	}
+	if !s.run(parent, step, f) {
+		s.abandon(name)
+	}
+}
+
+// run calls f with a step deadline, it returns when f returns or the deadline is exceeded.
+// The step is skipped if its deadline is already exceeded, returns false if f is still running
+func (s *shutdown) run(parent context.Context, step string, f func(ctx context.Context) error) bool {
+	ctx, cancel := s.stepContext(parent)
+	defer cancel()
+	if ctx.Err() != nil {
+		s.skip(step)
+		return true
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- f(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			s.fail(step, err)
+		}
+		return true
+	case <-ctx.Done():
+		s.lock.Lock()
+		s.report.Exceeded = append(s.report.Exceeded, step)
+		s.lock.Unlock()
+		return false
+	}
+}
+
+// abandon marks component name and the components it depends on, directly or not, as abandoned
+func (s *shutdown) abandon(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	pending := []string{name}
+	for len(pending) > 0 {
+		name, pending = pending[0], pending[1:]
+		if s.abandoned[name] {
+			continue
+		}
+		s.abandoned[name] = true
+		pending = append(pending, s.dependencies[name]...)
+	}
+}
+
+func (s *shutdown) skip(step string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.report.Skipped = append(s.report.Skipped, step)
+}
+
+func (s *shutdown) fail(step string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.report.Errors[step] = err.Error()
+}
+
+func (s *shutdown) finish() *ShutdownReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.report.Duration = time.Since(s.start)
+	return s.report
+}
+
+// TrackConnection registers a hijacked connection (e.g. websocket) which http.Server.Shutdown does not wait for.
+// The returned context is done when the server begins to drain connections,
+// release must be called once the connection is closed.
+// A connection tracked after draining begins is not waited for, the returned context is done already.
+func (m *Server) TrackConnection() (context.Context, func()) {
+	m.trackLock.Lock()
+	defer m.trackLock.Unlock()
+	if m.drainCtx.Err() != nil {
+		return m.drainCtx, func() {}
+	}
+	m.hijacked.Add(1)
+	once := sync.Once{}
+	return m.drainCtx, func() {
+		once.Do(m.hijacked.Done)
+	}
+}
+
+// drain shuts down the http server gracefully and waits for tracked connections
+func (m *Server) drain(ctx context.Context) error {
+	// stop tracking new connections before waiting for the tracked ones
+	m.trackLock.Lock()
+	m.drainCancel()
+	m.trackLock.Unlock()
+	err := m.httpServer.Shutdown(ctx)
+	if err != nil {
+		return err
+	}
+	if m.adminServer != nil {
+		err = m.adminServer.Shutdown(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		m.hijacked.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetShutdownReport returns the report of the last shutdown, nil if the server has not been stopped
+func (m *Server) GetShutdownReport() *ShutdownReport {
+	return m.shutdownReport
+}
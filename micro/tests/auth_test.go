@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiga-hub/arc/micro"
+	"github.com/kiga-hub/arc/micro/conf"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"rs","n":"%s","e":"%s"}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, ioutil.WriteFile(jwksFile, []byte(jwks), 0644))
+
+	config := &conf.AuthConfig{
+		Enable: true,
+		APIKeys: []conf.AuthIdentity{
+			{Name: "reader", Key: "reader-key"},
+			{Name: "ops", Key: "ops-key", Scopes: []string{micro.ScopeAdmin}},
+		},
+		JWT: conf.JWTConfig{Secret: "secret", JWKS: jwksFile, Issuer: "arc", ScopeClaim: "scope"},
+	}
+	auth, err := micro.NewAuthMiddleware(config)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	e.Use(auth.Handle())
+	root := echoswagger.New(e, "/swagger", nil)
+	ok := func(c echo.Context) error {
+		return c.String(http.StatusOK, micro.GetIdentity(c).Name)
+	}
+	g := root.Group("Test", "/test")
+	micro.Secure(g.POST("/admin", ok), micro.ScopeAdmin)
+	micro.Secure(g.GET("/read", ok))
+	g.GET("/open", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	do := func(method, target string, header ...string) int {
+		req := httptest.NewRequest(method, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	token := func(method jwt.SigningMethod, key interface{}, kid, scope string) string {
+		tk := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub":   "user",
+			"iss":   "arc",
+			"scope": scope,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		})
+		if kid != "" {
+			tk.Header["kid"] = kid
+		}
+		s, err := tk.SignedString(key)
+		assert.Nil(t, err)
+		return "Bearer " + s
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/test/open"))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/test/read"))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/test/read", "X-API-Key", "wrong"))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/test/read", "X-API-Key", "reader-key"))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/test/admin", "X-API-Key", "reader-key"))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/test/admin", "X-API-Key", "ops-key"))
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/test/admin", "Authorization", token(jwt.SigningMethodHS256, []byte("secret"), "", "read admin")))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/test/admin", "Authorization", token(jwt.SigningMethodHS256, []byte("secret"), "", "read")))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/test/admin", "Authorization", token(jwt.SigningMethodRS256, key, "rs", "admin")))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/test/read", "Authorization", token(jwt.SigningMethodHS256, []byte("wrong"), "", "")))
+	// the public key of a RSA key must not be used as a HMAC secret
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/test/read", "Authorization", token(jwt.SigningMethodHS256, key.N.Bytes(), "rs", "")))
+
+	// undeclared routes require authentication if authenticateAll is set, except public ones
+	config.AuthenticateAll = true
+	assert.Nil(t, auth.Update(config))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/test/open"))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/test/open", "X-API-Key", "reader-key"))
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiga-hub/arc/micro"
+)
+
+var testElementKey = micro.ElementKey("TestElement")
+
+type orderedComponent struct {
+	micro.EmptyComponent
+	name      string
+	deps      []string
+	elemDeps  []*micro.ElementKey
+	provides  []*micro.ElementKey
+	stopOrder *[]string
+}
+
+func (c *orderedComponent) Name() string                           { return c.name }
+func (c *orderedComponent) DependsOn() []string                    { return c.deps }
+func (c *orderedComponent) DependsOnElements() []*micro.ElementKey { return c.elemDeps }
+func (c *orderedComponent) ProvidesElements() []*micro.ElementKey  { return c.provides }
+func (c *orderedComponent) Stop(ctx context.Context) error {
+	_ = ctx
+	*c.stopOrder = append(*c.stopOrder, c.name)
+	return nil
+}
+
+func TestDependencyOrder(t *testing.T) {
+	var stopOrder []string
+	components := []micro.IComponent{
+		&orderedComponent{name: "tracing", deps: []string{"logging"}, stopOrder: &stopOrder},
+		&orderedComponent{name: "consumer", elemDeps: []*micro.ElementKey{&testElementKey}, stopOrder: &stopOrder},
+		&orderedComponent{name: "provider", provides: []*micro.ElementKey{&testElementKey}, stopOrder: &stopOrder},
+		&orderedComponent{name: "logging", stopOrder: &stopOrder},
+	}
+	s, err := micro.NewServer("test", "v1.0.0", components)
+	assert.Nil(t, err)
+
+	err = s.StopServe()
+	assert.Nil(t, err)
+	// started as provider, consumer, logging, tracing and stopped in reverse order
+	assert.Equal(t, []string{"tracing", "logging", "consumer", "provider"}, stopOrder)
+}
+
+func TestDependencyCycle(t *testing.T) {
+	components := []micro.IComponent{
+		&orderedComponent{name: "a", deps: []string{"b"}},
+		&orderedComponent{name: "b", deps: []string{"c"}},
+		&orderedComponent{name: "c", deps: []string{"a"}},
+	}
+	_, err := micro.NewServer("test", "v1.0.0", components)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle")
+}
+
+func TestDependencyMissing(t *testing.T) {
+	components := []micro.IComponent{
+		&orderedComponent{name: "a", deps: []string{"unknown"}},
+	}
+	_, err := micro.NewServer("test", "v1.0.0", components)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "missing component unknown")
+}
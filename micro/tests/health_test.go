@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc/micro"
+)
+
+func TestProbeBeforeStart(t *testing.T) {
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{&micro.EmptyComponent{}})
+	assert.Nil(t, err)
+
+	// not started yet, alive but not ready
+	assert.True(t, s.GetLiveness().IsOK)
+	readiness := s.GetReadiness()
+	assert.False(t, readiness.IsOK)
+	assert.Equal(t, "starting", readiness.Reason)
+}
+
+type healthComponent struct {
+	micro.EmptyComponent
+	checks []micro.HealthCheck
+}
+
+func (c *healthComponent) Name() string { return "dep" }
+
+func (c *healthComponent) HealthChecks() []micro.HealthCheck { return c.checks }
+
+// startHealthServer starts a server of the checks on a free port, checks run once unless interval is set
+func startHealthServer(t *testing.T, checks ...micro.HealthCheck) (*micro.Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	assert.Nil(t, l.Close())
+	viper.Set("basic.apiPort", port)
+	viper.Set("basic.healthInterval", int(time.Hour/time.Millisecond))
+	// values set are not overridden by a config file read later, so they are dropped
+	t.Cleanup(viper.Reset)
+
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{&healthComponent{checks: checks}})
+	assert.Nil(t, err)
+	assert.Nil(t, s.StartServe())
+	t.Cleanup(func() { _ = s.StopServe() })
+	// wait for the first round of checks
+	assert.Eventually(t, func() bool {
+		return len(s.GetStatus().Checks) == len(checks)
+	}, time.Second, 5*time.Millisecond)
+	return s, fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+func getProbe(t *testing.T, url string) (int, *micro.Probe) {
+	resp, err := http.Get(url)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	p := &micro.Probe{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(p))
+	return resp.StatusCode, p
+}
+
+func TestHealthChecks(t *testing.T) {
+	calls := atomic.NewInt32(0)
+	s, base := startHealthServer(t,
+		micro.HealthCheck{Name: "db", Check: func(context.Context) error {
+			calls.Inc()
+			return nil
+		}},
+		micro.HealthCheck{Name: "slow", Timeout: 20 * time.Millisecond, Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(time.Second)
+			return nil
+		}},
+		micro.HealthCheck{Name: "cache", Level: micro.HealthLevelWarning, Check: func(context.Context) error {
+			return fmt.Errorf("cache down")
+		}},
+	)
+
+	// a critical check exceeding its timeout makes the service not ready, the warning one does not count
+	code, readiness := getProbe(t, base+"/health/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, readiness.IsOK)
+	assert.Contains(t, readiness.Reason, "dep/slow: check timeout after 20ms")
+	assert.True(t, readiness.Checks["dep/db"].IsOK)
+	assert.NotContains(t, readiness.Checks, "dep/cache")
+
+	// warnings are only reported in status, the service is live without fatal checks
+	code, liveness := getProbe(t, base+"/health/live")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, liveness.IsOK)
+	checks := s.GetStatus().Checks
+	assert.False(t, checks["dep/cache"].IsOK)
+	assert.Equal(t, micro.HealthLevelWarning, checks["dep/cache"].Level)
+	assert.Equal(t, "cache down", checks["dep/cache"].Error)
+
+	// probes are served from the cached results
+	for i := 0; i < 10; i++ {
+		s.GetReadiness()
+		getProbe(t, base+"/health/ready")
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestHealthReady(t *testing.T) {
+	_, base := startHealthServer(t,
+		micro.HealthCheck{Name: "db", Check: func(context.Context) error { return nil }},
+		micro.HealthCheck{Name: "cache", Level: micro.HealthLevelWarning, Check: func(context.Context) error {
+			return fmt.Errorf("cache down")
+		}},
+	)
+	code, readiness := getProbe(t, base+"/health/ready")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, readiness.IsOK)
+}
+
+func TestHealthFatal(t *testing.T) {
+	_, base := startHealthServer(t,
+		micro.HealthCheck{Name: "disk", Level: micro.HealthLevelFatal, Check: func(context.Context) error {
+			return fmt.Errorf("disk full")
+		}},
+	)
+	code, liveness := getProbe(t, base+"/health/live")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "dep/disk: disk full", liveness.Reason)
+	code, _ = getProbe(t, base+"/health/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	platformConf "github.com/kiga-hub/arc/conf"
+	"github.com/kiga-hub/arc/micro"
+)
+
+func TestRateLimiter(t *testing.T) {
+	fallback := platformConf.RateLimitPolicy{Rate: 0.001, Burst: 1}
+	policies := []platformConf.RateLimitPolicy{
+		{
+			Name:    "ingest",
+			Path:    "/api/ingest",
+			Rate:    0.001,
+			Burst:   2,
+			Sensors: map[string]platformConf.RateLimitQuota{"A1B2C3": {Rate: 0.001, Burst: 3}},
+		},
+		{Name: "query", Path: "/api/query", Key: platformConf.RateLimitKeyRoute, Rate: 0.001, Burst: 1},
+	}
+	limiter, err := micro.NewRateLimiter(micro.NewMemoryRateLimitStore(time.Minute), fallback, policies)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	e.Use(limiter.Middleware(nil))
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "OK") }
+	e.GET("/api/ingest/data", ok)
+	e.GET("/api/query/data", ok)
+	e.GET("/other", ok)
+
+	allowed := func(target string) int {
+		n := 0
+		for i := 0; i < 5; i++ {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			if rec.Code == http.StatusOK {
+				n++
+			} else {
+				assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+			}
+		}
+		return n
+	}
+
+	assert.Equal(t, 2, allowed("/api/ingest/data?sensorid=000001"))
+	// each sensor has its own bucket, and a sensor may have its own quota
+	assert.Equal(t, 2, allowed("/api/ingest/data?sensorid=000002"))
+	assert.Equal(t, 3, allowed("/api/ingest/data?sensorid=a1b2c3"))
+	// all requests of a route policy share one bucket
+	assert.Equal(t, 1, allowed("/api/query/data?sensorid=000001")+allowed("/api/query/data?sensorid=000002"))
+	assert.Equal(t, 1, allowed("/other"))
+
+	// live update keeps the spent tokens of buckets and applies the new quota
+	policies[0].Burst = 4
+	assert.Nil(t, limiter.Update(fallback, policies))
+	assert.Equal(t, 0, allowed("/api/ingest/data?sensorid=000001"))
+	assert.Equal(t, 4, allowed("/api/ingest/data?sensorid=000003"))
+
+	policies[1].Key = "unknown"
+	assert.NotNil(t, limiter.Update(fallback, policies))
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiga-hub/arc/micro"
+	"github.com/kiga-hub/arc/micro/conf"
+)
+
+type reloadComponent struct {
+	micro.EmptyComponent
+	name     string
+	reject   bool
+	reloaded []*conf.BasicConfig
+}
+
+func (c *reloadComponent) Name() string {
+	if c.name == "" {
+		return "reload"
+	}
+	return c.name
+}
+
+func (c *reloadComponent) ValidateConfigReload(old, new *conf.BasicConfig) error {
+	_, _ = old, new
+	if c.reject && viper.GetBool("test.reject") {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func (c *reloadComponent) OnConfigReloaded(old, new *conf.BasicConfig) error {
+	_ = old
+	c.reloaded = append(c.reloaded, new)
+	return nil
+}
+
+func TestReloadConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.toml")
+	write := func(content string) {
+		assert.Nil(t, ioutil.WriteFile(file, []byte(content), 0644))
+	}
+	write("[basic]\napiPort = 8080\napiRate = 100.0\n")
+	conf.SetDefaultBasicConfig()
+	viper.SetConfigType("toml")
+	viper.SetConfigFile(file)
+	assert.Nil(t, viper.ReadInConfig())
+	defer viper.Reset()
+
+	c := &reloadComponent{}
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{c})
+	assert.Nil(t, err)
+	// the first reload takes the file as it is
+	assert.Nil(t, s.ReloadConfig())
+
+	write("[basic]\napiPort = 8080\napiRate = 50.0\n")
+	assert.Nil(t, s.ReloadConfig())
+	assert.Equal(t, 50.0, conf.GetBasicConfig().APIRate)
+	assert.Equal(t, 50.0, c.reloaded[len(c.reloaded)-1].APIRate)
+
+	// apiPort requires restart, the whole change is rejected
+	write("[basic]\napiPort = 9090\napiRate = 10.0\n")
+	err = s.ReloadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "apiPort")
+	assert.True(t, errors.Is(err, micro.ErrRejected))
+	assert.False(t, errors.Is(err, micro.ErrNeedRestart))
+	assert.Equal(t, 8080, conf.GetBasicConfig().APIPort)
+	assert.Equal(t, 50.0, conf.GetBasicConfig().APIRate)
+
+	// an invalid body limit is rejected as well
+	write("[basic]\napiPort = 8080\napiBodyLimit = \"10XB\"\n")
+	assert.NotNil(t, s.ReloadConfig())
+	assert.Equal(t, 50.0, conf.GetBasicConfig().APIRate)
+}
+
+// ownerComponent owns a section of the config file but can not reload it
+type ownerComponent struct {
+	micro.EmptyComponent
+}
+
+func (c *ownerComponent) Name() string { return "owner" }
+
+func (c *ownerComponent) ConfigSections() []string { return []string{"owner"} }
+
+func TestReloadConfigValidate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.toml")
+	write := func(content string) {
+		assert.Nil(t, ioutil.WriteFile(file, []byte(content), 0644))
+	}
+	write("[basic]\napiRate = 100.0\n[owner]\nhost = \"a\"\n")
+	conf.SetDefaultBasicConfig()
+	viper.SetConfigType("toml")
+	viper.SetConfigFile(file)
+	assert.Nil(t, viper.ReadInConfig())
+	defer viper.Reset()
+
+	first := &reloadComponent{name: "first", reject: true}
+	second := &reloadComponent{name: "second", reject: true}
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{first, &ownerComponent{}, second})
+	assert.Nil(t, err)
+	assert.Nil(t, s.ReloadConfig())
+	assert.Len(t, first.reloaded, 1)
+
+	// both components reject the change, nothing is applied and both errors are reported
+	write("[basic]\napiRate = 50.0\n[owner]\nhost = \"a\"\n[test]\nreject = true\n")
+	err = s.ReloadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "first: rejected")
+	assert.Contains(t, err.Error(), "second: rejected")
+	assert.Equal(t, 100.0, conf.GetBasicConfig().APIRate)
+	assert.Len(t, first.reloaded, 1)
+	assert.Len(t, second.reloaded, 1)
+
+	// a section of a component which can not reload it requires restart
+	write("[basic]\napiRate = 50.0\n[owner]\nhost = \"b\"\n")
+	err = s.ReloadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "owner: restart required for changed sections: owner")
+	assert.Equal(t, 100.0, conf.GetBasicConfig().APIRate)
+	assert.Equal(t, "a", viper.GetString("owner.host"))
+
+	write("[basic]\napiRate = 50.0\n[owner]\nhost = \"a\"\n")
+	assert.Nil(t, s.ReloadConfig())
+	assert.Equal(t, 50.0, conf.GetBasicConfig().APIRate)
+	assert.Len(t, first.reloaded, 2)
+	assert.Len(t, second.reloaded, 2)
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiga-hub/arc/micro"
+)
+
+type slowComponent struct {
+	micro.EmptyComponent
+	name    string
+	delay   time.Duration
+	deps    []string
+	stopped bool
+}
+
+func (c *slowComponent) Name() string { return c.name }
+
+func (c *slowComponent) DependsOn() []string { return c.deps }
+
+func (c *slowComponent) Stop(ctx context.Context) error {
+	select {
+	case <-time.After(c.delay):
+		c.stopped = true
+	case <-ctx.Done():
+		<-time.After(c.delay)
+	}
+	return nil
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	viper.Set("basic.shutdownTimeout", 2000)
+	viper.Set("basic.stopTimeout", 50)
+	defer func() {
+		viper.Set("basic.shutdownTimeout", 0)
+		viper.Set("basic.stopTimeout", 0)
+	}()
+
+	fast := &slowComponent{name: "fast", delay: time.Millisecond}
+	slow := &slowComponent{name: "slow", delay: time.Second}
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{fast, slow})
+	assert.Nil(t, err)
+
+	start := time.Now()
+	err = s.StopServe()
+	assert.NotNil(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	report := s.GetShutdownReport()
+	assert.NotNil(t, report)
+	assert.Equal(t, []string{"slow/Stop"}, report.Exceeded)
+	assert.Equal(t, []string{"slow/PostStop"}, report.Skipped)
+	assert.True(t, fast.stopped)
+	assert.False(t, slow.stopped)
+}
+
+func TestShutdownAbandoned(t *testing.T) {
+	viper.Set("basic.shutdownTimeout", 2000)
+	viper.Set("basic.stopTimeout", 50)
+	defer func() {
+		viper.Set("basic.shutdownTimeout", 0)
+		viper.Set("basic.stopTimeout", 0)
+	}()
+
+	base := &slowComponent{name: "base", delay: time.Millisecond}
+	other := &slowComponent{name: "other", delay: time.Millisecond}
+	user := &slowComponent{name: "user", delay: time.Second, deps: []string{"base"}}
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{base, other, user})
+	assert.Nil(t, err)
+
+	err = s.StopServe()
+	assert.NotNil(t, err)
+
+	// base is not stopped while Stop of user is still running
+	report := s.GetShutdownReport()
+	assert.Equal(t, []string{"user/Stop"}, report.Exceeded)
+	assert.Equal(t, []string{"base/Stop", "user/PostStop", "base/PostStop"}, report.Skipped)
+	assert.True(t, other.stopped)
+	assert.False(t, base.stopped)
+}
+
+func TestShutdownBudgetUsedUp(t *testing.T) {
+	viper.Set("basic.shutdownTimeout", 50)
+	defer viper.Set("basic.shutdownTimeout", 0)
+
+	fast := &slowComponent{name: "fast", delay: time.Millisecond}
+	slow := &slowComponent{name: "slow", delay: time.Second}
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{fast, slow})
+	assert.Nil(t, err)
+
+	err = s.StopServe()
+	assert.NotNil(t, err)
+
+	report := s.GetShutdownReport()
+	assert.Equal(t, []string{"slow/Stop"}, report.Exceeded)
+	assert.Equal(t, []string{"fast/Stop", "slow/PostStop", "fast/PostStop"}, report.Skipped)
+	assert.False(t, fast.stopped)
+}
+
+func TestTrackConnectionAfterDrain(t *testing.T) {
+	s, err := micro.NewServer("test", "v1.0.0", []micro.IComponent{})
+	assert.Nil(t, err)
+
+	ctx, release := s.TrackConnection()
+	assert.Nil(t, ctx.Err())
+	go func() {
+		<-ctx.Done()
+		release()
+	}()
+	assert.Nil(t, s.StopServe())
+
+	// connections tracked once draining began are not waited for
+	ctx, release = s.TrackConnection()
+	assert.NotNil(t, ctx.Err())
+	release()
+}
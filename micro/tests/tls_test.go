@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiga-hub/arc/micro"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	assert.Nil(t, ioutil.WriteFile(certFile, c.pem, 0644))
+	der, err := x509.MarshalECPrivateKey(c.key)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := newTestCert(t, "ca", nil, true)
+	assert.Nil(t, ioutil.WriteFile(caFile, ca.pem, 0644))
+	newTestCert(t, "server-1", ca, false).write(t, certFile, keyFile)
+
+	reloader, err := micro.NewCertReloader(certFile, keyFile, caFile)
+	assert.Nil(t, err)
+	config, err := reloader.TLSConfig(micro.TLSClientAuthRequire)
+	assert.Nil(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}))
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(client *testCert) (string, error) {
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if client != nil {
+			tlsConfig.Certificates = []tls.Certificate{client.tlsCertificate()}
+		}
+		var serverName string
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			serverName = cs.PeerCertificates[0].Subject.CommonName
+			return nil
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := c.Get(server.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return serverName + "/" + string(body), nil
+	}
+
+	client := newTestCert(t, "collector", ca, false)
+	result, err := get(client)
+	assert.Nil(t, err)
+	assert.Equal(t, "server-1/collector", result)
+
+	// a client certificate is required
+	_, err = get(nil)
+	assert.NotNil(t, err)
+
+	// new connections get the reloaded certificate
+	newTestCert(t, "server-2", ca, false).write(t, certFile, keyFile)
+	assert.Nil(t, reloader.Reload())
+	result, err = get(client)
+	assert.Nil(t, err)
+	assert.Equal(t, "server-2/collector", result)
+
+	// the loaded certificate is kept if the files are broken
+	assert.Nil(t, ioutil.WriteFile(keyFile, []byte("broken"), 0600))
+	assert.NotNil(t, reloader.Reload())
+	result, err = get(client)
+	assert.Nil(t, err)
+	assert.Equal(t, "server-2/collector", result)
+}
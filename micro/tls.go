@@ -0,0 +1,142 @@
+package micro
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/micro/conf"
+)
+
+const (
+	// TLSClientAuthRequire requires a client certificate signed by the client CA
+	TLSClientAuthRequire = "require"
+	// TLSClientAuthVerify verifies the client certificate if it is given, e.g. when api keys are accepted as well
+	TLSClientAuthVerify = "verify"
+)
+
+// certificates is a certificate with its client CA pool
+type certificates struct {
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// CertReloader keeps the server certificate and client CA loaded from files, they can be reloaded without restart
+type CertReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	current      atomic.Value
+}
+
+// NewCertReloader create a CertReloader and loads the files, clientCAFile is optional
+func NewCertReloader(certFile, keyFile, clientCAFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the files again, the loaded ones are kept if it fails
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	certs := &certificates{cert: &cert}
+	if r.clientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.clientCAFile)
+		if err != nil {
+			return err
+		}
+		certs.clientCAs = x509.NewCertPool()
+		if !certs.clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in %s", r.clientCAFile)
+		}
+	}
+	r.current.Store(certs)
+	return nil
+}
+
+// Watch reloads the files when they change until ctx is done
+func (r *CertReloader) Watch(ctx context.Context, logger func() logging.ILogger) error {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	return watchFiles(ctx, files, func() {
+		err := r.Reload()
+		if err != nil {
+			logger().Errorw("reload certificates", "error", err)
+			return
+		}
+		logger().Infow("certificates reloaded", "cert", r.certFile)
+	}, logger)
+}
+
+// TLSConfig returns the server tls config using the latest loaded files,
+// client certificates are verified against the client CA if it is set
+func (r *CertReloader) TLSConfig(clientAuth string) (*tls.Config, error) {
+	clientAuthType := tls.NoClientCert
+	if r.clientCAFile != "" {
+		switch clientAuth {
+		case "", TLSClientAuthRequire:
+			clientAuthType = tls.RequireAndVerifyClientCert
+		case TLSClientAuthVerify:
+			clientAuthType = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("unknown tls client auth %s", clientAuth)
+		}
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.current.Load().(*certificates).cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certs := r.current.Load().(*certificates)
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*certs.cert},
+				ClientAuth:   clientAuthType,
+				ClientCAs:    certs.clientCAs,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}, nil
+}
+
+// listenAddress returns the network and address to listen on for http api of the port
+func listenAddress(basicConf *conf.BasicConfig, port int) (string, string, error) {
+	host := basicConf.APIHost
+	switch basicConf.Network {
+	case "", "tcp4":
+		if host == "" {
+			host = "0.0.0.0"
+		}
+		return "tcp4", net.JoinHostPort(host, strconv.Itoa(port)), nil
+	case "tcp6":
+		if host == "" {
+			host = "::"
+		}
+		return "tcp6", net.JoinHostPort(host, strconv.Itoa(port)), nil
+	case "tcp":
+		// dual-stack if host is empty
+		return "tcp", net.JoinHostPort(host, strconv.Itoa(port)), nil
+	default:
+		return "", "", fmt.Errorf("unknown network %s", basicConf.Network)
+	}
+}
@@ -0,0 +1,75 @@
+package micro
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+// fileChangeDelay merges the burst of file events produced by one save of an editor
+const fileChangeDelay = 200 * time.Millisecond
+
+// watchFiles calls onChange when any of the files is written or replaced until ctx is done
+func watchFiles(ctx context.Context, files []string, onChange func(), logger func() logging.ILogger) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// watch the directories, since a file may be replaced, e.g. k8s configmap swaps a symlink
+	realFiles := map[string]string{}
+	dirs := map[string]bool{}
+	for _, file := range files {
+		file = filepath.Clean(file)
+		realFiles[file], _ = filepath.EvalSymlinks(file)
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		err = watcher.Add(dir)
+		if err != nil {
+			_ = watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer func() {
+			_ = watcher.Close()
+		}()
+		timer := time.NewTimer(fileChangeDelay)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				for file, realFile := range realFiles {
+					current, _ := filepath.EvalSymlinks(file)
+					written := name == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+					if written || (current != "" && current != realFile) {
+						realFiles[file] = current
+						timer.Reset(fileChangeDelay)
+					}
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger().Error(err)
+			case <-timer.C:
+				onChange()
+			}
+		}
+	}()
+	return nil
+}
@@ -22,6 +22,16 @@ func (c *MongoComponent) Name() string {
 	return "Mongo"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *MongoComponent) ConfigSections() []string {
+	return []string{"mongo"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *MongoComponent) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey}
+}
+
 // PreInit called before Init()
 func (c *MongoComponent) PreInit(ctx context.Context) error {
 	_ = ctx
@@ -45,6 +55,19 @@ func (c *MongoComponent) Init(server *micro.Server) error {
 	return nil
 }
 
+// HealthChecks returns checks of the component, called after Init()
+func (c *MongoComponent) HealthChecks() []micro.HealthCheck {
+	return []micro.HealthCheck{
+		{
+			Name:  "ping",
+			Level: micro.HealthLevelCritical,
+			Check: func(ctx context.Context) error {
+				return c.db.Client().Ping(ctx, nil)
+			},
+		},
+	}
+}
+
 // PostStop called after Stop()
 func (c *MongoComponent) PostStop(ctx context.Context) error {
 	return c.db.Client().Disconnect(ctx)
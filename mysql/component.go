@@ -22,6 +22,16 @@ func (c *Component) Name() string {
 	return "Mysql"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"mysql"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey}
+}
+
 // PreInit called before Init()
 func (c *Component) PreInit(ctx context.Context) error {
 	_ = ctx
@@ -44,6 +54,19 @@ func (c *Component) Init(server *micro.Server) error {
 	return nil
 }
 
+// HealthChecks returns checks of the component, called after Init()
+func (c *Component) HealthChecks() []micro.HealthCheck {
+	return []micro.HealthCheck{
+		{
+			Name:  "ping",
+			Level: micro.HealthLevelCritical,
+			Check: func(ctx context.Context) error {
+				return CheckDBContext(ctx, c.db)
+			},
+		},
+	}
+}
+
 // PostStop called after Stop()
 func (c *Component) PostStop(ctx context.Context) error {
 	_ = ctx
@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/kiga-hub/arc/leadelection"
+)
+
+// LeaseRecord is a row of a leader election record, Version is increased by each write
+type LeaseRecord struct {
+	Name                 string `gorm:"primary_key;size:191"`
+	HolderIdentity       string `gorm:"size:255"`
+	LeaseDurationSeconds int
+	AcquireTime          int64
+	RenewTime            int64
+	LeaderTransitions    int
+	Version              int64
+}
+
+// TableName of LeaseRecord
+func (LeaseRecord) TableName() string {
+	return "leader_election_lease"
+}
+
+// LeaseLock is a leadelection.ILock in a table, a row is updated only if its version is the observed one
+type LeaseLock struct {
+	db       *gorm.DB
+	name     string
+	identity string
+
+	lock     sync.Mutex
+	observed int64 // the version last read or written, 0 if none
+}
+
+// NewLeaseLock create a LeaseLock of the record of name, the table is created if not exists
+func NewLeaseLock(db *gorm.DB, name, identity string) (*LeaseLock, error) {
+	if err := db.AutoMigrate(&LeaseRecord{}).Error; err != nil {
+		return nil, err
+	}
+	return &LeaseLock{
+		db:       db,
+		name:     name,
+		identity: identity,
+	}, nil
+}
+
+// Get returns the LeaderElectionRecord
+func (l *LeaseLock) Get(ctx context.Context) (*leadelection.LeaderElectionRecord, []byte, error) {
+	_ = ctx
+	var row LeaseRecord
+	if err := l.db.Where("name = ?", l.name).First(&row).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			l.setObserved(0)
+			return nil, nil, leadelection.ErrNotFound
+		}
+		return nil, nil, err
+	}
+	// the version is in the raw record, so every write is seen as a change
+	raw, err := json.Marshal(row)
+	if err != nil {
+		return nil, nil, err
+	}
+	l.setObserved(row.Version)
+	return &leadelection.LeaderElectionRecord{
+		HolderIdentity:       row.HolderIdentity,
+		LeaseDurationSeconds: row.LeaseDurationSeconds,
+		AcquireTime:          row.AcquireTime,
+		RenewTime:            row.RenewTime,
+		LeaderTransitions:    row.LeaderTransitions,
+	}, raw, nil
+}
+
+// Create attempts to create a LeaderElectionRecord
+func (l *LeaseLock) Create(ctx context.Context, ler leadelection.LeaderElectionRecord) error {
+	_ = ctx
+	row := LeaseRecord{
+		Name:                 l.name,
+		HolderIdentity:       ler.HolderIdentity,
+		LeaseDurationSeconds: ler.LeaseDurationSeconds,
+		AcquireTime:          ler.AcquireTime,
+		RenewTime:            ler.RenewTime,
+		LeaderTransitions:    ler.LeaderTransitions,
+		Version:              1,
+	}
+	if err := l.db.Create(&row).Error; err != nil {
+		// a duplicate key, unless the row is still missing
+		var count int
+		if l.db.Model(&LeaseRecord{}).Where("name = ?", l.name).Count(&count).Error == nil && count > 0 {
+			return leadelection.ErrConflict
+		}
+		return err
+	}
+	l.setObserved(row.Version)
+	return nil
+}
+
+// Update will update and existing LeaderElectionRecord
+func (l *LeaseLock) Update(ctx context.Context, ler leadelection.LeaderElectionRecord) error {
+	_ = ctx
+	l.lock.Lock()
+	observed := l.observed
+	l.lock.Unlock()
+	if observed == 0 {
+		return leadelection.ErrConflict
+	}
+	// a map so zero values, e.g. the empty identity of a released lease, are written
+	result := l.db.Model(&LeaseRecord{}).Where("name = ? AND version = ?", l.name, observed).Updates(map[string]interface{}{
+		"holder_identity":        ler.HolderIdentity,
+		"lease_duration_seconds": ler.LeaseDurationSeconds,
+		"acquire_time":           ler.AcquireTime,
+		"renew_time":             ler.RenewTime,
+		"leader_transitions":     ler.LeaderTransitions,
+		"version":                observed + 1,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return leadelection.ErrConflict
+	}
+	l.setObserved(observed + 1)
+	return nil
+}
+
+func (l *LeaseLock) setObserved(version int64) {
+	l.lock.Lock()
+	l.observed = version
+	l.lock.Unlock()
+}
+
+// RecordEvent is used to record events
+func (l *LeaseLock) RecordEvent(string) {}
+
+// Identity will return the locks Identity
+func (l *LeaseLock) Identity() string {
+	return l.identity
+}
+
+// Describe is used to convert details on current resource lock into a string
+func (l *LeaseLock) Describe() string {
+	return fmt.Sprintf("mysql/%s/%s", LeaseRecord{}.TableName(), l.name)
+}
@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/kiga-hub/arc/leadelection"
+	"github.com/kiga-hub/arc/leadelection/locktest"
+)
+
+// testDSNEnv is the environment variable of the data source name of a mysql database for tests,
+// e.g. root:123456@tcp(127.0.0.1:3306)/test, the tests are skipped if it is not set
+const testDSNEnv = "ARC_TEST_MYSQL_DSN"
+
+func TestLeaseLock(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	locktest.Run(t, func(t *testing.T) func(identity string) leadelection.ILock {
+		db, err := gorm.Open("mysql", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		if err := db.AutoMigrate(&LeaseRecord{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		// every test has its own record, it is removed before and after the test
+		name := t.Name()
+		clean := func() { db.Where("name = ?", name).Delete(&LeaseRecord{}) }
+		clean()
+		t.Cleanup(clean)
+		return func(identity string) leadelection.ILock {
+			l, err := NewLeaseLock(db, name, identity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return l
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"fmt"
 
 	_ "github.com/go-sql-driver/mysql" //_ Import the required drivers
@@ -39,6 +40,14 @@ func CheckDB(db *gorm.DB, config Config) error {
 	return db.DB().Ping()
 }
 
+// CheckDBContext check db, it returns when ctx is done
+func CheckDBContext(ctx context.Context, db *gorm.DB) error {
+	if db == nil {
+		return error2.ErrDbConnection
+	}
+	return db.DB().PingContext(ctx)
+}
+
 // DropDatabase drop db
 //
 //goland:noinspection GoUnusedExportedFunction
@@ -0,0 +1,211 @@
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kiga-hub/arc/protocols"
+)
+
+// maxID is the max collector id of 6 bytes
+const maxID = 1<<48 - 1
+
+// FrameToProto converts a frame to its protobuf message, segments of types other than arc are kept as encoded payloads
+func FrameToProto(f *protocols.Frame) (*Frame, error) {
+	dg, err := DataGroupToProto(&f.DataGroup)
+	if err != nil {
+		return nil, err
+	}
+	p := &Frame{
+		Version:    uint32(f.Version),
+		Id:         f.GetID(),
+		Timestamp:  f.Timestamp,
+		Sequence:   f.Sequence,
+		SampleRate: f.SampleRate,
+		DataGroup:  dg,
+		Crc:        uint32(f.Crc),
+	}
+	for _, e := range f.Extensions {
+		p.Extensions = append(p.Extensions, &Extension{Type: uint32(e.Type), Value: e.Value})
+	}
+	return p, nil
+}
+
+// FrameFromProto converts a protobuf message to a frame, it can be encoded to the same binary frame it was converted from
+func FrameFromProto(p *Frame) (*protocols.Frame, error) {
+	if p.GetVersion() > math.MaxUint8 {
+		return nil, fmt.Errorf("frame version %d out of range", p.GetVersion())
+	}
+	version := byte(p.GetVersion())
+	if version != protocols.FrameV1 && version != protocols.FrameV2 {
+		return nil, &protocols.UnsupportedVersionError{Version: version}
+	}
+	if p.GetId() > maxID {
+		return nil, fmt.Errorf("frame id %d out of 6 bytes", p.GetId())
+	}
+	if p.GetCrc() > math.MaxUint16 {
+		return nil, fmt.Errorf("frame crc %d out of 2 bytes", p.GetCrc())
+	}
+	dg, err := DataGroupFromProto(p.GetDataGroup())
+	if err != nil {
+		return nil, err
+	}
+	f := protocols.NewDefaultFrame().SetVersion(version).SetID(p.GetId())
+	f.Timestamp = p.GetTimestamp()
+	f.Sequence = p.GetSequence()
+	f.SampleRate = p.GetSampleRate()
+	for _, e := range p.GetExtensions() {
+		if e.GetType() > math.MaxUint8 || len(e.GetValue()) > math.MaxUint16 {
+			return nil, fmt.Errorf("frame extension %d out of range", e.GetType())
+		}
+		f.AddExtension(byte(e.GetType()), e.GetValue())
+	}
+	f.SetDataGroup(dg)
+	f.Crc = uint16(p.GetCrc())
+	return f, nil
+}
+
+// DataGroupToProto converts a data group to its protobuf message
+func DataGroupToProto(d *protocols.DataGroup) (*DataGroup, error) {
+	p := &DataGroup{}
+	for _, s := range d.Segments {
+		segment := &Segment{Type: uint32(s.Type())}
+		switch v := s.(type) {
+		case *protocols.SegmentArc:
+			segment.Body = &Segment_Arc{Arc: &SegmentArc{Data: v.Data}}
+		case *protocols.SegmentRaw:
+			segment.Body = &Segment_Raw{Raw: v.Data}
+		default:
+			buf := make([]byte, s.Size())
+			if _, err := s.Encode(buf); err != nil {
+				return nil, fmt.Errorf("encode segment %d: %w", s.Type(), err)
+			}
+			segment.Body = &Segment_Raw{Raw: buf[1:]}
+		}
+		p.Segments = append(p.Segments, segment)
+	}
+	return p, nil
+}
+
+// DataGroupFromProto converts a protobuf message to a data group, raw payloads are decoded by the registered segment types
+func DataGroupFromProto(p *DataGroup) (*protocols.DataGroup, error) {
+	d := protocols.NewDefaultDataGroup()
+	for _, s := range p.GetSegments() {
+		if s.GetType() > math.MaxUint8 {
+			return nil, fmt.Errorf("segment type %d out of range", s.GetType())
+		}
+		sType := byte(s.GetType())
+		switch body := s.GetBody().(type) {
+		case *Segment_Arc:
+			if sType != protocols.STypeArc {
+				return nil, fmt.Errorf("arc segment of type %d", sType)
+			}
+			segment := protocols.NewDefaultSegmentArc()
+			segment.SetData(body.Arc.GetData())
+			d.AppendSegment(segment)
+		case *Segment_Raw:
+			segment := protocols.NewSegment(sType)
+			data := make([]byte, 1+len(body.Raw))
+			data[0] = sType
+			copy(data[1:], body.Raw)
+			if err := segment.Decode(data); err != nil {
+				return nil, fmt.Errorf("decode segment %d: %w", sType, err)
+			}
+			d.AppendSegment(segment)
+		default:
+			return nil, fmt.Errorf("segment %d without body", sType)
+		}
+	}
+	return d, nil
+}
+
+// ArcItemArrayToProto converts an ArcItemArray to its protobuf message
+func ArcItemArrayToProto(a *protocols.ArcItemArray) *ArcItemArray {
+	p := &ArcItemArray{
+		CollectorId:   a.CollectorID,
+		CollectorType: a.CollectorType,
+		Items:         make([]*ArcItem, 0, len(a.Items)),
+	}
+	for _, item := range a.Items {
+		p.Items = append(p.Items, &ArcItem{Ts: item.Ts, Data: item.Data})
+	}
+	return p
+}
+
+// ArcItemArrayFromProto converts a protobuf message to an ArcItemArray
+func ArcItemArrayFromProto(p *ArcItemArray) *protocols.ArcItemArray {
+	a := &protocols.ArcItemArray{
+		CollectorID:   p.GetCollectorId(),
+		CollectorType: p.GetCollectorType(),
+		Items:         make([]protocols.ArcItem, 0, len(p.GetItems())),
+	}
+	for _, item := range p.GetItems() {
+		a.Items = append(a.Items, protocols.ArcItem{Ts: item.GetTs(), Data: item.GetData()})
+	}
+	return a
+}
+
+// CountItemToProto converts a CountItem to its protobuf message
+func CountItemToProto(c *protocols.CountItem) *CountItem {
+	return &CountItem{
+		CollectorId:   c.CollectorID,
+		CollectorType: c.CollectorType,
+		Ts:            c.Ts,
+		Count:         int64(c.Count),
+		Size:          int64(c.Size),
+	}
+}
+
+// CountItemFromProto converts a protobuf message to a CountItem
+func CountItemFromProto(p *CountItem) *protocols.CountItem {
+	return &protocols.CountItem{
+		CollectorID:   p.GetCollectorId(),
+		CollectorType: p.GetCollectorType(),
+		Ts:            p.GetTs(),
+		Count:         int(p.GetCount()),
+		Size:          int(p.GetSize()),
+	}
+}
+
+// MarshalJSON returns the canonical JSON of a message: the proto3 JSON mapping without spaces,
+// bytes are base64 and 64-bit integers are strings
+func MarshalJSON(m proto.Message) ([]byte, error) {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	// protojson output is unstable on purpose, compact it so the same message is always the same JSON
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON parses JSON of the proto3 JSON mapping into m, both camelCase and original field names are accepted
+func UnmarshalJSON(data []byte, m proto.Message) error {
+	return protojson.Unmarshal(data, m)
+}
+
+// FrameToJSON returns the canonical JSON of a frame
+func FrameToJSON(f *protocols.Frame) ([]byte, error) {
+	p, err := FrameToProto(f)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalJSON(p)
+}
+
+// FrameFromJSON parses the JSON of a frame
+func FrameFromJSON(data []byte) (*protocols.Frame, error) {
+	p := &Frame{}
+	if err := UnmarshalJSON(data, p); err != nil {
+		return nil, err
+	}
+	return FrameFromProto(p)
+}
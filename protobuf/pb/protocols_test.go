@@ -0,0 +1,107 @@
+package pb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kiga-hub/arc/protocols"
+)
+
+func encodeFrame(t *testing.T, f *protocols.Frame) []byte {
+	buf := make([]byte, f.Size+protocols.DefaultHeadLength)
+	_, err := f.Encode(buf)
+	assert.Nil(t, err)
+	return buf
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	arc := protocols.NewDefaultSegmentArc()
+	arc.SetData([]byte{1, 2, 3})
+	g := protocols.NewDefaultDataGroup()
+	g.AppendSegment(arc)
+	g.AppendSegment(&protocols.SegmentRaw{SType: 200, Data: []byte{9, 8}})
+
+	for _, version := range protocols.SupportedVersions() {
+		f := protocols.NewDefaultFrame().SetVersion(version).SetID(0xA1B2C3D4E5F6).SetDataGroup(g)
+		f.Timestamp = 1234567
+		if version == protocols.FrameV2 {
+			f.Sequence, f.SampleRate = 7, 51200
+			f.AddExtension(1, []byte("axis-x"))
+		}
+		buf := encodeFrame(t, f)
+		decoded := protocols.NewDefaultFrame()
+		assert.Nil(t, decoded.Decode(buf))
+
+		p, err := FrameToProto(decoded)
+		assert.Nil(t, err)
+		data, err := proto.Marshal(p)
+		assert.Nil(t, err)
+		p2 := &Frame{}
+		assert.Nil(t, proto.Unmarshal(data, p2))
+		f2, err := FrameFromProto(p2)
+		assert.Nil(t, err)
+		assert.Equal(t, decoded.Crc, f2.Crc)
+		assert.Equal(t, buf, encodeFrame(t, f2))
+
+		js, err := FrameToJSON(decoded)
+		assert.Nil(t, err)
+		js2, err := FrameToJSON(f2)
+		assert.Nil(t, err)
+		assert.Equal(t, string(js), string(js2))
+		f3, err := FrameFromJSON(js)
+		assert.Nil(t, err)
+		assert.Equal(t, buf, encodeFrame(t, f3))
+	}
+}
+
+func TestFrameJSON(t *testing.T) {
+	arc := protocols.NewDefaultSegmentArc()
+	arc.SetData([]byte{1, 2, 3})
+	g := protocols.NewDefaultDataGroup()
+	g.AppendSegment(arc)
+	f := protocols.NewDefaultFrame().SetID(15).SetDataGroup(g)
+	f.Timestamp = 100
+	f.Crc = 0x1234
+
+	js, err := FrameToJSON(f)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"version":1,"id":"15","timestamp":"100","dataGroup":{"segments":[{"type":10,"arc":{"data":"AQID"}}]},"crc":4660}`, string(js))
+
+	// original field names are accepted
+	f2, err := FrameFromJSON([]byte(`{"version":1,"id":"15","timestamp":"100","data_group":{"segments":[{"type":10,"arc":{"data":"AQID"}}]}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, f.Size, f2.Size)
+
+	for _, invalid := range []string{
+		`{"version":3}`,
+		`{"version":1,"id":"281474976710656"}`,
+		`{"version":1,"dataGroup":{"segments":[{"type":2,"arc":{}}]}}`,
+		`{"version":1,"dataGroup":{"segments":[{"type":10}]}}`,
+		`{"version":1,"dataGroup":{"segments":[{"type":300,"raw":""}]}}`,
+	} {
+		_, err = FrameFromJSON([]byte(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestItemsRoundTrip(t *testing.T) {
+	a := &protocols.ArcItemArray{
+		CollectorID:   []byte{0, 0, 0, 0, 0, 1},
+		CollectorType: "arc",
+		Items:         []protocols.ArcItem{{Ts: 1, Data: []byte{1}}, {Ts: 2, Data: []byte{2, 3}}},
+	}
+	data, err := MarshalJSON(ArcItemArrayToProto(a))
+	assert.Nil(t, err)
+	p := &ArcItemArray{}
+	assert.Nil(t, UnmarshalJSON(data, p))
+	assert.Equal(t, a, ArcItemArrayFromProto(p))
+
+	c := &protocols.CountItem{CollectorID: []byte{0, 0, 0, 0, 0, 1}, CollectorType: "arc", Ts: 3, Count: 4, Size: 5}
+	data, err = proto.Marshal(CountItemToProto(c))
+	assert.Nil(t, err)
+	p2 := &CountItem{}
+	assert.Nil(t, proto.Unmarshal(data, p2))
+	assert.Equal(t, c, CountItemFromProto(p2))
+}
@@ -0,0 +1,270 @@
+package protocols
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recentTimestamps is the number of released timestamps kept per collector to detect duplicates
+const recentTimestamps = 32
+
+// defaultGapTolerance is the gap tolerance if ContinuityConfig.Tolerance is not set
+const defaultGapTolerance = 1.5
+
+// ContinuityConfig configures a ContinuityTracker
+type ContinuityConfig struct {
+	Interval  int64         // ms, expected cadence of frames of a collector, 0 means it is learned from the stream
+	Tolerance float64       // a gap is counted if the timestamp delta exceeds Interval*Tolerance, 1.5 if not set
+	Window    int           // frames held per collector to reorder, 0 disables reordering
+	MaxDelay  time.Duration // max time a frame is held to reorder, see Expire
+}
+
+// ContinuityCounters are the counters of continuity
+type ContinuityCounters struct {
+	Frames     uint64 `json:"frames"`       // frames released
+	Gaps       uint64 `json:"gaps"`         // timestamp gaps
+	Missing    uint64 `json:"missing"`      // frames estimated to be lost in gaps
+	Duplicates uint64 `json:"duplicates"`   // frames of seen timestamps, they are dropped
+	OutOfOrder uint64 `json:"out_of_order"` // frames arrived before frames of earlier timestamps
+	Reordered  uint64 `json:"reordered"`    // out of order frames put back in order in the window
+	Late       uint64 `json:"late"`         // out of order frames arrived after the window, they are released as is
+}
+
+func (c *ContinuityCounters) add(o *ContinuityCounters) {
+	c.Frames += o.Frames
+	c.Gaps += o.Gaps
+	c.Missing += o.Missing
+	c.Duplicates += o.Duplicates
+	c.OutOfOrder += o.OutOfOrder
+	c.Reordered += o.Reordered
+	c.Late += o.Late
+}
+
+// CollectorContinuity is the continuity of a collector
+type CollectorContinuity struct {
+	ContinuityCounters
+	ID       string `json:"id"`       // hex of Frame.ID
+	Interval int64  `json:"interval"` // ms, the expected cadence
+	Last     int64  `json:"last"`     // timestamp of the last frame released
+	Buffered int    `json:"buffered"` // frames held to reorder
+}
+
+// ContinuityStats is the continuity of all collectors
+type ContinuityStats struct {
+	ContinuityCounters
+	Collectors []*CollectorContinuity `json:"collectors"`
+}
+
+type heldFrame[T any] struct {
+	ts      int64
+	arrival time.Time
+	value   T
+}
+
+// collectorState is the state of a collector, held frames are sorted by timestamp
+type collectorState[T any] struct {
+	ContinuityCounters
+	interval float64
+	released bool
+	last     int64
+	maxSeen  int64
+	recent   [recentTimestamps]int64
+	recentN  int
+	held     []heldFrame[T]
+}
+
+func (c *collectorState[T]) seen(ts int64) bool {
+	if c.released && ts == c.last {
+		return true
+	}
+	for i := 0; i < c.recentN && i < recentTimestamps; i++ {
+		if c.recent[i] == ts {
+			return true
+		}
+	}
+	for i := range c.held {
+		if c.held[i].ts == ts {
+			return true
+		}
+	}
+	return false
+}
+
+// ContinuityTracker tracks timestamps of frames per collector, keyed by Frame.GetID().
+// It detects gaps relative to the expected cadence, duplicates and out of order frames,
+// and optionally reorders frames in a bounded window before they are released.
+// Values of type T, e.g. frames or items decoded from them, are released in the order of timestamps of a collector.
+// It is safe for concurrent use.
+type ContinuityTracker[T any] struct {
+	config     ContinuityConfig
+	lock       sync.Mutex
+	collectors map[uint64]*collectorState[T]
+}
+
+// NewContinuityTracker create a ContinuityTracker
+func NewContinuityTracker[T any](config ContinuityConfig) *ContinuityTracker[T] {
+	if config.Tolerance <= 1 {
+		config.Tolerance = defaultGapTolerance
+	}
+	if config.Window < 0 {
+		config.Window = 0
+	}
+	return &ContinuityTracker[T]{
+		config:     config,
+		collectors: map[uint64]*collectorState[T]{},
+	}
+}
+
+// Track tracks the frame of timestamp ts of the collector id, and appends the values released to dst.
+// Without reordering the value is released at once unless it is a duplicate.
+func (t *ContinuityTracker[T]) Track(dst []T, id uint64, ts int64, value T) []T {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	c, ok := t.collectors[id]
+	if !ok {
+		c = &collectorState[T]{interval: float64(t.config.Interval)}
+		t.collectors[id] = c
+	}
+	if c.seen(ts) {
+		c.Duplicates++
+		return dst
+	}
+	if ts < c.maxSeen {
+		c.OutOfOrder++
+	} else {
+		c.maxSeen = ts
+	}
+
+	if c.released && ts < c.last {
+		// it can not be put back in order
+		c.Late++
+		c.Frames++
+		c.remember(ts)
+		return append(dst, value)
+	}
+	if t.config.Window == 0 {
+		return t.release(dst, c, ts, value)
+	}
+
+	// insert into held frames sorted by timestamp
+	i := sort.Search(len(c.held), func(i int) bool { return c.held[i].ts > ts })
+	if i < len(c.held) {
+		c.Reordered++
+	}
+	c.held = append(c.held, heldFrame[T]{})
+	copy(c.held[i+1:], c.held[i:])
+	c.held[i] = heldFrame[T]{ts: ts, arrival: time.Now(), value: value}
+	if len(c.held) > t.config.Window {
+		dst = t.releaseHeld(dst, c, len(c.held)-t.config.Window)
+	}
+	return dst
+}
+
+// Expire releases frames held longer than MaxDelay, and frames of earlier timestamps of the same collector.
+// It should be called periodically if reordering is enabled. now is usually time.Now().
+func (t *ContinuityTracker[T]) Expire(dst []T, now time.Time) []T {
+	if t.config.Window == 0 {
+		return dst
+	}
+	deadline := now.Add(-t.config.MaxDelay)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, c := range t.collectors {
+		n := 0
+		for i := range c.held {
+			if c.held[i].arrival.Before(deadline) {
+				n = i + 1
+			}
+		}
+		dst = t.releaseHeld(dst, c, n)
+	}
+	return dst
+}
+
+// Flush releases all held frames
+func (t *ContinuityTracker[T]) Flush(dst []T) []T {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, c := range t.collectors {
+		dst = t.releaseHeld(dst, c, len(c.held))
+	}
+	return dst
+}
+
+// Stats returns the continuity of collectors sorted by ID
+func (t *ContinuityTracker[T]) Stats() *ContinuityStats {
+	stats := &ContinuityStats{Collectors: []*CollectorContinuity{}}
+	t.lock.Lock()
+	for id, c := range t.collectors {
+		stats.add(&c.ContinuityCounters)
+		stats.Collectors = append(stats.Collectors, &CollectorContinuity{
+			ContinuityCounters: c.ContinuityCounters,
+			ID:                 fmt.Sprintf("%012X", id),
+			Interval:           int64(math.Round(c.interval)),
+			Last:               c.last,
+			Buffered:           len(c.held),
+		})
+	}
+	t.lock.Unlock()
+	sort.Slice(stats.Collectors, func(i, j int) bool {
+		return stats.Collectors[i].ID < stats.Collectors[j].ID
+	})
+	return stats
+}
+
+// releaseHeld releases the first n held frames of the collector
+func (t *ContinuityTracker[T]) releaseHeld(dst []T, c *collectorState[T], n int) []T {
+	if n <= 0 {
+		return dst
+	}
+	for i := 0; i < n; i++ {
+		dst = t.release(dst, c, c.held[i].ts, c.held[i].value)
+	}
+	rest := copy(c.held, c.held[n:])
+	// the released values are not referenced by the held frames
+	var zero heldFrame[T]
+	for i := rest; i < len(c.held); i++ {
+		c.held[i] = zero
+	}
+	c.held = c.held[:rest]
+	return dst
+}
+
+// release checks the gap to the last released frame of the collector, ts is never less than c.last here
+func (t *ContinuityTracker[T]) release(dst []T, c *collectorState[T], ts int64, value T) []T {
+	if c.released {
+		delta := float64(ts - c.last)
+		switch {
+		case c.interval <= 0:
+			// learn the cadence from the first delta
+			c.interval = delta
+		case delta > c.interval*t.config.Tolerance:
+			c.Gaps++
+			missing := math.Round(delta/c.interval) - 1
+			if missing < 1 {
+				missing = 1
+			}
+			c.Missing += uint64(missing)
+		case t.config.Interval > 0:
+		case delta < c.interval/t.config.Tolerance:
+			// the learned cadence was a gap or the cadence changed
+			c.interval = delta
+		default:
+			c.interval = 0.9*c.interval + 0.1*delta
+		}
+	}
+	c.released = true
+	c.last = ts
+	c.Frames++
+	c.remember(ts)
+	return append(dst, value)
+}
+
+func (c *collectorState[T]) remember(ts int64) {
+	c.recent[c.recentN%recentTimestamps] = ts
+	c.recentN++
+}
@@ -0,0 +1,74 @@
+package protocols
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContinuityGaps(t *testing.T) {
+	tracker := NewContinuityTracker[int64](ContinuityConfig{})
+	var released []int64
+	// the cadence is learned as 10ms, 30 and 40 are lost, 60 is a duplicate, 55 is late
+	for _, ts := range []int64{0, 10, 20, 50, 60, 60, 55, 70} {
+		released = tracker.Track(released, 1, ts, ts)
+	}
+	// another collector
+	released = tracker.Track(released, 2, 100, 100)
+	assert.Equal(t, []int64{0, 10, 20, 50, 60, 55, 70, 100}, released)
+
+	stats := tracker.Stats()
+	assert.Equal(t, 2, len(stats.Collectors))
+	c := stats.Collectors[0]
+	assert.Equal(t, "000000000001", c.ID)
+	assert.Equal(t, int64(10), c.Interval)
+	assert.Equal(t, int64(70), c.Last)
+	assert.Equal(t, ContinuityCounters{Frames: 7, Gaps: 1, Missing: 2, Duplicates: 1, OutOfOrder: 1, Late: 1}, c.ContinuityCounters)
+	assert.Equal(t, uint64(8), stats.Frames)
+}
+
+func TestContinuityInterval(t *testing.T) {
+	tracker := NewContinuityTracker[int64](ContinuityConfig{Interval: 10})
+	var released []int64
+	// the first delta is a gap, the learned cadence would miss it
+	for _, ts := range []int64{0, 40, 50, 60, 80} {
+		released = tracker.Track(released, 1, ts, ts)
+	}
+	c := tracker.Stats().Collectors[0]
+	assert.Equal(t, uint64(2), c.Gaps)
+	assert.Equal(t, uint64(4), c.Missing)
+	assert.Equal(t, int64(10), c.Interval)
+}
+
+func TestContinuityReorder(t *testing.T) {
+	tracker := NewContinuityTracker[int64](ContinuityConfig{Window: 3, MaxDelay: time.Hour})
+	var released []int64
+	for _, ts := range []int64{0, 20, 10, 30, 40, 20, 50} {
+		released = tracker.Track(released, 1, ts, ts)
+	}
+	// 3 frames are held
+	assert.Equal(t, []int64{0, 10, 20}, released)
+	c := tracker.Stats().Collectors[0]
+	assert.Equal(t, 3, c.Buffered)
+	assert.Equal(t, uint64(0), c.Gaps)
+	assert.Equal(t, uint64(1), c.Reordered)
+	assert.Equal(t, uint64(1), c.Duplicates)
+
+	// a frame older than the released ones can not be reordered
+	released = tracker.Track(released[:0], 1, 15, 15)
+	assert.Equal(t, []int64{15}, released)
+	assert.Equal(t, uint64(1), tracker.Stats().Collectors[0].Late)
+
+	assert.Empty(t, tracker.Expire(nil, time.Now()))
+	assert.Equal(t, []int64{30}, tracker.Track(nil, 1, 60, 60))
+	assert.Equal(t, []int64{40, 50, 60}, tracker.Expire(nil, time.Now().Add(2*time.Hour)))
+	assert.Equal(t, 0, tracker.Stats().Collectors[0].Buffered)
+
+	released = tracker.Track(nil, 1, 80, 80)
+	assert.Empty(t, released)
+	assert.Equal(t, []int64{80}, tracker.Flush(nil))
+	c = tracker.Stats().Collectors[0]
+	assert.Equal(t, uint64(1), c.Gaps)
+	assert.Equal(t, uint64(1), c.Missing)
+}
@@ -13,14 +13,20 @@ const (
 	ItemSampleRate = 1
 )
 
-// ISegment -
+// ISegment - a segment encoded as type(1) + payload, new types are added by RegisterSegment
 type ISegment interface {
 	Encode([]byte) (int, error)
+	Decode([]byte) error
 	Type() byte
 	Size() uint32
 	Dump()
 }
 
+// IInPlaceSegment is implemented by segments which can reference the source data instead of copying it
+type IInPlaceSegment interface {
+	DecodeInPlace([]byte) error
+}
+
 // DataGroup Protocol=2   CType 1:GetCollector2Structure.Size()
 type DataGroup struct {
 	Count    byte       //1 segment count
@@ -77,20 +83,41 @@ func (d *DataGroup) GetArcSegment() (*SegmentArc, error) {
 	return s.(*SegmentArc), nil
 }
 
-// Decode - Decode
+// Decode - Decode, segments of unregistered types are kept as SegmentRaw. The data group does not reference data.
 func (d *DataGroup) Decode(data []byte) error {
+	return d.decode(data, false)
+}
+
+// DecodeInPlace decodes data without copying if the segments implement IInPlaceSegment, see Frame.DecodeInPlace
+func (d *DataGroup) DecodeInPlace(data []byte) error {
+	return d.decode(data, true)
+}
+
+// decode reuses Sizes, STypes and the segments of the same type at the same index if inPlace is true,
+// otherwise they are allocated, so the ones got from an earlier Decode are not overwritten
+func (d *DataGroup) decode(data []byte, inPlace bool) error {
 	idx := 0
+	if !inPlace {
+		d.Sizes, d.STypes, d.Segments = nil, nil, nil
+	}
 
 	// get segment count
+	if len(data) == 0 {
+		return fmt.Errorf("empty data group")
+	}
 	d.Count = data[idx]
 	idx++
 	if d.Count <= 0 {
 		return fmt.Errorf("data group count %d", d.Count)
 	}
+	count := int(d.Count)
+	if len(data) < idx+4*count {
+		return fmt.Errorf("data group sizes out of range %d", len(data))
+	}
 
-	d.Sizes = make([]uint32, d.Count)
-	for i := 0; i < int(d.Count); i++ {
-		d.Sizes[i] = binary.BigEndian.Uint32(data[idx : idx+4])
+	d.Sizes = d.Sizes[:0]
+	for i := 0; i < count; i++ {
+		d.Sizes = append(d.Sizes, binary.BigEndian.Uint32(data[idx:idx+4]))
 		idx += 4
 	}
 
@@ -100,27 +127,38 @@ func (d *DataGroup) Decode(data []byte) error {
 	}
 
 	// decode
-	for i := 0; i < int(d.Count); i++ {
-		if i >= len(d.STypes) {
-			d.STypes = append(d.STypes, 0)
+	d.STypes = d.STypes[:0]
+	if cap(d.Segments) < count {
+		segments := make([]ISegment, count)
+		copy(segments, d.Segments[:cap(d.Segments)])
+		d.Segments = segments
+	}
+	d.Segments = d.Segments[:count]
+	for i := 0; i < count; i++ {
+		if d.Sizes[i] == 0 || len(data) < idx+int(d.Sizes[i]) {
+			return fmt.Errorf("data group segment size out of range %d", d.Sizes[i])
 		}
-
-		switch data[idx] {
-		case STypeArc:
-			st, err := d.GetArcSegment()
-			if err != nil {
-				st = NewDefaultSegmentArc()
-				d.Segments = append(d.Segments, st)
-			}
-			if err := st.Decode(data[idx : idx+int(d.Sizes[i])]); err != nil {
-				return err
-			}
-			d.STypes[i] = STypeArc
-		default:
-			return fmt.Errorf("no match stype")
+		st := d.Segments[i]
+		if st == nil || st.Type() != data[idx] {
+			st = NewSegment(data[idx])
+			d.Segments[i] = st
+		}
+		segmentData := data[idx : idx+int(d.Sizes[i])]
+		var err error
+		if s, ok := st.(IInPlaceSegment); ok && inPlace {
+			err = s.DecodeInPlace(segmentData)
+		} else {
+			err = st.Decode(segmentData)
 		}
+		if err != nil {
+			return err
+		}
+		d.STypes = append(d.STypes, st.Type())
 		idx += int(d.Sizes[i])
 	}
+	if idx != len(data) {
+		return fmt.Errorf("data group size does not match %d != %d", idx, len(data))
+	}
 	return nil
 }
 
@@ -148,12 +186,6 @@ func (d *DataGroup) Encode(buf []byte) (int, error) {
 		idx += 4
 	}
 
-	// //type 1
-	for _, tp := range d.STypes {
-		buf[idx] = tp
-		idx++
-	}
-
 	// segments
 	for i, s := range d.Segments {
 		n, err := s.Encode(buf[idx:])
@@ -170,11 +202,7 @@ func (d *DataGroup) Encode(buf []byte) (int, error) {
 
 // Dump -
 func (d *DataGroup) Dump() {
-	for _, tp := range d.STypes {
-		s, err := d.GetSegment(tp)
-		if err != nil {
-			fmt.Printf("|Not Found Segment Type: %d\n", tp)
-		}
+	for _, s := range d.Segments {
 		s.Dump()
 	}
 }
@@ -9,12 +9,22 @@ import (
 	"github.com/kiga-hub/arc/utils"
 )
 
-// DefaultHeadLength head  + size
-const DefaultHeadLength = 8
+const (
+	// FrameV1 is the version of frames with timestamp, id and data group
+	FrameV1 byte = 1
+	// FrameV2 is the version of frames with sequence number, sample rate and header extensions in addition to v1
+	FrameV2 byte = 2
+)
+
+// DefaultHeadLength head + version + size
+const DefaultHeadLength = 9
 
 // LengthWithoutData ... Timestamp + ID + Crc + End
 const LengthWithoutData uint32 = 17
 
+// LengthWithoutDataV2 ... LengthWithoutData + Sequence + SampleRate + extensions length
+const LengthWithoutDataV2 = LengthWithoutData + 10
+
 var (
 	//Head ...
 	Head = []byte{0xFC, 0xFC, 0xFC, 0xFC}
@@ -26,15 +36,56 @@ var (
 	MaxSize uint32 = 1024 * 6
 )
 
-// Frame package size 25+n
+// UnsupportedVersionError is the error of frames of unknown or not accepted versions, it is ErrBadVersion
+type UnsupportedVersionError struct {
+	Version byte
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("%s [%d]", ErrBadVersion, e.Version)
+}
+
+// Is reports whether target is ErrBadVersion
+func (e *UnsupportedVersionError) Is(target error) bool {
+	return target == ErrBadVersion
+}
+
+// SupportedVersions returns the frame versions can be decoded
+func SupportedVersions() []byte {
+	return []byte{FrameV1, FrameV2}
+}
+
+// minSize returns the size of frames of the version without data group
+func minSize(version byte) (uint32, error) {
+	switch version {
+	case FrameV1:
+		return LengthWithoutData, nil
+	case FrameV2:
+		return LengthWithoutDataV2, nil
+	default:
+		return 0, &UnsupportedVersionError{Version: version}
+	}
+}
+
+// Extension is a TLV header extension of v2 frames, encoded as type(1) + length(2) + value
+type Extension struct {
+	Type  byte
+	Value []byte
+}
+
+// Frame package size 26+n of v1, 36+m+n of v2 (m: extensions)
 type Frame struct {
-	Head      [4]byte   //4 Head 0xFC 0xFC 0xFC 0xFC
-	Size      uint32    //4 Package size  [Timestamp, End] = 17+n BigEndian
-	Timestamp int64     //8 timestamp ms BigEndian
-	ID        [6]byte   //6 machin id (Mac Address)
-	DataGroup DataGroup //n data
-	Crc       uint16    //2 crc [Timestamp, Data], CRC-16 BigEndian
-	End       byte      //1 End 0xFD
+	Head       [4]byte     //4 Head 0xFC 0xFC 0xFC 0xFC
+	Version    byte        //1 FrameV1 or FrameV2
+	Size       uint32      //4 Package size  [Timestamp, End] = 17+n of v1, 27+m+n of v2 BigEndian
+	Timestamp  int64       //8 timestamp ms BigEndian
+	ID         [6]byte     //6 machin id (Mac Address)
+	Sequence   uint32      //4 v2, sequence number of frames of the sensor BigEndian
+	SampleRate uint32      //4 v2, sample rate of the sensor in Hz BigEndian
+	Extensions []Extension //2+m v2, length of extensions BigEndian + extensions
+	DataGroup  DataGroup   //n data
+	Crc        uint16      //2 crc [Timestamp, Data], CRC-16 BigEndian
+	End        byte        //1 End 0xFD
 }
 
 // ConfigFrame -
@@ -48,10 +99,59 @@ func ConfigFrame(maxSize uint32) error {
 // NewDefaultFrame -
 func NewDefaultFrame() *Frame {
 	return &Frame{
-		Head: [4]byte{0xFC, 0xFC, 0xFC, 0xFC},
-		Size: LengthWithoutData,
-		ID:   [6]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
-		End:  0xFD,
+		Head:    [4]byte{0xFC, 0xFC, 0xFC, 0xFC},
+		Version: FrameV1,
+		Size:    LengthWithoutData + 1,
+		ID:      [6]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		End:     0xFD,
+	}
+}
+
+// SetVersion - set version, the fields not in the version are ignored when encoding
+func (f *Frame) SetVersion(version byte) *Frame {
+	f.Version = version
+	f.updateSize()
+	return f
+}
+
+// AddExtension - add a header extension of v2
+func (f *Frame) AddExtension(tp byte, value []byte) *Frame {
+	v := make([]byte, len(value))
+	copy(v, value)
+	f.Extensions = append(f.Extensions, Extension{Type: tp, Value: v})
+	f.updateSize()
+	return f
+}
+
+// GetExtension - get the value of the first header extension of the type
+func (f *Frame) GetExtension(tp byte) ([]byte, bool) {
+	for _, e := range f.Extensions {
+		if e.Type == tp {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// extensionsSize returns the encoded size of extensions without the length
+func (f *Frame) extensionsSize() int {
+	size := 0
+	for _, e := range f.Extensions {
+		size += 3 + len(e.Value)
+	}
+	return size
+}
+
+// updateSize updates Size by version, extensions and data group
+func (f *Frame) updateSize() {
+	f.Size = LengthWithoutData
+	if f.Version == FrameV2 {
+		f.Size = LengthWithoutDataV2 + uint32(f.extensionsSize())
+	}
+	f.Size++ // add count: 1byte
+	for _, s := range f.DataGroup.Sizes {
+		f.Size += 4
+		f.Size += s
 	}
 }
 
@@ -79,12 +179,7 @@ func (f *Frame) GetID() uint64 {
 // SetDataGroup -
 func (f *Frame) SetDataGroup(dg *DataGroup) *Frame {
 	f.DataGroup = *dg
-	f.Size = LengthWithoutData + 1 // add count: 1byte
-	for _, s := range dg.Sizes {
-		f.Size += 4
-		f.Size += s
-	}
-
+	f.updateSize()
 	return f
 }
 
@@ -98,7 +193,10 @@ func (f *Frame) Dump() {
 	for i := range f.ID {
 		fmt.Printf("%02X", f.ID[i])
 	}
-	fmt.Printf(", Timestamp: %d, Size: %d\n", f.Timestamp, f.Size)
+	fmt.Printf(", Version: %d, Timestamp: %d, Size: %d\n", f.Version, f.Timestamp, f.Size)
+	if f.Version == FrameV2 {
+		fmt.Printf("Sequence: %d, SampleRate: %d, Extensions: %d\n", f.Sequence, f.SampleRate, len(f.Extensions))
+	}
 
 	fmt.Printf("\n")
 	f.DataGroup.Dump()
@@ -106,6 +204,9 @@ func (f *Frame) Dump() {
 
 // Encode 字节数组写入当前流, 直接对buf操作
 func (f *Frame) Encode(buf []byte) (int, error) {
+	if _, err := minSize(f.Version); err != nil {
+		return 0, err
+	}
 	if len(buf) < int(f.Size)+DefaultHeadLength {
 		return 0, fmt.Errorf("frame out of allocated memory")
 	}
@@ -117,6 +218,10 @@ func (f *Frame) Encode(buf []byte) (int, error) {
 		idx++
 	}
 
+	// version(1)
+	buf[idx] = f.Version
+	idx++
+
 	// size(4)
 	binary.BigEndian.PutUint32(buf[idx:idx+4], f.Size)
 	idx += 4
@@ -131,12 +236,42 @@ func (f *Frame) Encode(buf []byte) (int, error) {
 		idx++
 	}
 
-	// dataGroup TODO: check size
+	if f.Version == FrameV2 {
+		// sequence(4)
+		binary.BigEndian.PutUint32(buf[idx:idx+4], f.Sequence)
+		idx += 4
+
+		// sample rate(4)
+		binary.BigEndian.PutUint32(buf[idx:idx+4], f.SampleRate)
+		idx += 4
+
+		// extensions(2+m)
+		size := f.extensionsSize()
+		if size > 0xFFFF || int(f.Size)+DefaultHeadLength < idx+2+size {
+			return idx, fmt.Errorf("extensions size does not match %d", size)
+		}
+		binary.BigEndian.PutUint16(buf[idx:idx+2], uint16(size))
+		idx += 2
+		for _, e := range f.Extensions {
+			if len(e.Value) > 0xFFFF {
+				return idx, fmt.Errorf("extension %d out of range %d", e.Type, len(e.Value))
+			}
+			buf[idx] = e.Type
+			binary.BigEndian.PutUint16(buf[idx+1:idx+3], uint16(len(e.Value)))
+			idx += 3
+			idx += copy(buf[idx:], e.Value)
+		}
+	}
+
+	// dataGroup
 	n, err := f.DataGroup.Encode(buf[idx:])
-	idx += n - 1
+	idx += n
 	if err != nil {
 		return idx, err
 	}
+	if idx+3 != int(f.Size)+DefaultHeadLength {
+		return idx, fmt.Errorf("frame size does not match %d != %d", idx+3-DefaultHeadLength, f.Size)
+	}
 
 	// crc(2)
 	f.Crc = utils.CheckSum(buf[DefaultHeadLength:idx])
@@ -150,11 +285,13 @@ func (f *Frame) Encode(buf []byte) (int, error) {
 	return idx, nil
 }
 
-// Decode 当前流中读取字节
-func (f *Frame) Decode(srcData []byte) error {
+// decodeHeader decodes the fields before data group, it returns the index of data group.
+// Extension values reference data if inPlace is set.
+func (f *Frame) decodeHeader(data []byte, inPlace bool) (int, error) {
+	if len(data) < DefaultHeadLength {
+		return 0, fmt.Errorf("frame too short(%d)", len(data))
+	}
 	idx := 0
-	data := make([]byte, len(srcData))
-	copy(data, srcData)
 
 	// head(4)
 	for i := 0; i < 4; i++ {
@@ -162,9 +299,21 @@ func (f *Frame) Decode(srcData []byte) error {
 		idx++
 	}
 
+	// version(1)
+	f.Version = data[idx]
+	idx++
+	sizeWithoutData, err := minSize(f.Version)
+	if err != nil {
+		return idx, err
+	}
+
 	// size(4)
 	f.Size = binary.BigEndian.Uint32(data[idx : idx+4])
 	idx += 4
+	if f.Size < sizeWithoutData+1 || int(f.Size)+DefaultHeadLength != len(data) {
+		return idx, fmt.Errorf("invalid frame size(%d)", f.Size)
+	}
+
 	// timestamp(8)
 	f.Timestamp = int64(binary.BigEndian.Uint64(data[idx : idx+8]))
 	idx += 8
@@ -175,86 +324,128 @@ func (f *Frame) Decode(srcData []byte) error {
 		idx++
 	}
 
+	f.Sequence, f.SampleRate, f.Extensions = 0, 0, f.Extensions[:0]
+	if !inPlace {
+		// extensions of an earlier Decode may still be used
+		f.Extensions = nil
+	}
+	if f.Version == FrameV2 {
+		// sequence(4)
+		f.Sequence = binary.BigEndian.Uint32(data[idx : idx+4])
+		idx += 4
+
+		// sample rate(4)
+		f.SampleRate = binary.BigEndian.Uint32(data[idx : idx+4])
+		idx += 4
+
+		// extensions(2+m)
+		size := int(binary.BigEndian.Uint16(data[idx : idx+2]))
+		idx += 2
+		end := idx + size
+		if end > len(data)-3 {
+			return idx, fmt.Errorf("invalid extensions size(%d)", size)
+		}
+		for idx < end {
+			if idx+3 > end {
+				return idx, fmt.Errorf("invalid extension at %d", idx)
+			}
+			e := Extension{Type: data[idx]}
+			l := int(binary.BigEndian.Uint16(data[idx+1 : idx+3]))
+			idx += 3
+			if idx+l > end {
+				return idx, fmt.Errorf("invalid extension %d size(%d)", e.Type, l)
+			}
+			if inPlace {
+				e.Value = data[idx : idx+l : idx+l]
+			} else {
+				e.Value = make([]byte, l)
+				copy(e.Value, data[idx:idx+l])
+			}
+			idx += l
+			f.Extensions = append(f.Extensions, e)
+		}
+	}
+	return idx, nil
+}
+
+// Decode 当前流中读取字节, the frame does not reference data.
+// Segments and extensions got from an earlier decode of the frame are not modified.
+func (f *Frame) Decode(data []byte) error {
+	return f.decode(data, false)
+}
+
+// DecodeInPlace decodes data without copying, segment data and extension values reference data.
+// data must not be modified or reused while the frame is in use, the frame should be reset or released before that.
+// Segments of the frame are reused, so the ones got from the frame are overwritten by the next decode.
+func (f *Frame) DecodeInPlace(data []byte) error {
+	return f.decode(data, true)
+}
+
+func (f *Frame) decode(data []byte, inPlace bool) error {
+	idx, err := f.decodeHeader(data, inPlace)
+	if err != nil {
+		return err
+	}
+
 	// Crc(2)
 	f.Crc = binary.BigEndian.Uint16(data[len(data)-3 : len(data)-1])
 	// End(1)
 	f.End = data[len(data)-1]
 
 	// DataGroup
-	return f.DataGroup.Decode(data[idx : len(data)-3])
+	return f.DataGroup.decode(data[idx:len(data)-3], inPlace)
 }
 
-// FrameValidate -  包格式检查
-func FrameValidate(srcData []byte) error {
-	data := make([]byte, len(srcData))
-	copy(data, srcData)
-
-	idx := 0
-
+// FrameValidate -  包格式检查, it does not allocate
+func FrameValidate(data []byte) error {
 	// check head(4)
-	if !bytes.Equal(data[idx:idx+4], Head[:]) {
-		return fmt.Errorf("invalid frame header(%02x%02x%02x%02x)", data[idx], data[idx+1], data[idx+2], data[idx+3])
+	if len(data) < DefaultHeadLength || !bytes.Equal(data[:4], Head[:]) {
+		return fmt.Errorf("invalid frame header(% x)", data[:min(4, len(data))])
 	}
-	idx += 4
 
-	// size(4)
-	// fSize := binary.BigEndian.Uint32(data[idx : idx+4])
-	// if fSize > MaxSize || fSize < LengthWithoutData || fSize != uint32(len(data)-DefaultHeadLength) {
-	// 	return fmt.Errorf("invalid frame size(%d)", fSize)
-	// }
-	_ = binary.BigEndian.Uint32(data[idx : idx+4])
-	idx += 4
-
-	// timestamp(8)
-	seq := int64(binary.BigEndian.Uint64(data[idx : idx+8]))
-	// TODO: check timestamp
-	idx += 8
-
-	// ID(6)
-	// TODO: check id
-	clientID := hex.EncodeToString(data[idx : idx+6])
-	idx += 6
-
-	// DataGroup.Count
-	SegmentCount := data[idx]
-	if SegmentCount > 255 {
-		return fmt.Errorf("[%s][%d]invalid datagroup count(%d)", clientID, seq, SegmentCount)
+	// version, size, timestamp, ID and fields of the version
+	f := AcquireFrame()
+	defer ReleaseFrame(f)
+	idx, err := f.decodeHeader(data, true)
+	if err != nil {
+		return err
 	}
-	idx++
-	// DataGroup.Segments
-	SegmentSize := 0
-	for i := 0; i < int(SegmentCount); i++ {
-		// Sizes
-		Size := binary.BigEndian.Uint32(data[idx : idx+4]) // Sizes
-		// Segments * size
-		SegmentIdx := idx + ((int(SegmentCount) - i) * 4) + SegmentSize
-		if SegmentIdx+int(Size) > len(data) {
-			return fmt.Errorf("[%s][%d]datagroup valid size(%d:%d)", clientID, seq, i+1, Size)
-		}
-		switch data[SegmentIdx] {
-		case STypeArc:
-			if err := ArcSegmentValidate(data[SegmentIdx : SegmentIdx+int(Size)]); err != nil {
-				return fmt.Errorf("[%s][%d]%s", clientID, seq, err.Error())
-			}
-		default:
-			return fmt.Errorf("[%s][%d]datagroup invalid segment stype(%d:%d)", clientID, seq, i+1, data[SegmentIdx])
-		}
-		idx += 4
-		SegmentSize += int(Size)
+
+	// DataGroup, decoded into the segments of the pooled frame
+	err = f.DataGroup.decode(data[idx:len(data)-3], true)
+	if err != nil {
+		return fmt.Errorf("[%s][%d]%s", hex.EncodeToString(f.ID[:]), f.Timestamp, err.Error())
 	}
-	idx += SegmentSize
+	idx = len(data) - 3
 
 	// Crc(2)
 	Crc := binary.BigEndian.Uint16(data[idx : idx+2])
 	if Crc != utils.CheckSum(data[DefaultHeadLength:idx]) {
-		return fmt.Errorf("[%s][%d]invalid frame crc(%d)", clientID, seq, Crc)
+		return fmt.Errorf("[%s][%d]invalid frame crc(%d)", hex.EncodeToString(f.ID[:]), f.Timestamp, Crc)
 	}
 	idx += 2
 
 	// End(1)
 	if data[idx] != End {
-		return fmt.Errorf("[%s][%d]invalid frame end(%02x)", clientID, seq, data[idx])
+		return fmt.Errorf("[%s][%d]invalid frame end(%02x)", hex.EncodeToString(f.ID[:]), f.Timestamp, data[idx])
 	}
-	idx++
 	return nil
 }
+
+// Reset - reset the frame to NewDefaultFrame, the allocated slices and segments are kept for reuse
+func (f *Frame) Reset() {
+	extensions := f.Extensions[:0]
+	dg := f.DataGroup
+	*f = Frame{
+		Head:       [4]byte{0xFC, 0xFC, 0xFC, 0xFC},
+		Version:    FrameV1,
+		Size:       LengthWithoutData + 1,
+		End:        0xFD,
+		Extensions: extensions,
+	}
+	f.DataGroup = DataGroup{
+		Sizes:    dg.Sizes[:0],
+		STypes:   dg.STypes[:0],
+		Segments: dg.Segments[:0],
+	}
+}
@@ -0,0 +1,61 @@
+package protocols
+
+import "sync"
+
+var (
+	framePool = sync.Pool{
+		New: func() interface{} {
+			return NewDefaultFrame()
+		},
+	}
+	bufferPool sync.Pool
+)
+
+// AcquireFrame returns a reset frame from the pool.
+// Release it by ReleaseFrame when neither the frame nor its segments are used,
+// the data decoded in place into it can be reused after that.
+func AcquireFrame() *Frame {
+	return framePool.Get().(*Frame)
+}
+
+// ReleaseFrame resets the frame and puts it back to the pool, the frame must not be used after that
+func ReleaseFrame(f *Frame) {
+	f.Reset()
+	framePool.Put(f)
+}
+
+// Buffer is a byte slice from the pool
+type Buffer struct {
+	B []byte
+}
+
+// AcquireBuffer returns a buffer of length size from the pool, release it by ReleaseBuffer when it is not used
+func AcquireBuffer(size int) *Buffer {
+	if v := bufferPool.Get(); v != nil {
+		b := v.(*Buffer)
+		if cap(b.B) >= size {
+			b.B = b.B[:size]
+			return b
+		}
+		b.B = make([]byte, size)
+		return b
+	}
+	return &Buffer{B: make([]byte, size)}
+}
+
+// ReleaseBuffer puts the buffer back to the pool, the buffer and the frames decoded in place from it must not be used after that
+func ReleaseBuffer(b *Buffer) {
+	bufferPool.Put(b)
+}
+
+// EncodeToBuffer encodes the frame to a buffer from the pool, release it by ReleaseBuffer when it is not used
+func (f *Frame) EncodeToBuffer() (*Buffer, error) {
+	b := AcquireBuffer(int(f.Size) + DefaultHeadLength)
+	n, err := f.Encode(b.B)
+	if err != nil {
+		ReleaseBuffer(b)
+		return nil, err
+	}
+	b.B = b.B[:n]
+	return b, nil
+}
@@ -0,0 +1,224 @@
+package protocols
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var benchmarkSizes = []int{1024, 2048, 4096, 6000}
+
+// benchmarkFrame returns a frame of size bytes
+func benchmarkFrame(size int) *Frame {
+	st := NewDefaultSegmentArc()
+	st.SetData(bytes.Repeat([]byte{0x5A}, size-int(LengthWithoutData)-DefaultHeadLength-6))
+	g := NewDefaultDataGroup()
+	g.AppendSegment(st)
+	f := NewDefaultFrame().SetID(15).SetDataGroup(g)
+	f.Timestamp = 1234567
+	return f
+}
+
+// repeatReader reads data repeatedly
+type repeatReader struct {
+	data []byte
+	idx  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data[r.idx:])
+	r.idx = (r.idx + n) % len(r.data)
+	return n, nil
+}
+
+func TestFramePoolOwnership(t *testing.T) {
+	buf, err := benchmarkFrame(1024).EncodeToBuffer()
+	assert.Nil(t, err)
+	assert.Equal(t, 1024, len(buf.B))
+	defer ReleaseBuffer(buf)
+
+	inPlace := AcquireFrame()
+	defer ReleaseFrame(inPlace)
+	assert.Nil(t, inPlace.DecodeInPlace(buf.B))
+	copied := NewDefaultFrame()
+	assert.Nil(t, copied.Decode(buf.B))
+	assert.Equal(t, inPlace.DataGroup.Sizes, copied.DataGroup.Sizes)
+
+	// segments decoded in place see changes of the source buffer, copied ones do not
+	inPlaceArc, err := inPlace.DataGroup.GetArcSegment()
+	assert.Nil(t, err)
+	copiedArc, err := copied.DataGroup.GetArcSegment()
+	assert.Nil(t, err)
+	assert.Equal(t, copiedArc.Data, inPlaceArc.Data)
+	buf.B[len(buf.B)-4] = 0
+	assert.Equal(t, byte(0), inPlaceArc.Data[len(inPlaceArc.Data)-1])
+	assert.Equal(t, byte(0x5A), copiedArc.Data[len(copiedArc.Data)-1])
+
+	// released frames are reset
+	f := AcquireFrame()
+	assert.Nil(t, f.DecodeInPlace(buf.B))
+	ReleaseFrame(f)
+	assert.Equal(t, NewDefaultFrame().Size, f.Size)
+	assert.Equal(t, 0, len(f.DataGroup.Segments))
+}
+
+func TestFrameDecodeKeepsSegments(t *testing.T) {
+	small, err := benchmarkFrame(1024).EncodeToBuffer()
+	assert.Nil(t, err)
+	defer ReleaseBuffer(small)
+	large, err := benchmarkFrame(2048).EncodeToBuffer()
+	assert.Nil(t, err)
+	defer ReleaseBuffer(large)
+
+	// segments of an earlier Decode are not overwritten by the next one
+	f := NewDefaultFrame()
+	assert.Nil(t, f.Decode(small.B))
+	first, err := f.DataGroup.GetArcSegment()
+	assert.Nil(t, err)
+	size := len(first.Data)
+	assert.Nil(t, f.Decode(large.B))
+	second, err := f.DataGroup.GetArcSegment()
+	assert.Nil(t, err)
+	assert.Len(t, first.Data, size)
+	assert.Greater(t, len(second.Data), size)
+}
+
+func TestFrameAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	f := benchmarkFrame(6000)
+	buf, err := f.EncodeToBuffer()
+	assert.Nil(t, err)
+	data := append([]byte(nil), buf.B...)
+	ReleaseBuffer(buf)
+
+	var encodeErr error
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		b, err := f.EncodeToBuffer()
+		if err != nil {
+			encodeErr = err
+			return
+		}
+		ReleaseBuffer(b)
+	}), "encode to pooled buffer")
+	assert.Nil(t, encodeErr)
+
+	decoded := AcquireFrame()
+	defer ReleaseFrame(decoded)
+	var decodeErr error
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		if err := decoded.DecodeInPlace(data); err != nil {
+			decodeErr = err
+		}
+	}), "decode in place")
+	assert.Nil(t, decodeErr)
+
+	var validateErr error
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		if err := FrameValidate(data); err != nil {
+			validateErr = err
+		}
+	}), "validate")
+	assert.Nil(t, validateErr)
+
+	scanner := NewFrameScanner(&repeatReader{data: data}, true)
+	var scanErr error
+	scan := func() {
+		if !scanner.Scan() {
+			scanErr = scanner.Err()
+			return
+		}
+		if err := scanner.DecodeFrame(decoded, true); err != nil {
+			scanErr = err
+		}
+	}
+	// the scanner buffer grows to fit the frame on the first scans
+	scan()
+	scan()
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, scan), "scan")
+	assert.Nil(t, scanErr)
+}
+
+func BenchmarkFrameEncode(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			f := benchmarkFrame(size)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf, err := f.EncodeToBuffer()
+				if err != nil {
+					b.Fatal(err)
+				}
+				ReleaseBuffer(buf)
+			}
+		})
+	}
+}
+
+func BenchmarkFrameDecode(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		data, _ := benchmarkFrame(size).EncodeToBuffer()
+		b.Run(fmt.Sprintf("copy/%d", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f := AcquireFrame()
+				if err := f.Decode(data.B); err != nil {
+					b.Fatal(err)
+				}
+				ReleaseFrame(f)
+			}
+		})
+		b.Run(fmt.Sprintf("inplace/%d", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f := AcquireFrame()
+				if err := f.DecodeInPlace(data.B); err != nil {
+					b.Fatal(err)
+				}
+				ReleaseFrame(f)
+			}
+		})
+	}
+}
+
+func BenchmarkFrameValidate(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		data, _ := benchmarkFrame(size).EncodeToBuffer()
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := FrameValidate(data.B); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFrameScanner(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		data, _ := benchmarkFrame(size).EncodeToBuffer()
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			scanner := NewFrameScanner(&repeatReader{data: data.B}, true)
+			f := AcquireFrame()
+			defer ReleaseFrame(f)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if !scanner.Scan() {
+					b.Fatal(scanner.Err())
+				}
+				if err := scanner.DecodeFrame(f, true); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package protocols
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestProto2(t *testing.T) {
@@ -55,3 +59,69 @@ func TestProto2(t *testing.T) {
 	}
 	p2.Dump()
 }
+
+func TestFrameV2(t *testing.T) {
+	st := NewDefaultSegmentArc()
+	st.SetData([]byte{1, 2, 3})
+	g := NewDefaultDataGroup()
+	g.AppendSegment(st)
+
+	p := NewDefaultFrame().SetVersion(FrameV2).SetID(15).SetDataGroup(g)
+	p.Timestamp = 1000
+	p.Sequence = 7
+	p.SampleRate = 48000
+	p.AddExtension(1, []byte("gain")).AddExtension(2, nil)
+	buf := make([]byte, p.Size+DefaultHeadLength)
+	n, err := p.Encode(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, len(buf), n)
+	assert.Nil(t, FrameValidate(buf))
+
+	p2 := NewDefaultFrame()
+	assert.Nil(t, p2.Decode(buf))
+	assert.Equal(t, FrameV2, p2.Version)
+	assert.Equal(t, uint32(7), p2.Sequence)
+	assert.Equal(t, uint32(48000), p2.SampleRate)
+	assert.Equal(t, []Extension{{Type: 1, Value: []byte("gain")}, {Type: 2, Value: []byte{}}}, p2.Extensions)
+	v, ok := p2.GetExtension(1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("gain"), v)
+	arc, err := p2.DataGroup.GetArcSegment()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, arc.Data)
+
+	// the stream decoder dispatches by version, v2 is dropped if only v1 is accepted
+	v1 := testFrame([]byte{4, 5, 6})
+	data := append(append([]byte{}, buf...), v1...)
+	scanner := NewFrameScanner(bytes.NewReader(data), true)
+	var frames []*Frame
+	for scanner.Scan() {
+		f, err := scanner.Frame()
+		assert.Nil(t, err)
+		frames = append(frames, f)
+	}
+	assert.Equal(t, 2, len(frames))
+	assert.Equal(t, FrameV2, frames[0].Version)
+	assert.Equal(t, FrameV1, frames[1].Version)
+
+	scanner = NewFrameScanner(bytes.NewReader(data), true).SetVersions(FrameV1)
+	assert.True(t, scanner.Scan())
+	assert.Equal(t, v1, scanner.Bytes())
+	assert.False(t, scanner.Scan())
+	assert.Equal(t, uint64(1), scanner.Stats().BadVersion)
+}
+
+func TestFrameUnsupportedVersion(t *testing.T) {
+	buf := testFrame([]byte{1})
+	buf[4] = 9
+	var versionErr *UnsupportedVersionError
+	err := NewDefaultFrame().Decode(buf)
+	assert.True(t, errors.As(err, &versionErr))
+	assert.Equal(t, byte(9), versionErr.Version)
+	assert.True(t, errors.Is(FrameValidate(buf), ErrBadVersion))
+	_, err = (&Frame{Version: 9}).Encode(buf)
+	assert.True(t, errors.Is(err, ErrBadVersion))
+
+	_, err = (&Coder{}).Decode(&testConn{buffer: buf})
+	assert.True(t, errors.As(err, &versionErr))
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package protocols
+
+// raceEnabled is true if the tests are built with the race detector, which makes allocations of its own
+const raceEnabled = true
@@ -0,0 +1,304 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc/utils"
+)
+
+var (
+	// ErrNotEnoughData means more data is required to decode a frame
+	ErrNotEnoughData = errors.New("not enough data")
+	// ErrBadVersion means the version of the frame is not supported, see UnsupportedVersionError
+	ErrBadVersion = errors.New("bad version")
+	// ErrBadSize means the size of the frame is out of range
+	ErrBadSize = errors.New("bad size")
+	// ErrBadEnd means the frame does not end with End
+	ErrBadEnd = errors.New("bad end")
+	// ErrBadCrc means the crc check sum of the frame does not match
+	ErrBadCrc = errors.New("bad crc check sum")
+
+	// they are returned for every partial frame of streams, so they are not allocated on each call
+	errNotEnoughHeader  = fmt.Errorf("%w: header", ErrNotEnoughData)
+	errNotEnoughPayload = fmt.Errorf("%w: payload", ErrNotEnoughData)
+)
+
+// maxConsecutiveEmptyReads is the number of reads returning no data and no error before giving up
+const maxConsecutiveEmptyReads = 100
+
+// frameReader is the buffered input of a frame stream, gnet.Conn implements it
+type frameReader interface {
+	// ReadN reads n bytes without moving the read pointer, less bytes are returned if not enough data
+	ReadN(n int) (size int, buf []byte)
+	// ShiftN moves the read pointer n bytes, all data is discarded if not enough data
+	ShiftN(n int) (size int)
+}
+
+// scanCounters are the counters of frame decoding
+type scanCounters struct {
+	frames       atomic.Uint64
+	droppedBytes atomic.Uint64
+	badVersion   atomic.Uint64
+	badSize      atomic.Uint64
+	badEnd       atomic.Uint64
+	badCrc       atomic.Uint64
+}
+
+func (s *scanCounters) drop(r frameReader, n int, counter *atomic.Uint64) {
+	s.droppedBytes.Add(uint64(r.ShiftN(n)))
+	if counter != nil {
+		counter.Inc()
+	}
+}
+
+// ScanStats is the snapshot of frame decoding counters
+type ScanStats struct {
+	Frames       uint64 `json:"frames"`        // frames decoded
+	DroppedBytes uint64 `json:"dropped_bytes"` // bytes skipped to resynchronise
+	BadVersion   uint64 `json:"bad_version"`   // frames of unsupported version
+	BadSize      uint64 `json:"bad_size"`      // frames of size out of range
+	BadEnd       uint64 `json:"bad_end"`       // frames without End
+	BadCrc       uint64 `json:"bad_crc"`       // frames of mismatched crc
+}
+
+func (s *scanCounters) stats() ScanStats {
+	return ScanStats{
+		Frames:       s.frames.Load(),
+		DroppedBytes: s.droppedBytes.Load(),
+		BadVersion:   s.badVersion.Load(),
+		BadSize:      s.badSize.Load(),
+		BadEnd:       s.badEnd.Load(),
+		BadCrc:       s.badCrc.Load(),
+	}
+}
+
+// decodeFrame finds the head and decodes a frame of the versions from r, the data before the head and broken frames
+// are shifted to resynchronise. ErrNotEnoughData is returned if r has no complete frame, call it again when more data comes.
+// If inPlace is true, the returned frame references the data of r and is valid until r reads more data, otherwise
+// it is copied before the frame is shifted, as the shifted data of a gnet conn can be released and reused.
+func decodeFrame(r frameReader, isCrcCheck, inPlace bool, versions []byte, counters *scanCounters) ([]byte, error) {
+	// find package head
+	idx := 0
+	var size int
+	var header []byte
+	var find bool
+	for {
+		if idx == 0 || idx+4 > DefaultHeadLength || find {
+			idx = 0
+			size, header = r.ReadN(DefaultHeadLength)
+			if size != DefaultHeadLength {
+				return nil, errNotEnoughHeader
+			}
+			if find {
+				break
+			}
+		}
+		if header[idx] != Head[0] || header[idx+1] != Head[1] || header[idx+2] != Head[2] || header[idx+3] != Head[3] {
+			counters.drop(r, 1, nil)
+			idx++
+			continue
+		}
+		find = true
+		if idx == 0 {
+			break
+		}
+	}
+
+	// check version
+	sizeWithoutData, err := minSize(header[4])
+	if err == nil && !bytes.Contains(versions, header[4:5]) {
+		err = &UnsupportedVersionError{Version: header[4]}
+	}
+	if err != nil {
+		counters.drop(r, 1, &counters.badVersion)
+		return nil, err
+	}
+
+	// check size
+	fSize := binary.BigEndian.Uint32(header[5:9])
+	if fSize > MaxSize || fSize < sizeWithoutData {
+		counters.drop(r, len(Head), &counters.badSize)
+		return nil, fmt.Errorf("%w [%d]", ErrBadSize, fSize)
+	}
+
+	// parse payload
+	protocolLen := DefaultHeadLength + int(fSize)
+	dataSize, data := r.ReadN(protocolLen)
+	if dataSize != protocolLen {
+		return nil, errNotEnoughPayload
+	}
+
+	// check packet end
+	if data[protocolLen-1] != End {
+		end := data[protocolLen-1]
+		counters.drop(r, DefaultHeadLength-1, &counters.badEnd)
+		return nil, fmt.Errorf("%w [%02X]", ErrBadEnd, end)
+	}
+
+	// check packet crc
+	if isCrcCheck {
+		fCrc := binary.BigEndian.Uint16(data[protocolLen-3 : protocolLen-1])
+		crc := utils.CheckSum(data[DefaultHeadLength : dataSize-3])
+		if crc != fCrc {
+			counters.drop(r, protocolLen, &counters.badCrc)
+			return nil, fmt.Errorf("%w %v != %v", ErrBadCrc, crc, fCrc)
+		}
+	}
+
+	if !inPlace {
+		data = append([]byte(nil), data...)
+	}
+	r.ShiftN(protocolLen)
+	counters.frames.Inc()
+	return data, nil
+}
+
+// frameBuffer is the frameReader of io.Reader, data is kept in buf[start:end]
+type frameBuffer struct {
+	r     io.Reader
+	buf   []byte
+	start int
+	end   int
+}
+
+// ReadN implements frameReader
+func (b *frameBuffer) ReadN(n int) (int, []byte) {
+	if available := b.end - b.start; available < n {
+		n = available
+	}
+	return n, b.buf[b.start : b.start+n]
+}
+
+// ShiftN implements frameReader
+func (b *frameBuffer) ShiftN(n int) int {
+	if available := b.end - b.start; available < n {
+		b.start, b.end = 0, 0
+		return available
+	}
+	b.start += n
+	return n
+}
+
+// fill reads more data, the buffered data is moved to the front or buf grows if it is full
+func (b *frameBuffer) fill() error {
+	if b.start > 0 {
+		copy(b.buf, b.buf[b.start:b.end])
+		b.end -= b.start
+		b.start = 0
+	}
+	if b.end == len(b.buf) {
+		buf := make([]byte, 2*len(b.buf))
+		copy(buf, b.buf[:b.end])
+		b.buf = buf
+	}
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		n, err := b.r.Read(b.buf[b.end:])
+		b.end += n
+		if n > 0 || err != nil {
+			return err
+		}
+	}
+	return io.ErrNoProgress
+}
+
+// FrameScanner reads frames from an io.Reader, e.g. files, serial ports, websockets or net.Conn.
+// It resynchronises on broken data the same way as Coder does.
+type FrameScanner struct {
+	buf        frameBuffer
+	isCrcCheck bool
+	versions   []byte
+	frame      []byte
+	readErr    error // error of the last read, the buffered data is decoded before it is returned
+	err        error
+	counters   scanCounters
+}
+
+// NewFrameScanner create a FrameScanner reading from r
+func NewFrameScanner(r io.Reader, isCrcCheck bool) *FrameScanner {
+	return &FrameScanner{
+		buf:        frameBuffer{r: r, buf: make([]byte, 4096)},
+		isCrcCheck: isCrcCheck,
+		versions:   SupportedVersions(),
+	}
+}
+
+// SetVersions sets the accepted versions, frames of other versions are dropped as bad version.
+// It must be called before Scan.
+func (s *FrameScanner) SetVersions(versions ...byte) *FrameScanner {
+	s.versions = versions
+	return s
+}
+
+// Scan advances to the next valid frame, it returns false at the end of the reader or on read errors.
+// Broken data is skipped and counted in Stats.
+func (s *FrameScanner) Scan() bool {
+	s.frame = nil
+	if s.err != nil {
+		return false
+	}
+	for {
+		frame, err := decodeFrame(&s.buf, s.isCrcCheck, true, s.versions, &s.counters)
+		if err == nil {
+			s.frame = frame
+			return true
+		}
+		if !errors.Is(err, ErrNotEnoughData) {
+			continue
+		}
+		if s.readErr != nil {
+			// no more data, the rest is an incomplete frame
+			s.counters.droppedBytes.Add(uint64(s.buf.end - s.buf.start))
+			s.buf.start, s.buf.end = 0, 0
+			s.err = s.readErr
+			return false
+		}
+		s.readErr = s.buf.fill()
+	}
+}
+
+// Bytes returns the raw data of the frame found by Scan, including head and end.
+// It references the buffer of the scanner and is overwritten by the next Scan, copy it to keep it.
+func (s *FrameScanner) Bytes() []byte {
+	return s.frame
+}
+
+// Frame decodes the frame found by Scan to a new Frame, it does not reference the buffer of the scanner
+func (s *FrameScanner) Frame() (*Frame, error) {
+	f := NewDefaultFrame()
+	err := s.DecodeFrame(f, false)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// DecodeFrame decodes the frame found by Scan to f, e.g. a frame of AcquireFrame.
+// If inPlace is true, segments reference the buffer of the scanner and are valid until the next Scan.
+func (s *FrameScanner) DecodeFrame(f *Frame, inPlace bool) error {
+	if s.frame == nil {
+		return fmt.Errorf("no frame")
+	}
+	if inPlace {
+		return f.DecodeInPlace(s.frame)
+	}
+	return f.Decode(s.frame)
+}
+
+// Err returns the first error except io.EOF of the reader
+func (s *FrameScanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// Stats returns the counters of frames and broken data, it is safe to call it concurrently with Scan
+func (s *FrameScanner) Stats() ScanStats {
+	return s.counters.stats()
+}
@@ -0,0 +1,238 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/iotest"
+
+	"github.com/panjf2000/gnet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiga-hub/arc/utils"
+)
+
+// testConn is a gnet.Conn holding all data in its buffer, shifted data is cleared as gnet releases it
+type testConn struct {
+	gnet.Conn
+	buffer []byte
+}
+
+func (c *testConn) ReadN(n int) (int, []byte) {
+	if len(c.buffer) < n {
+		n = len(c.buffer)
+	}
+	return n, c.buffer[:n]
+}
+
+func (c *testConn) ShiftN(n int) int {
+	if len(c.buffer) < n {
+		n = len(c.buffer)
+	}
+	for i := range c.buffer[:n] {
+		c.buffer[i] = 0
+	}
+	c.buffer = c.buffer[n:]
+	return n
+}
+
+// testFrame returns a v1 frame of an arc segment of the data
+func testFrame(data []byte) []byte {
+	st := NewDefaultSegmentArc()
+	st.SetData(data)
+	g := NewDefaultDataGroup()
+	g.AppendSegment(st)
+	p := NewDefaultFrame().SetDataGroup(g)
+	buf := make([]byte, p.Size+DefaultHeadLength)
+	_, _ = p.Encode(buf)
+	return buf
+}
+
+// decodeAll decodes data by Coder and FrameScanner
+func decodeAll(data []byte, chunk int) ([][]byte, ScanStats, [][]byte, ScanStats) {
+	coder := &Coder{IsCrcCheck: true}
+	conn := &testConn{buffer: append([]byte(nil), data...)}
+	var coderFrames [][]byte
+	for {
+		frame, err := coder.Decode(conn)
+		if errors.Is(err, ErrNotEnoughData) {
+			break
+		}
+		if err == nil {
+			coderFrames = append(coderFrames, frame)
+		}
+	}
+
+	scanner := NewFrameScanner(iotest.HalfReader(&chunkReader{data: data, chunk: chunk}), true)
+	var scannerFrames [][]byte
+	for scanner.Scan() {
+		scannerFrames = append(scannerFrames, append([]byte(nil), scanner.Bytes()...))
+	}
+	return coderFrames, coder.Stats(), scannerFrames, scanner.Stats()
+}
+
+// referenceDecode is the gnet Coder.Decode before FrameScanner, it is the reference of the fuzz test
+func referenceDecode(c gnet.Conn, isCrcCheck bool) ([]byte, error) {
+	// find package head
+	idx := 0
+	var size int
+	var header []byte
+	var find bool
+	for {
+		if idx == 0 || idx+4 > DefaultHeadLength || find {
+			idx = 0
+			size, header = c.ReadN(DefaultHeadLength)
+			if size != DefaultHeadLength {
+				return nil, fmt.Errorf("not enough header data")
+			}
+			if find {
+				break
+			}
+		}
+		if !bytes.Equal(header[idx:idx+4], Head[:]) {
+			c.ShiftN(1)
+			idx++
+			continue
+		}
+		find = true
+		if idx == 0 {
+			break
+		}
+	}
+
+	// check version
+	if header[4] != 1 {
+		c.ShiftN(1)
+		return nil, fmt.Errorf("version=!1 ignore this package")
+	}
+
+	// check size
+	fSize := binary.BigEndian.Uint32(header[5:9])
+	if fSize > MaxSize || fSize < LengthWithoutData {
+		c.ShiftN(DefaultHeadLength - 4)
+		return nil, fmt.Errorf("bad size [%d]", fSize)
+	}
+
+	// parse payload
+	protocolLen := DefaultHeadLength + int(fSize)
+	dataSize, data := c.ReadN(protocolLen)
+	if dataSize != protocolLen {
+		return nil, fmt.Errorf("not enough payload data")
+	}
+
+	// check packet end
+	if data[protocolLen-1] != End {
+		c.ShiftN(DefaultHeadLength - 1)
+		return nil, fmt.Errorf("bad end [%02X]", data[protocolLen-1])
+	}
+
+	// check packet crc
+	if isCrcCheck {
+		fCrc := binary.BigEndian.Uint16(data[protocolLen-3 : protocolLen-1])
+		crc := utils.CheckSum(data[DefaultHeadLength : dataSize-3])
+		if crc != fCrc {
+			c.ShiftN(protocolLen)
+			fmt.Println(FrameValidate(data))
+			return nil, fmt.Errorf("bad crc check sum %v != %v", crc, fCrc)
+		}
+	}
+
+	output := make([]byte, len(data))
+	copy(output, data)
+
+	c.ShiftN(protocolLen)
+
+	return output, nil
+}
+
+// chunkReader reads at most chunk bytes at a time
+type chunkReader struct {
+	data  []byte
+	chunk int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, iotest.ErrTimeout
+	}
+	if len(p) > r.chunk {
+		p = p[:r.chunk]
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestFrameScanner(t *testing.T) {
+	f1 := testFrame(bytes.Repeat([]byte{1}, 20))
+	f2 := testFrame(bytes.Repeat([]byte{2}, 13))
+	badCrc := testFrame(bytes.Repeat([]byte{3}, 13))
+	badCrc[10]++
+	badEnd := testFrame(bytes.Repeat([]byte{4}, 13))
+	badEnd[len(badEnd)-1] = 0
+	badSize := testFrame(bytes.Repeat([]byte{5}, 13))
+	binary.BigEndian.PutUint32(badSize[5:9], MaxSize+1)
+
+	var data []byte
+	for _, b := range [][]byte{{0, 0xFC, 0xFC}, f1, badCrc, {0xFC, 0xFC, 0xFC, 0xFC, 3}, badEnd, badSize, f2, f1[:10]} {
+		data = append(data, b...)
+	}
+
+	scanner := NewFrameScanner(iotest.OneByteReader(bytes.NewReader(data)), true)
+	var frames [][]byte
+	for scanner.Scan() {
+		frames = append(frames, append([]byte(nil), scanner.Bytes()...))
+	}
+	assert.Nil(t, scanner.Err())
+	assert.Equal(t, [][]byte{f1, f2}, frames)
+	stats := scanner.Stats()
+	assert.Equal(t, uint64(2), stats.Frames)
+	assert.Equal(t, uint64(1), stats.BadCrc)
+	assert.Equal(t, uint64(1), stats.BadEnd)
+	assert.Equal(t, uint64(1), stats.BadSize)
+	assert.Equal(t, uint64(3), stats.BadVersion) // the heads overlapped with the leading 0xFC are checked first
+	assert.Equal(t, uint64(len(data)-len(f1)-len(f2)), stats.DroppedBytes)
+
+	coderFrames, coderStats, scannerFrames, scannerStats := decodeAll(data, 7)
+	assert.Equal(t, coderFrames, scannerFrames)
+	assert.Equal(t, coderStats.Frames, scannerStats.Frames)
+	assert.Equal(t, iotest.ErrTimeout, func() error {
+		s := NewFrameScanner(&chunkReader{data: f1, chunk: 3}, true)
+		for s.Scan() {
+		}
+		return s.Err()
+	}())
+}
+
+func FuzzFrameScanner(f *testing.F) {
+	f1 := testFrame(bytes.Repeat([]byte{1}, 20))
+	f2 := testFrame(bytes.Repeat([]byte{0xFC}, 13))
+	f.Add(f1, 1)
+	f.Add(append(append([]byte{0xFC, 0xFC, 0xFC}, f1...), f2...), 5)
+	f.Add(append(f2[:len(f2)-2], f1...), 64)
+	f.Fuzz(func(t *testing.T, data []byte, chunk int) {
+		if chunk <= 0 {
+			chunk = 1
+		}
+		conn := &testConn{buffer: append([]byte(nil), data...)}
+		var referenceFrames [][]byte
+		for {
+			frame, err := referenceDecode(conn, true)
+			if err != nil && (err.Error() == "not enough header data" || err.Error() == "not enough payload data") {
+				break
+			}
+			if err == nil {
+				referenceFrames = append(referenceFrames, frame)
+			}
+		}
+
+		scanner := NewFrameScanner(iotest.HalfReader(&chunkReader{data: data, chunk: chunk}), true).SetVersions(FrameV1)
+		var scannerFrames [][]byte
+		for scanner.Scan() {
+			scannerFrames = append(scannerFrames, append([]byte(nil), scanner.Bytes()...))
+		}
+		assert.Equal(t, referenceFrames, scannerFrames)
+	})
+}
@@ -0,0 +1,117 @@
+package protocols
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kiga-hub/arc/utils"
+)
+
+// SegmentFactory creates an empty segment to decode into
+type SegmentFactory func() ISegment
+
+var (
+	segmentLock      sync.RWMutex
+	segmentFactories = map[byte]SegmentFactory{}
+)
+
+func init() {
+	MustRegisterSegment(STypeArc, func() ISegment {
+		return NewDefaultSegmentArc()
+	})
+}
+
+// RegisterSegment registers the factory of segment type, segments of unregistered types are decoded as SegmentRaw
+func RegisterSegment(sType byte, factory SegmentFactory) error {
+	segmentLock.Lock()
+	defer segmentLock.Unlock()
+	if _, ok := segmentFactories[sType]; ok {
+		return fmt.Errorf("segment type %d is registered", sType)
+	}
+	segmentFactories[sType] = factory
+	return nil
+}
+
+// MustRegisterSegment registers the factory of segment type, it panics if the type is registered.
+// It is meant to be called in init().
+func MustRegisterSegment(sType byte, factory SegmentFactory) {
+	err := RegisterSegment(sType, factory)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewSegment creates an empty segment of the type, SegmentRaw if the type is not registered
+func NewSegment(sType byte) ISegment {
+	segmentLock.RLock()
+	factory, ok := segmentFactories[sType]
+	segmentLock.RUnlock()
+	if !ok {
+		return &SegmentRaw{SType: sType}
+	}
+	return factory()
+}
+
+// GetSegmentAs returns the first segment of type T in the data group, e.g. GetSegmentAs[*SegmentArc](d)
+func GetSegmentAs[T ISegment](d *DataGroup) (T, error) {
+	for _, s := range d.Segments {
+		if v, ok := s.(T); ok {
+			return v, nil
+		}
+	}
+	var v T
+	return v, fmt.Errorf("not find segment %T", v)
+}
+
+// SegmentRaw is a segment of unregistered type, it is kept as is so it can be forwarded
+type SegmentRaw struct {
+	SType byte //1 segment type
+	Data  []byte
+}
+
+// Decode - decode
+func (s *SegmentRaw) Decode(srcData []byte) error {
+	if len(srcData) == 0 {
+		return fmt.Errorf("empty segment")
+	}
+	s.SType = srcData[0]
+	s.Data = make([]byte, len(srcData)-1)
+	copy(s.Data, srcData[1:])
+	return nil
+}
+
+// DecodeInPlace - decode, Data references srcData
+func (s *SegmentRaw) DecodeInPlace(srcData []byte) error {
+	if len(srcData) == 0 {
+		return fmt.Errorf("empty segment")
+	}
+	s.SType = srcData[0]
+	s.Data = srcData[1:len(srcData):len(srcData)]
+	return nil
+}
+
+// Encode - encode
+func (s *SegmentRaw) Encode(buf []byte) (int, error) {
+	if len(buf) < int(s.Size()) {
+		return 0, fmt.Errorf("segment out of allocated memory")
+	}
+	buf[0] = s.SType
+	copy(buf[1:], s.Data)
+	return int(s.Size()), nil
+}
+
+// Type - segment type
+func (s *SegmentRaw) Type() byte {
+	return s.SType
+}
+
+// Size - encode size
+func (s *SegmentRaw) Size() uint32 {
+	return 1 + uint32(len(s.Data))
+}
+
+// Dump -
+func (s *SegmentRaw) Dump() {
+	title := fmt.Sprintf("Raw segment type: %d, len: %d\n  ", s.SType, len(s.Data))
+	utils.Hexdump(title, s.Data)
+}
@@ -28,32 +28,37 @@ func NewDefaultSegmentArc() *SegmentArc {
 }
 
 // ArcSegmentValidate - validation
-func ArcSegmentValidate(srcData []byte) error {
-	data := make([]byte, len(srcData))
-	copy(data, srcData)
-
-	idx := 0
-
-	if data[idx] != STypeArc {
-		return fmt.Errorf("arc segment stype invalid(%d)", data[idx])
+func ArcSegmentValidate(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty arc segment")
+	}
+	if data[0] != STypeArc {
+		return fmt.Errorf("arc segment stype invalid(%d)", data[0])
 	}
-
 	return nil
 }
 
-// Decode - decode
+// Decode - decode, the segment does not reference srcData
 func (s *SegmentArc) Decode(srcData []byte) error {
-
-	data := make([]byte, len(srcData))
-	copy(data, srcData)
-
-	idx := 0
+	if err := ArcSegmentValidate(srcData); err != nil {
+		return err
+	}
 	// sType(1)
-	s.SType = data[idx]
-	idx++
+	s.SType = srcData[0]
+	s.Data = make([]byte, len(srcData)-1)
+	copy(s.Data, srcData[1:])
+	return nil
+}
 
-	s.Data = data[idx:]
-	return s.Validate()
+// DecodeInPlace - decode, Data references srcData
+func (s *SegmentArc) DecodeInPlace(srcData []byte) error {
+	if err := ArcSegmentValidate(srcData); err != nil {
+		return err
+	}
+	// sType(1)
+	s.SType = srcData[0]
+	s.Data = srcData[1:len(srcData):len(srcData)]
+	return nil
 }
 
 // SetData - set data
@@ -74,6 +79,9 @@ func (s *SegmentArc) GetType() byte {
 
 // Encode - encode
 func (s *SegmentArc) Encode(buf []byte) (int, error) {
+	if len(buf) < int(s.Size()) {
+		return 0, fmt.Errorf("arc segment out of allocated memory")
+	}
 	idx := 0
 
 	// sType(1)
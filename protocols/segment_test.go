@@ -0,0 +1,106 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sTypeTemperature byte = 11
+
+// segmentTemperature is a segment of temperatures in 0.01 ℃
+type segmentTemperature struct {
+	Values []int16
+}
+
+func (s *segmentTemperature) Decode(data []byte) error {
+	if len(data) == 0 || data[0] != sTypeTemperature || len(data)%2 != 1 {
+		return fmt.Errorf("invalid temperature segment")
+	}
+	s.Values = make([]int16, len(data)/2)
+	for i := range s.Values {
+		s.Values[i] = int16(binary.BigEndian.Uint16(data[1+2*i:]))
+	}
+	return nil
+}
+
+func (s *segmentTemperature) Encode(buf []byte) (int, error) {
+	if len(buf) < int(s.Size()) {
+		return 0, fmt.Errorf("temperature segment out of allocated memory")
+	}
+	buf[0] = sTypeTemperature
+	for i, v := range s.Values {
+		binary.BigEndian.PutUint16(buf[1+2*i:], uint16(v))
+	}
+	return int(s.Size()), nil
+}
+
+func (s *segmentTemperature) Type() byte   { return sTypeTemperature }
+func (s *segmentTemperature) Size() uint32 { return 1 + 2*uint32(len(s.Values)) }
+func (s *segmentTemperature) Dump()        {}
+
+func init() {
+	MustRegisterSegment(sTypeTemperature, func() ISegment { return &segmentTemperature{} })
+}
+
+func TestSegmentRegistry(t *testing.T) {
+	assert.NotNil(t, RegisterSegment(STypeArc, func() ISegment { return &SegmentRaw{} }))
+	assert.IsType(t, &SegmentArc{}, NewSegment(STypeArc))
+	assert.IsType(t, &segmentTemperature{}, NewSegment(sTypeTemperature))
+	assert.Equal(t, &SegmentRaw{SType: 200}, NewSegment(200))
+}
+
+func TestDataGroupRoundTrip(t *testing.T) {
+	arc := NewDefaultSegmentArc()
+	arc.SetData([]byte{1, 2, 3, 4, 5})
+	temperature := &segmentTemperature{Values: []int16{2512, -300}}
+	unknown := &SegmentRaw{SType: 200, Data: []byte{9, 8, 7}}
+
+	g := NewDefaultDataGroup()
+	g.AppendSegment(temperature)
+	g.AppendSegment(arc)
+	g.AppendSegment(unknown)
+	assert.Nil(t, g.Validate())
+
+	p := NewDefaultFrame()
+	p.SetID(15)
+	p.Timestamp = 1234567
+	p.SetDataGroup(g)
+	buf := make([]byte, p.Size+DefaultHeadLength)
+	n, err := p.Encode(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, len(buf), n)
+	assert.Nil(t, FrameValidate(buf))
+
+	p2 := NewDefaultFrame()
+	assert.Nil(t, p2.Decode(buf))
+	assert.Equal(t, p.Timestamp, p2.Timestamp)
+	assert.Equal(t, p.ID, p2.ID)
+	assert.Equal(t, p.Crc, p2.Crc)
+	assert.Equal(t, []byte{sTypeTemperature, STypeArc, 200}, p2.DataGroup.STypes)
+	assert.Equal(t, g.Sizes, p2.DataGroup.Sizes)
+
+	arc2, err := p2.DataGroup.GetArcSegment()
+	assert.Nil(t, err)
+	assert.Equal(t, arc.Data, arc2.Data)
+	temperature2, err := GetSegmentAs[*segmentTemperature](&p2.DataGroup)
+	assert.Nil(t, err)
+	assert.Equal(t, temperature.Values, temperature2.Values)
+	_, err = GetSegmentAs[*SegmentRaw](&p2.DataGroup)
+	assert.Nil(t, err)
+
+	// unknown segments are forwarded as is
+	p3 := NewDefaultFrame()
+	p3.ID, p3.Timestamp = p2.ID, p2.Timestamp
+	p3.SetDataGroup(&p2.DataGroup)
+	buf2 := make([]byte, p3.Size+DefaultHeadLength)
+	_, err = p3.Encode(buf2)
+	assert.Nil(t, err)
+	assert.Equal(t, buf, buf2)
+
+	// a segment failed to decode as its type fails
+	buf[len(buf)-3-int(unknown.Size())-int(arc.Size())] = sTypeTemperature
+	assert.NotNil(t, p2.Decode(buf))
+}
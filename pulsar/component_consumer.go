@@ -20,6 +20,11 @@ func (c *ConsumerComponent) Name() string {
 	return "PulsarConsumer"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *ConsumerComponent) ConfigSections() []string {
+	return []string{"pulsar"}
+}
+
 // PreInit called before Init()
 func (c *ConsumerComponent) PreInit(ctx context.Context) error {
 	_ = ctx
@@ -21,6 +21,16 @@ func (c *ProducerComponent) Name() string {
 	return "PulsarProducer"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *ProducerComponent) ConfigSections() []string {
+	return []string{"pulsar"}
+}
+
+// DependsOnElements returns keys of elements which must be registered before this one is initialized
+func (c *ProducerComponent) DependsOnElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&micro.LoggingElementKey}
+}
+
 // PreInit called before Init()
 func (c *ProducerComponent) PreInit(ctx context.Context) error {
 	_ = ctx
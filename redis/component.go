@@ -24,6 +24,16 @@ func (c *Component) Name() string {
 	return "Redis"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"redis"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey, &micro.RateLimitStoreElementKey}
+}
+
 // PreInit called before Init()
 func (c *Component) PreInit(ctx context.Context) error {
 	_ = ctx
@@ -44,9 +54,24 @@ func (c *Component) Init(server *micro.Server) error {
 		DB:       redisConf.DB,
 	})
 	server.RegisterElement(&ElementKey, c.client)
+	// used by micro if rateLimit.store is redis
+	server.RegisterElement(&micro.RateLimitStoreElementKey, NewRateLimitStore(c.client))
 	return nil
 }
 
+// HealthChecks returns checks of the component, called after Init()
+func (c *Component) HealthChecks() []micro.HealthCheck {
+	return []micro.HealthCheck{
+		{
+			Name:  "ping",
+			Level: micro.HealthLevelCritical,
+			Check: func(ctx context.Context) error {
+				return c.client.WithContext(ctx).Ping().Err()
+			},
+		},
+	}
+}
+
 // PostStop called after Stop()
 func (c *Component) PostStop(ctx context.Context) error {
 	_ = ctx
@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/kiga-hub/arc/leadelection"
+)
+
+// leaseKeyPrefix is prefix of keys of leader election records in redis
+const leaseKeyPrefix = "arc:lease:"
+
+// leaseTransitionsSuffix is suffix of keys of the last leader transitions of records, they do not expire with records
+const leaseTransitionsSuffix = ":transitions"
+
+// leaseCreateScript creates the record only if there is none and the last transitions are still below the new ones.
+// KEYS[1] key, KEYS[2] last transitions, ARGV[1] new record, ARGV[2] ttl in milliseconds, ARGV[3] transitions of the
+// new record
+var leaseCreateScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local last = redis.call("GET", KEYS[2])
+if last and tonumber(last) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+redis.call("SET", KEYS[2], ARGV[3])
+return 1
+`)
+
+// leaseRenewScript replaces the record only if it is still the observed one, and raises the last transitions.
+// KEYS[1] key, KEYS[2] last transitions, ARGV[1] observed record, ARGV[2] new record, ARGV[3] ttl in milliseconds,
+// ARGV[4] transitions of the new record
+var leaseRenewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+if tonumber(redis.call("GET", KEYS[2]) or "0") < tonumber(ARGV[4]) then
+	redis.call("SET", KEYS[2], ARGV[4])
+end
+return 1
+`)
+
+// LeaseLock is a leadelection.ILock in redis. The record is created by SET NX and replaced by a script comparing
+// it with the observed one, and it expires after its lease duration, so a dead leader leaves no record.
+// The last LeaderTransitions are kept without expiry, a record created again gets greater ones, so the fencing
+// tokens keep increasing after records expire.
+type LeaseLock struct {
+	client   *redis.Client
+	key      string
+	identity string
+
+	lock     sync.Mutex
+	observed string // the record last read or written, empty if none
+}
+
+// NewLeaseLock create a LeaseLock of the record of name
+func NewLeaseLock(client *redis.Client, name, identity string) *LeaseLock {
+	return &LeaseLock{
+		client:   client,
+		key:      leaseKeyPrefix + name,
+		identity: identity,
+	}
+}
+
+// Get returns the LeaderElectionRecord
+func (l *LeaseLock) Get(ctx context.Context) (*leadelection.LeaderElectionRecord, []byte, error) {
+	value, err := l.client.WithContext(ctx).Get(l.key).Result()
+	if err == redis.Nil {
+		l.setObserved("")
+		return nil, nil, leadelection.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var record leadelection.LeaderElectionRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, nil, err
+	}
+	l.setObserved(value)
+	return &record, []byte(value), nil
+}
+
+// Create attempts to create a LeaderElectionRecord, LeaderTransitions is raised above those of expired records
+func (l *LeaseLock) Create(ctx context.Context, ler leadelection.LeaderElectionRecord) error {
+	client := l.client.WithContext(ctx)
+	last, err := client.Get(l.key + leaseTransitionsSuffix).Int()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if err == nil {
+		ler.LeaderTransitions = max(ler.LeaderTransitions, last+1)
+	}
+	value, err := json.Marshal(ler)
+	if err != nil {
+		return err
+	}
+	ttl := leaseTTL(ler).Milliseconds()
+	keys := []string{l.key, l.key + leaseTransitionsSuffix}
+	result, err := leaseCreateScript.Run(client, keys, string(value), ttl, ler.LeaderTransitions).Int64()
+	if err != nil {
+		return err
+	}
+	if result != 1 {
+		return leadelection.ErrConflict
+	}
+	l.setObserved(string(value))
+	return nil
+}
+
+// Update will update and existing LeaderElectionRecord
+func (l *LeaseLock) Update(ctx context.Context, ler leadelection.LeaderElectionRecord) error {
+	l.lock.Lock()
+	observed := l.observed
+	l.lock.Unlock()
+	if observed == "" {
+		return leadelection.ErrConflict
+	}
+	value, err := json.Marshal(ler)
+	if err != nil {
+		return err
+	}
+	ttl := leaseTTL(ler).Milliseconds()
+	result, err := leaseRenewScript.Run(l.client.WithContext(ctx), []string{l.key, l.key + leaseTransitionsSuffix},
+		observed, string(value), ttl, ler.LeaderTransitions).Int64()
+	if err != nil {
+		return err
+	}
+	if result != 1 {
+		return leadelection.ErrConflict
+	}
+	l.setObserved(string(value))
+	return nil
+}
+
+func (l *LeaseLock) setObserved(value string) {
+	l.lock.Lock()
+	l.observed = value
+	l.lock.Unlock()
+}
+
+// leaseTTL returns the expiration of the record, at least a second
+func leaseTTL(ler leadelection.LeaderElectionRecord) time.Duration {
+	return time.Duration(max(ler.LeaseDurationSeconds, 1)) * time.Second
+}
+
+// RecordEvent is used to record events
+func (l *LeaseLock) RecordEvent(string) {}
+
+// Identity will return the locks Identity
+func (l *LeaseLock) Identity() string {
+	return l.identity
+}
+
+// Describe is used to convert details on current resource lock into a string
+func (l *LeaseLock) Describe() string {
+	return fmt.Sprintf("redis/%s", l.key)
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiga-hub/arc/leadelection"
+	"github.com/kiga-hub/arc/leadelection/locktest"
+)
+
+func newMiniredis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return s, client
+}
+
+func TestLeaseLock(t *testing.T) {
+	locktest.Run(t, func(t *testing.T) func(identity string) leadelection.ILock {
+		_, client := newMiniredis(t)
+		return func(identity string) leadelection.ILock {
+			return NewLeaseLock(client, "test", identity)
+		}
+	})
+}
+
+func TestLeaseLockExpire(t *testing.T) {
+	s, client := newMiniredis(t)
+	ctx := context.Background()
+	a, b := NewLeaseLock(client, "test", "a"), NewLeaseLock(client, "test", "b")
+	assert.Nil(t, a.Create(ctx, leadelection.LeaderElectionRecord{HolderIdentity: "a", LeaseDurationSeconds: 10}))
+	assert.Equal(t, 10*time.Second, s.TTL(leaseKeyPrefix+"test"))
+	assert.Nil(t, a.Update(ctx, leadelection.LeaderElectionRecord{HolderIdentity: "a", LeaseDurationSeconds: 15}))
+	assert.Equal(t, 15*time.Second, s.TTL(leaseKeyPrefix+"test"))
+
+	// the record of a leader not renewing disappears
+	s.FastForward(16 * time.Second)
+	_, _, err := b.Get(ctx)
+	assert.ErrorIs(t, err, leadelection.ErrNotFound)
+	assert.Nil(t, b.Create(ctx, leadelection.LeaderElectionRecord{HolderIdentity: "b", LeaseDurationSeconds: 10}))
+	// the fencing token of b is above the one of a, though the record of a is gone
+	record, _, err := b.Get(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, record.LeaderTransitions)
+	assert.ErrorIs(t, a.Update(ctx, leadelection.LeaderElectionRecord{HolderIdentity: "a", LeaseDurationSeconds: 10}), leadelection.ErrConflict)
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+
+	platformConf "github.com/kiga-hub/arc/conf"
+)
+
+// rateLimitKeyPrefix is prefix of keys of rate limit counters in redis
+const rateLimitKeyPrefix = "arc:ratelimit:"
+
+// rateLimitScript implements GCRA, a token bucket keeping only the theoretical arrival time of the next request.
+// KEYS[1] key, ARGV[1] emission interval in microseconds, ARGV[2] burst, ARGV[3] now in microseconds
+var rateLimitScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+local next = tat + interval
+if next - burst * interval > now then
+	return 0
+end
+redis.call("SET", KEYS[1], next, "PX", math.ceil((next - now) / 1000))
+return 1
+`)
+
+// RateLimitStore is a micro.RateLimitStore in redis, so the limits hold across replicas.
+// The clock of the replica is used, clocks of replicas should be synchronized.
+type RateLimitStore struct {
+	client *redis.Client
+}
+
+// NewRateLimitStore create a RateLimitStore with the redis client
+func NewRateLimitStore(client *redis.Client) *RateLimitStore {
+	return &RateLimitStore{client: client}
+}
+
+// Allow returns whether one more request of the key is allowed under the quota
+func (s *RateLimitStore) Allow(key string, quota platformConf.RateLimitQuota) (bool, error) {
+	interval := int64(float64(time.Second/time.Microsecond) / quota.Rate)
+	now := time.Now().UnixNano() / int64(time.Microsecond)
+	result, err := rateLimitScript.Run(s.client, []string{rateLimitKeyPrefix + key}, interval, quota.Burst, now).Int64()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
@@ -27,6 +27,16 @@ func (c *Component) Name() string {
 	return "Trace"
 }
 
+// ConfigSections returns top level keys of the config file read by the component
+func (c *Component) ConfigSections() []string {
+	return []string{"trace"}
+}
+
+// ProvidesElements returns keys of elements registered by the component in Init()
+func (c *Component) ProvidesElements() []*micro.ElementKey {
+	return []*micro.ElementKey{&ElementKey}
+}
+
 // PreInit called before Init()
 func (c *Component) PreInit(ctx context.Context) error {
 	_ = ctx
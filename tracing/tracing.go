@@ -251,8 +251,7 @@ func GetGRPCClientSpan(
 	}
 	parent := opentracing.SpanFromContext(ctx)
 	// machinery will bring a noopSpan in the context if no other span set
-	pt := reflect.TypeOf(parent)
-	if pt.String() == skipSpanType {
+	if parent != nil && reflect.TypeOf(parent).String() == skipSpanType {
 		parent = nil
 	}
 	//fmt.Println(pt.String())
@@ -286,6 +285,35 @@ func GetGRPCClientSpan(
 	}
 }
 
+// GetGRPCServerSpan get grpc server span, the parent is extracted from the metadata injected by GetGRPCClientSpan
+//
+//goland:noinspection GoUnusedExportedFunction
+func GetGRPCServerSpan(
+	ctx context.Context,
+	logger logging.ILogger,
+	tracer opentracing.Tracer,
+	operationName string,
+	mustFindParent bool,
+) (context.Context, func(), logging.ILogger) { // ctx, finish(), logger
+	if tracer == nil {
+		return ctx, emptyFinishSpan, logger
+	}
+	md := metautils.ExtractIncoming(ctx)
+	parent, err := tracer.Extract(opentracing.HTTPHeaders, metadataTextMap(md))
+	if err != nil && !errors.Is(err, opentracing.ErrSpanContextNotFound) {
+		logger.Error(err)
+	}
+	if mustFindParent && parent == nil {
+		return ctx, emptyFinishSpan, logger
+	}
+
+	span := tracer.StartSpan(operationName, ext.RPCServerOption(parent), grpcTag)
+	return opentracing.ContextWithSpan(ctx, span), span.Finish, &LoggerWithSpan{
+		Span:           span,
+		OriginalLogger: logger,
+	}
+}
+
 const (
 	binHdrSuffix = "-bin"
 )
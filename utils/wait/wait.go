@@ -47,7 +47,7 @@ func JitterUntil(f func(), period time.Duration, jitterFactor float64, sliding b
 //
 // If sliding is true, the period is computed after f runs. If it is false then
 // period includes the runtime for f.
-func BackoffUntil(f func(), backoff backoffManager, sliding bool, stopCh <-chan struct{}) {
+func BackoffUntil(f func(), backoff BackoffManager, sliding bool, stopCh <-chan struct{}) {
 	var t *time.Timer
 	for {
 		select {
@@ -84,7 +84,8 @@ func BackoffUntil(f func(), backoff backoffManager, sliding bool, stopCh <-chan
 	}
 }
 
-type backoffManager interface {
+// BackoffManager tells how long to wait before the next run of BackoffUntil
+type BackoffManager interface {
 	Backoff() *time.Timer
 }
 
@@ -96,7 +97,7 @@ type jitteredBackoffManagerImpl struct {
 
 // newJitteredBackoffManager returns a BackoffManager that back-offs with given duration plus given jitter. If the jitter
 // is negative, backoff will not be jittered.
-func newJitteredBackoffManager(duration time.Duration, jitter float64) backoffManager {
+func newJitteredBackoffManager(duration time.Duration, jitter float64) BackoffManager {
 	return &jitteredBackoffManagerImpl{
 		duration:     duration,
 		jitter:       jitter,
@@ -124,6 +125,50 @@ func (j *jitteredBackoffManagerImpl) Backoff() *time.Timer {
 	return j.backoffTimer
 }
 
+type exponentialBackoffManagerImpl struct {
+	initBackoff   time.Duration
+	maxBackoff    time.Duration
+	backoffFactor float64
+	jitter        float64
+	backoff       time.Duration
+	backoffTimer  *time.Timer
+}
+
+// NewExponentialBackoffManager returns a BackoffManager that backoff from initBackoff, multiplied by backoffFactor
+// each time up to maxBackoff, plus given jitter. If the jitter is not positive, backoff will not be jittered.
+func NewExponentialBackoffManager(initBackoff, maxBackoff time.Duration, backoffFactor, jitter float64) BackoffManager {
+	return &exponentialBackoffManagerImpl{
+		initBackoff:   initBackoff,
+		maxBackoff:    maxBackoff,
+		backoffFactor: backoffFactor,
+		jitter:        jitter,
+	}
+}
+
+func (b *exponentialBackoffManagerImpl) getNextBackoff() time.Duration {
+	if b.backoff == 0 {
+		b.backoff = b.initBackoff
+	} else {
+		b.backoff = min(time.Duration(float64(b.backoff)*b.backoffFactor), b.maxBackoff)
+	}
+	if b.jitter > 0.0 {
+		return Jitter(b.backoff, b.jitter)
+	}
+	return b.backoff
+}
+
+// Backoff implements BackoffManager.Backoff, it returns a timer so caller can block on the timer for exponential backoff.
+// The returned timer must be drained before calling Backoff() the second time
+func (b *exponentialBackoffManagerImpl) Backoff() *time.Timer {
+	backoff := b.getNextBackoff()
+	if b.backoffTimer == nil {
+		b.backoffTimer = time.NewTimer(backoff)
+	} else {
+		b.backoffTimer.Reset(backoff)
+	}
+	return b.backoffTimer
+}
+
 // ConditionFunc returns true if the condition is satisfied, or an error
 // if the loop should be aborted.
 type ConditionFunc func() (done bool, err error)
@@ -0,0 +1,33 @@
+package wait
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoffManager(t *testing.T) {
+	b := NewExponentialBackoffManager(time.Second, 5*time.Second, 2, 0).(*exponentialBackoffManagerImpl)
+	var backoffs []time.Duration
+	for i := 0; i < 5; i++ {
+		backoffs = append(backoffs, b.getNextBackoff())
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, backoffs)
+
+	b = NewExponentialBackoffManager(time.Second, 5*time.Second, 2, 0.5).(*exponentialBackoffManagerImpl)
+	backoff := b.getNextBackoff()
+	assert.True(t, backoff >= time.Second && backoff <= 1500*time.Millisecond, backoff)
+}
+
+func TestBackoffUntil(t *testing.T) {
+	stop := make(chan struct{})
+	runs := 0
+	BackoffUntil(func() {
+		runs++
+		if runs == 3 {
+			close(stop)
+		}
+	}, NewExponentialBackoffManager(time.Millisecond, 10*time.Millisecond, 2, 0), true, stop)
+	assert.Equal(t, 3, runs)
+}